@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gochallenges/internal/model"
+	repo "gochallenges/internal/repository"
+	"gochallenges/internal/service"
+	"gochallenges/pkg"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiKeysUsage = "usage: apikeys mint USER [-name NAME] [-scopes tasks:read,tasks:write,tasks:delete,admin] [-expires 720h]|list USER|revoke USER ID"
+
+// runApiKeys manages a user's API keys without their password, for operators.
+func runApiKeys(dbImpl string, dbConfig pkg.DbConfig, args []string) {
+	if len(args) < 2 {
+		log.Fatal(apiKeysUsage)
+	}
+
+	store, usersRepo, err := repo.Open(dbImpl, dbConfig)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %s", err)
+	}
+	defer store.Tasks.Close()
+
+	ctx := context.Background()
+	user, err := usersRepo.FindByName(ctx, args[1])
+	if err != nil {
+		log.Fatalf("Could not find user %q: %s", args[1], err)
+	}
+	user.Scopes = model.AllScopes
+	users := service.NewUser(usersRepo, nil)
+
+	switch {
+	case args[0] == "mint":
+		flags := flag.NewFlagSet("mint", flag.ExitOnError)
+		name := flags.String("name", "", "what the key is for")
+		scopeList := flags.String("scopes", "", "comma-separated scopes, every scope when empty")
+		expires := flags.Duration("expires", 0, "how long the key lasts, forever when 0")
+		flags.Parse(args[2:])
+
+		var scopes model.Scopes
+		if *scopeList != "" {
+			if scopes, err = model.ParseScopes(strings.Split(*scopeList, ",")); err != nil {
+				log.Fatal(err)
+			}
+		}
+		var expiresAt *time.Time
+		if *expires != 0 {
+			at := model.Now().Add(*expires)
+			expiresAt = &at
+		}
+
+		token, err := users.CreateToken(ctx, user, *name, scopes, expiresAt)
+		if err != nil {
+			log.Fatalf("Could not mint key: %s", err)
+		}
+		fmt.Println(token.Secret)
+	case args[0] == "list" && len(args) == 2:
+		tokens, err := users.Tokens(ctx, user)
+		if err != nil {
+			log.Fatalf("Could not list keys: %s", err)
+		}
+		for _, token := range tokens {
+			fmt.Printf("%-5d %-20s %-45s expires %-20s last used %s\n", token.ID, token.Name, token.Scopes, formatTime(token.ExpiresAt), formatTime(token.LastUsedAt))
+		}
+	case args[0] == "revoke" && len(args) == 3:
+		id, err := strconv.Atoi(args[2])
+		if err != nil {
+			log.Fatal(apiKeysUsage)
+		}
+		if err := users.RevokeToken(ctx, user, id); err != nil {
+			log.Fatalf("Could not revoke key: %s", err)
+		}
+	default:
+		log.Fatal(apiKeysUsage)
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	repo "gochallenges/internal/repository"
+	"gochallenges/pkg"
+	"log"
+	"time"
+)
+
+const asOfUsage = "usage: asof TIME (RFC 3339), with DB_IMPL=events"
+
+// runAsOf prints every user's tasks as they were at a past time, which only the events backend knows.
+func runAsOf(dbImpl string, dbConfig pkg.DbConfig, args []string) {
+	flags := flag.NewFlagSet("asof", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatal(asOfUsage)
+	}
+	at, err := time.Parse(time.RFC3339, flags.Arg(0))
+	if err != nil {
+		log.Fatal(asOfUsage)
+	}
+
+	store, _, err := repo.Open(dbImpl, dbConfig)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %s", err)
+	}
+	defer store.Tasks.Close()
+
+	events, ok := store.Tasks.(*repo.TaskEvents)
+	if !ok {
+		log.Fatal(asOfUsage)
+	}
+	tasks, err := events.StateAt(context.Background(), at)
+	if err != nil {
+		log.Fatalf("Could not rebuild the tasks: %s", err)
+	}
+	for _, task := range tasks {
+		log.Printf("%+v", task)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gochallenges/internal/auth"
+	"log"
+	"os"
+)
+
+const keysUsage = "usage: keys list|add HS256|RS256|EdDSA|remove KID"
+
+// runKeys manages the key set in JWT_KEYS_FILE, which servers only read on start.
+func runKeys(keysFile string, args []string) {
+	if keysFile == "" {
+		log.Fatal("JWT_KEYS_FILE is not set")
+	}
+	if len(args) == 0 {
+		log.Fatal(keysUsage)
+	}
+
+	keys, err := auth.LoadKeySet(keysFile)
+	if errors.Is(err, os.ErrNotExist) && args[0] == "add" {
+		keys, err = &auth.KeySet{}, nil
+	}
+	if err != nil {
+		log.Fatalf("Could not load keys: %s", err)
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+	case args[0] == "add" && len(args) == 2:
+		key, err := auth.GenerateKey(args[1])
+		if err != nil {
+			log.Fatalf("Could not generate key: %s", err)
+		}
+		keys.Add(key)
+	case args[0] == "remove" && len(args) == 2:
+		if !keys.Remove(args[1]) {
+			log.Fatalf("No key %q", args[1])
+		}
+	default:
+		log.Fatal(keysUsage)
+	}
+
+	if args[0] != "list" {
+		if err := keys.Save(keysFile); err != nil {
+			log.Fatalf("Could not save keys: %s", err)
+		}
+	}
+
+	for i, key := range keys.Keys {
+		signing := ""
+		if i == 0 {
+			signing = "signing"
+		}
+		fmt.Printf("%-30s %-6s %s\n", key.ID, key.Algorithm, signing)
+	}
+}
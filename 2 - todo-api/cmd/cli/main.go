@@ -1,26 +1,59 @@
 package main
 
 import (
+	"context"
+	"gochallenges/internal/model"
 	repo "gochallenges/internal/repository"
 	"gochallenges/pkg"
 	"log"
+	"os"
 )
 
 func main() {
 	dbConfig := pkg.GetDbConfig()
-	dbConnn := pkg.GetMysqlDbConnection(dbConfig)
+	dbImpl := pkg.GetDbImplementation()
 
-	tasksRepo, err := repo.NewTaskSql(dbConfig.Driver, dbConnn)
-	if err != nil {
-		log.Fatalf("Could not connect to database: %s", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(dbImpl, dbConfig, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "apikeys" {
+		runApiKeys(dbImpl, dbConfig, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "purge" {
+		runPurge(dbImpl, dbConfig, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "asof" {
+		runAsOf(dbImpl, dbConfig, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		runKeys(pkg.GetJwtConfig().KeysFile, os.Args[2:])
+		return
 	}
 
-	tasks, err := tasksRepo.FindAll()
+	store, _, err := repo.Open(dbImpl, dbConfig)
 	if err != nil {
-		log.Fatalf("Could not get tasks: %s", err)
+		log.Fatalf("Could not connect to database: %s", err)
 	}
+	defer store.Tasks.Close()
+
+	page := model.PageRequest{Size: model.MaxPageSize}
+	for {
+		tasks, err := store.Tasks.FindAll(context.Background(), 0, page)
+		if err != nil {
+			log.Fatalf("Could not get tasks: %s", err)
+		}
+
+		for _, task := range tasks.Tasks {
+			log.Printf("%+v", task)
+		}
 
-	for _, task := range tasks {
-		log.Printf("%+v", task)
+		if tasks.NextPageToken == "" {
+			break
+		}
+		page.Token = tasks.NextPageToken
 	}
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gochallenges/internal/migration"
+	repo "gochallenges/internal/repository"
+	"gochallenges/pkg"
+	"log"
+	"strconv"
+	"time"
+)
+
+const migrateUsage = "usage: migrate up|down|status|to N"
+
+func runMigrate(dbImpl string, dbConfig pkg.DbConfig, args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	db, dialect, err := repo.OpenDatabase(dbImpl, dbConfig)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %s", err)
+	}
+	defer db.Close()
+
+	migrator, err := migration.New(db, dialect)
+	if err != nil {
+		log.Fatalf("Could not load migrations: %s", err)
+	}
+
+	ctx := context.Background()
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		err = migrator.Up(ctx)
+	case args[0] == "down" && len(args) == 1:
+		err = migrator.Down(ctx)
+	case args[0] == "to" && len(args) == 2:
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			log.Fatal(migrateUsage)
+		}
+		err = migrator.To(ctx, version)
+	case args[0] == "status" && len(args) == 1:
+	default:
+		log.Fatal(migrateUsage)
+	}
+	if err != nil {
+		log.Fatalf("Could not migrate: %s", err)
+	}
+
+	printMigrationStatus(ctx, migrator)
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migration.Migrator) {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		log.Fatalf("Could not get migration status: %s", err)
+	}
+
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%04d %-30s %s\n", status.Version, status.Name, applied)
+	}
+}
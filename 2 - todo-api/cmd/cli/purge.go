@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	repo "gochallenges/internal/repository"
+	"gochallenges/internal/service"
+	"gochallenges/pkg"
+	"log"
+	"time"
+)
+
+// runPurge deletes the tasks trashed more than -days ago, for operators running it from cron.
+func runPurge(dbImpl string, dbConfig pkg.DbConfig, args []string) {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	days := flags.Int("days", pkg.GetTrashRetentionDays(), "purge the tasks trashed more than this many days ago, all of them when 0")
+	flags.Parse(args)
+	if *days < 0 || flags.NArg() > 0 {
+		log.Fatal("usage: purge [-days N]")
+	}
+
+	store, _, err := repo.Open(dbImpl, dbConfig)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %s", err)
+	}
+	defer store.Tasks.Close()
+
+	tasks := service.NewTask(store, nil)
+	purged, err := tasks.PurgeTrash(context.Background(), time.Duration(*days)*24*time.Hour)
+	if err != nil {
+		log.Fatalf("Could not purge the trash: %s", err)
+	}
+	fmt.Printf("purged %d tasks\n", purged)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gochallenges/internal/model"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "gochallenges/api/proto"
+)
+
+const importUsage = "usage: import [-format jsonl|csv] FILE"
+
+type taskReader func() (model.Task, error)
+
+// doImportTasks skips lines that hold no task, so the server numbers failed tasks in the order sent.
+func doImportTasks(c pb.TasksServiceClient, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "jsonl or csv, by default from the file extension")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatal(importUsage)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("could not open the import file: %v", err)
+	}
+	defer file.Close()
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(file.Name()), ".")
+	}
+	var next taskReader
+	switch *format {
+	case "jsonl", "ndjson":
+		next = jsonLinesReader(file)
+	case "csv":
+		if next, err = csvReader(file); err != nil {
+			log.Fatalf("could not read the CSV header: %v", err)
+		}
+	default:
+		log.Fatal(importUsage)
+	}
+
+	stream, err := c.ImportTasks(authorized(context.Background()))
+	if err != nil {
+		log.Fatalf("error calling ImportTasks RPC: %v", err)
+	}
+	for {
+		task, err := next()
+		if err == io.EOF {
+			break
+		}
+		var skipped skippedError
+		if errors.As(err, &skipped) {
+			log.Printf("skipped %v", err)
+			continue
+		}
+		if err != nil {
+			log.Fatalf("could not read the import file: %v", err)
+		}
+		// A failed Send means the server ended the call; CloseAndRecv says why.
+		if err := stream.Send(toPbTask(task)); err != nil {
+			break
+		}
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		log.Fatalf("error importing tasks: %v", err)
+	}
+	log.Printf("received %d, imported %d, failed %d", res.GetReceived(), res.GetImported(), res.GetFailed())
+	for _, importErr := range res.GetErrors() {
+		log.Printf("task #%d: %s", importErr.GetRow(), importErr.GetError().GetMessage())
+	}
+}
+
+// skippedError is a line that holds no task; it is reported instead of ending the import.
+type skippedError struct {
+	line int
+	err  error
+}
+
+func (e skippedError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+func jsonLinesReader(r io.Reader) taskReader {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	return func() (model.Task, error) {
+		for lines.Scan() {
+			line++
+			if strings.TrimSpace(lines.Text()) == "" {
+				continue
+			}
+			var task model.Task
+			if err := json.Unmarshal(lines.Bytes(), &task); err != nil {
+				return task, skippedError{line, err}
+			}
+			return task, nil
+		}
+		if err := lines.Err(); err != nil {
+			return model.Task{}, err
+		}
+		return model.Task{}, io.EOF
+	}
+}
+
+// csvReader takes the task columns in any order; all but name may be left out.
+func csvReader(r io.Reader) (taskReader, error) {
+	records := csv.NewReader(r)
+	header, err := records.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("no name column")
+	}
+
+	return func() (model.Task, error) {
+		record, err := records.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+			return model.Task{}, skippedError{parseErr.StartLine, parseErr.Err}
+		}
+		if err != nil {
+			return model.Task{}, err
+		}
+		line, _ := records.FieldPos(0)
+		task, err := parseCsvTask(columns, record)
+		if err != nil {
+			return task, skippedError{line, err}
+		}
+		return task, nil
+	}, nil
+}
+
+func parseCsvTask(columns map[string]int, record []string) (model.Task, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var task model.Task
+	var err error
+	task.Name = field("name")
+	task.Description = field("description")
+	if id := field("id"); id != "" {
+		if task.ID, err = strconv.Atoi(id); err != nil {
+			return task, fmt.Errorf("invalid id %q", id)
+		}
+	}
+	if parentId := field("parent_id"); parentId != "" {
+		id, err := strconv.Atoi(parentId)
+		if err != nil {
+			return task, fmt.Errorf("invalid parent_id %q", parentId)
+		}
+		task.ParentID = &id
+	}
+	if projectId := field("project_id"); projectId != "" {
+		id, err := strconv.Atoi(projectId)
+		if err != nil {
+			return task, fmt.Errorf("invalid project_id %q", projectId)
+		}
+		task.ProjectID = &id
+	}
+	if completed := field("completed"); completed != "" {
+		if task.Completed, err = strconv.ParseBool(completed); err != nil {
+			return task, fmt.Errorf("invalid completed %q", completed)
+		}
+	}
+	if dueAt := field("due_at"); dueAt != "" {
+		t, err := time.Parse(time.RFC3339, dueAt)
+		if err != nil {
+			return task, fmt.Errorf("invalid due_at %q", dueAt)
+		}
+		task.DueAt = &t
+	}
+	if task.Priority, err = model.ParsePriority(field("priority")); err != nil {
+		return task, err
+	}
+	return task, nil
+}
+
+func toPbTask(task model.Task) *pb.Task {
+	pbTask := &pb.Task{
+		Id:          int32(task.ID),
+		Name:        task.Name,
+		Completed:   task.Completed,
+		Description: task.Description,
+		Priority:    pb.Priority(task.Priority),
+	}
+	if task.DueAt != nil {
+		pbTask.DueAt = timestamppb.New(*task.DueAt)
+	}
+	if task.ParentID != nil {
+		parentId := int32(*task.ParentID)
+		pbTask.ParentId = &parentId
+	}
+	if task.ProjectID != nil {
+		projectId := int32(*task.ProjectID)
+		pbTask.ProjectId = &projectId
+	}
+	return pbTask
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -19,5 +20,13 @@ func main() {
 	defer conn.Close()
 
 	c := pb.NewTasksServiceClient(conn)
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		doWatchTasks(c, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		doImportTasks(c, os.Args[2:])
+		return
+	}
 	doGetAllTasks(c)
 }
@@ -2,16 +2,69 @@ package main
 
 import (
 	"context"
+	"flag"
+	"gochallenges/pkg"
 	"log"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	pb "gochallenges/api/proto"
 )
 
+func authorized(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", pkg.GetBearerToken())
+}
+
 func doGetAllTasks(c pb.TasksServiceClient) {
 	req := &pb.GetTasksRequest{}
-	res, err := c.GetTasks(context.Background(), req)
+	res, err := c.GetTasks(authorized(context.Background()), req)
 	if err != nil {
 		log.Fatalf("error calling Task RPC: %v", err)
 	}
 	log.Printf("Response from Tasks RPC: %v", res)
 }
+
+// doWatchTasks resumes from the last event it printed when the server drops the watch.
+func doWatchTasks(c pb.TasksServiceClient, args []string) {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	completed := flags.String("completed", "", "only watch completed (true) or open (false) tasks")
+	resumeToken := flags.String("resume", "", "resume token of the last event seen")
+	flags.Parse(args)
+
+	req := &pb.WatchTasksRequest{ResumeToken: *resumeToken}
+	if *completed != "" {
+		value, err := strconv.ParseBool(*completed)
+		if err != nil {
+			log.Fatalf("invalid -completed: %v", err)
+		}
+		req.Completed = &value
+	}
+
+	for {
+		stream, err := c.WatchTasks(authorized(context.Background()), req)
+		if err != nil {
+			log.Fatalf("error calling WatchTasks RPC: %v", err)
+		}
+
+		for {
+			res, err := stream.Recv()
+			if status.Code(err) == codes.Unavailable {
+				log.Printf("watch interrupted, resuming: %v", err)
+				break
+			}
+			if err != nil {
+				log.Fatalf("error watching tasks: %v", err)
+			}
+
+			req.ResumeToken = res.GetResumeToken()
+			if res.GetType() == pb.WatchTasksResponse_CURRENT {
+				log.Printf("%s (resume token %s)", res.GetType(), res.GetResumeToken())
+				continue
+			}
+			log.Printf("%s %v", res.GetType(), res.GetTask())
+		}
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
 	pb "gochallenges/api/proto"
+	"gochallenges/internal/auth"
+	"gochallenges/internal/event"
+	"gochallenges/internal/problem"
 	"gochallenges/internal/repository"
 	"gochallenges/internal/service"
 	"gochallenges/pkg"
@@ -32,13 +36,31 @@ func startRpcServer() {
 
 	log.Printf("Listening on %s", addr)
 
-	s := grpc.NewServer()
+	store, userRepository := loadRepositories()
+	userService := service.NewUser(userRepository, loadJwt())
+	interceptors := auth.NewInterceptors(userService.Authenticate, authorize)
 
-	var repository = loadTaskRepository()
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(problem.UnaryServerInterceptor, interceptors.Unary),
+		grpc.ChainStreamInterceptor(problem.StreamServerInterceptor, interceptors.Stream),
+	)
+
+	events := event.NewBus(event.DefaultReplaySize)
+	taskService := service.NewTask(store, events)
+	webhookService := service.NewWebhook(store.Webhooks)
 	pb.RegisterTasksServiceServer(s, &RpcServer{
-		taskRepository: repository,
-		taskService:    service.NewTask(repository),
+		taskRepository:  store.Tasks,
+		taskService:     taskService,
+		webhookService:  webhookService,
+		events:          events,
+		importChunkSize: pkg.GetImportChunkSize(),
+	})
+	pb.RegisterProjectsServiceServer(s, &ProjectsRpcServer{
+		taskRepository: store.Tasks,
+		projectService: service.NewProject(store, events),
 	})
+	startTrashPurge(taskService)
+	startWebhookDispatch(webhookService)
 
 	go func() {
 		log.Fatalln(s.Serve(lis))
@@ -56,11 +78,18 @@ func startRpcGateway() {
 		log.Fatalln("failed to dial grpc server:", err)
 	}
 
-	gwmux := runtime.NewServeMux()
+	gwmux := runtime.NewServeMux(
+		runtime.WithErrorHandler(problem.GatewayErrorHandler),
+		runtime.WithRoutingErrorHandler(problem.GatewayRoutingErrorHandler),
+	)
 	err = pb.RegisterTasksServiceHandler(context.Background(), gwmux, conn)
 	if err != nil {
 		log.Fatalln("Failed to register gRPC-Gateway:", err)
 	}
+	err = pb.RegisterProjectsServiceHandler(context.Background(), gwmux, conn)
+	if err != nil {
+		log.Fatalln("Failed to register gRPC-Gateway:", err)
+	}
 
 	gwServer := &http.Server{
 		Addr:    addrGw,
@@ -71,26 +100,44 @@ func startRpcGateway() {
 	log.Fatalln(gwServer.ListenAndServe())
 }
 
-func loadTaskRepository() repository.Task {
+func startTrashPurge(taskService service.Task) {
+	days := pkg.GetTrashRetentionDays()
+	if days == 0 {
+		return
+	}
+	go taskService.PurgeTrashEvery(context.Background(), time.Duration(days)*24*time.Hour, time.Hour)
+}
+
+func startWebhookDispatch(webhookService service.Webhook) {
+	interval := pkg.GetWebhookDispatchInterval()
+	if interval == 0 {
+		return
+	}
+	go webhookService.DispatchEvery(context.Background(), interval)
+}
+
+func loadRepositories() (repository.Store, repository.User) {
 	dbConfig := pkg.GetDbConfig()
-	dbConnn := pkg.GetMysqlDbConnection(dbConfig)
 	dbImpl := pkg.GetDbImplementation()
 
-	var taskRepository repository.Task
-	var err error
-
-	switch dbImpl {
-	case repository.DbVanilla:
-		taskRepository, err = repository.NewTaskSql(dbConfig.Driver, dbConnn)
-	case repository.DbOrm:
-		taskRepository, err = repository.NewTaskOrm(dbConnn)
-	default:
-		panic("Invalid db implementation")
+	store, userRepository, err := repository.Open(dbImpl, dbConfig)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %s", err)
 	}
 
+	return store, userRepository
+}
+
+func loadJwt() *auth.JWT {
+	config := pkg.GetJwtConfig()
+
+	jwt, err := auth.Load(config)
 	if err != nil {
-		log.Fatalf("Could not connect to database: %s", err)
+		log.Fatalf("Could not load the JWT keys: %s", err)
+	}
+	if config.KeysFile == "" {
+		log.Printf("JWT_KEYS_FILE is not set, JWTs are signed with a key that is lost on restart")
 	}
 
-	return taskRepository
+	return jwt
 }
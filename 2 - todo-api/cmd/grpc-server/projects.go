@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"gochallenges/internal/repository"
+	"gochallenges/internal/service"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "gochallenges/api/proto"
+)
+
+type ProjectsRpcServer struct {
+	pb.ProjectsServiceServer
+	taskRepository repository.Task
+	projectService service.Project
+}
+
+var projectDeleteModes = map[pb.ProjectDeleteMode]model.ProjectDeleteMode{
+	pb.ProjectDeleteMode_PROJECT_DELETE_MODE_UNSPECIFIED: model.ProjectArchive,
+	pb.ProjectDeleteMode_PROJECT_DELETE_MODE_ARCHIVE:     model.ProjectArchive,
+	pb.ProjectDeleteMode_PROJECT_DELETE_MODE_CASCADE:     model.ProjectCascade,
+	pb.ProjectDeleteMode_PROJECT_DELETE_MODE_REASSIGN:    model.ProjectReassign,
+}
+
+func (s *ProjectsRpcServer) CreateProject(ctx context.Context, in *pb.CreateProjectRequest) (*pb.CreateProjectResponse, error) {
+	project, err := s.projectService.Create(ctx, fromPbProject(in.GetProject()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateProjectResponse{Project: toPbProject(project)}, nil
+}
+
+func (s *ProjectsRpcServer) ListProjects(ctx context.Context, in *pb.ListProjectsRequest) (*pb.ListProjectsResponse, error) {
+	projects, err := s.projectService.List(ctx, in.GetArchived())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.ListProjectsResponse{}
+	for _, project := range projects {
+		response.Projects = append(response.Projects, toPbProject(project))
+	}
+	return response, nil
+}
+
+func (s *ProjectsRpcServer) GetProject(ctx context.Context, in *pb.GetProjectRequest) (*pb.GetProjectResponse, error) {
+	project, err := s.projectService.Find(ctx, int(in.GetId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetProjectResponse{Project: toPbProject(project)}, nil
+}
+
+func (s *ProjectsRpcServer) UpdateProject(ctx context.Context, in *pb.UpdateProjectRequest) (*pb.UpdateProjectResponse, error) {
+	project := fromPbProject(in.GetProject())
+	project.ID = int(in.GetId())
+
+	updatedProject, err := s.projectService.Update(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateProjectResponse{Project: toPbProject(updatedProject)}, nil
+}
+
+func (s *ProjectsRpcServer) DeleteProject(ctx context.Context, in *pb.DeleteProjectRequest) (*empty.Empty, error) {
+	if err := s.projectService.Delete(ctx, int(in.GetId()), toProjectDeleteMode(in.GetMode()), fromPbId(in.To)); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+func (s *ProjectsRpcServer) UnarchiveProject(ctx context.Context, in *pb.UnarchiveProjectRequest) (*pb.UnarchiveProjectResponse, error) {
+	project, err := s.projectService.Unarchive(ctx, int(in.GetId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UnarchiveProjectResponse{Project: toPbProject(project)}, nil
+}
+
+func (s *ProjectsRpcServer) GetProjectStats(ctx context.Context, in *pb.GetProjectStatsRequest) (*pb.ProjectStats, error) {
+	stats, err := s.projectService.Stats(ctx, int(in.GetId()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ProjectStats{
+		ProjectId:      int32(stats.ProjectID),
+		Total:          int32(stats.Total),
+		Completed:      int32(stats.Completed),
+		Open:           int32(stats.Open),
+		Overdue:        int32(stats.Overdue),
+		CompletionRate: stats.CompletionRate,
+	}, nil
+}
+
+func (s *ProjectsRpcServer) GetProjectTasks(ctx context.Context, in *pb.GetProjectTasksRequest) (*pb.GetTasksResponse, error) {
+	if _, err := s.projectService.Find(ctx, int(in.GetId())); err != nil {
+		return nil, err
+	}
+
+	q, err := query.Parse(in.GetFilter(), in.GetOrderBy())
+	if err != nil {
+		return nil, err
+	}
+	q.Filter = query.AndAlso(q.Filter, query.Comparison{Field: "project_id", Op: query.Eq, Value: int(in.GetId())})
+	if in.Completed != nil {
+		q.Filter = query.AndAlso(q.Filter, query.Comparison{Field: "completed", Op: query.Eq, Value: in.GetCompleted()})
+	}
+
+	page := model.PageRequest{Size: int(in.GetPageSize()), Token: in.GetPageToken()}
+	tasks, err := s.taskRepository.Find(ctx, q, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbTasks []*pb.Task
+	for _, task := range tasks.Tasks {
+		pbTasks = append(pbTasks, toPbTask(task))
+	}
+
+	return &pb.GetTasksResponse{
+		Tasks:         pbTasks,
+		NextPageToken: tasks.NextPageToken,
+	}, nil
+}
+
+func toProjectDeleteMode(mode pb.ProjectDeleteMode) model.ProjectDeleteMode {
+	if m, ok := projectDeleteModes[mode]; ok {
+		return m
+	}
+	return model.ProjectDeleteMode(mode.String())
+}
+
+func toPbProject(project model.Project) *pb.Project {
+	return &pb.Project{
+		Id:          int32(project.ID),
+		OwnerId:     int32(project.OwnerID),
+		Name:        project.Name,
+		Description: project.Description,
+		CreatedAt:   timestamppb.New(project.CreatedAt),
+		UpdatedAt:   timestamppb.New(project.UpdatedAt),
+		ArchivedAt:  toPbTimestamp(project.ArchivedAt),
+	}
+}
+
+func fromPbProject(project *pb.Project) model.Project {
+	return model.Project{
+		Name:        project.GetName(),
+		Description: project.GetDescription(),
+	}
+}
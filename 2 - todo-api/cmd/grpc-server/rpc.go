@@ -2,109 +2,643 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"gochallenges/internal/event"
 	"gochallenges/internal/model"
+	"gochallenges/internal/problem"
+	"gochallenges/internal/query"
 	"gochallenges/internal/repository"
 	"gochallenges/internal/service"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "gochallenges/api/proto"
 )
 
 type RpcServer struct {
 	pb.TasksServiceServer
-	taskRepository repository.Task
-	taskService    service.Task
+	taskRepository  repository.Task
+	taskService     service.Task
+	webhookService  service.Webhook
+	events          *event.Bus
+	importChunkSize int
 }
 
-func (s *RpcServer) GetTasks(ctx context.Context, in *pb.GetTasksRequest) (*pb.GetTasksResponse, error) {
-	var tasks []model.Task
-	var err error
+// methodScopes is the scope each method needs; methods missing from it are denied.
+var methodScopes = map[string]model.Scopes{
+	"/tasks.TasksService/GetTasks":    model.ScopeTasksRead,
+	"/tasks.TasksService/GetTaskById": model.ScopeTasksRead,
+	"/tasks.TasksService/CreateTask":  model.ScopeTasksWrite,
+	"/tasks.TasksService/UpdateTask":  model.ScopeTasksWrite,
+	"/tasks.TasksService/DeleteTask":  model.ScopeTasksDelete,
+	"/tasks.TasksService/WatchTasks":  model.ScopeTasksRead,
+	"/tasks.TasksService/GetTrash":    model.ScopeTasksRead,
+	"/tasks.TasksService/RestoreTask": model.ScopeTasksDelete,
+
+	"/tasks.TasksService/GetSubtasks": model.ScopeTasksRead,
+	"/tasks.TasksService/GetTaskTree": model.ScopeTasksRead,
+
+	"/tasks.TasksService/GetTaskHistory": model.ScopeTasksRead,
+	"/tasks.TasksService/GetAuditLog":    model.ScopeAdmin,
+
+	"/tasks.TasksService/BatchCreateTasks": model.ScopeTasksWrite,
+	"/tasks.TasksService/BatchUpdateTasks": model.ScopeTasksWrite,
+	"/tasks.TasksService/BatchDeleteTasks": model.ScopeTasksDelete,
+	"/tasks.TasksService/ImportTasks":      model.ScopeTasksWrite,
+
+	"/tasks.TasksService/CreateWebhook":            model.ScopeAdmin,
+	"/tasks.TasksService/ListWebhooks":             model.ScopeAdmin,
+	"/tasks.TasksService/DeleteWebhook":            model.ScopeAdmin,
+	"/tasks.TasksService/ListWebhookDeliveries":    model.ScopeAdmin,
+	"/tasks.TasksService/RedeliverWebhookDelivery": model.ScopeAdmin,
+
+	"/tasks.ProjectsService/CreateProject":    model.ScopeTasksWrite,
+	"/tasks.ProjectsService/ListProjects":     model.ScopeTasksRead,
+	"/tasks.ProjectsService/GetProject":       model.ScopeTasksRead,
+	"/tasks.ProjectsService/UpdateProject":    model.ScopeTasksWrite,
+	"/tasks.ProjectsService/DeleteProject":    model.ScopeTasksDelete,
+	"/tasks.ProjectsService/UnarchiveProject": model.ScopeTasksWrite,
+	"/tasks.ProjectsService/GetProjectStats":  model.ScopeTasksRead,
+	"/tasks.ProjectsService/GetProjectTasks":  model.ScopeTasksRead,
+}
+
+var batchModes = map[pb.BatchMode]model.BatchMode{
+	pb.BatchMode_BATCH_MODE_ALL_OR_NOTHING: model.BatchAllOrNothing,
+	pb.BatchMode_BATCH_MODE_PER_ITEM:       model.BatchPerItem,
+}
+
+var completionModes = map[pb.CompletionMode]model.CompletionMode{
+	pb.CompletionMode_COMPLETION_MODE_UNSPECIFIED: model.CompleteStrict,
+	pb.CompletionMode_COMPLETION_MODE_STRICT:      model.CompleteStrict,
+	pb.CompletionMode_COMPLETION_MODE_FORCE:       model.CompleteForce,
+	pb.CompletionMode_COMPLETION_MODE_CASCADE:     model.CompleteCascade,
+}
+
+var auditOperations = map[model.AuditOperation]pb.AuditRecord_Operation{
+	model.AuditCreate:  pb.AuditRecord_CREATE,
+	model.AuditUpdate:  pb.AuditRecord_UPDATE,
+	model.AuditDelete:  pb.AuditRecord_DELETE,
+	model.AuditRestore: pb.AuditRecord_RESTORE,
+}
 
-	if in.String() == "" {
-		tasks, err = s.taskRepository.FindAll()
-	} else {
-		tasks, err = s.taskRepository.FindByStatus(in.GetCompleted())
+var deliveryStatuses = map[model.DeliveryStatus]pb.DeliveryStatus{
+	model.DeliveryPending: pb.DeliveryStatus_DELIVERY_STATUS_PENDING,
+	model.DeliveryDone:    pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED,
+	model.DeliveryDead:    pb.DeliveryStatus_DELIVERY_STATUS_DEAD,
+}
+
+var watchTypes = map[string]pb.WatchTasksResponse_Type{
+	event.TaskCreated:  pb.WatchTasksResponse_CREATED,
+	event.TaskUpdated:  pb.WatchTasksResponse_UPDATED,
+	event.TaskDeleted:  pb.WatchTasksResponse_DELETED,
+	event.TaskRestored: pb.WatchTasksResponse_RESTORED,
+}
+
+func authorize(ctx context.Context, user model.User, fullMethod string) error {
+	scope, ok := methodScopes[fullMethod]
+	if !ok {
+		return fmt.Errorf("%w: %s is not allowed", model.ErrForbidden, fullMethod)
 	}
+	if !user.Scopes.Has(scope) {
+		return fmt.Errorf("%w: needs scope %s", model.ErrForbidden, scope)
+	}
+	return nil
+}
+
+func (s *RpcServer) GetTasks(ctx context.Context, in *pb.GetTasksRequest) (*pb.GetTasksResponse, error) {
+	q, err := query.Parse(in.GetFilter(), in.GetOrderBy())
+	if err != nil {
+		return nil, err
+	}
+	if in.Completed != nil {
+		q.Filter = query.AndAlso(q.Filter, query.Comparison{Field: "completed", Op: query.Eq, Value: in.GetCompleted()})
+	}
+
+	page := model.PageRequest{Size: int(in.GetPageSize()), Token: in.GetPageToken()}
+	tasks, err := s.taskRepository.Find(ctx, q, page)
 	if err != nil {
 		return nil, err
 	}
 
 	var pbTasks []*pb.Task
-	for _, task := range tasks {
-		pbTasks = append(pbTasks, &pb.Task{
-			Id:        int32(task.ID),
-			Name:      task.Name,
-			Completed: task.Completed,
-		})
+	for _, task := range tasks.Tasks {
+		pbTasks = append(pbTasks, toPbTask(task))
 	}
 
 	return &pb.GetTasksResponse{
-		Tasks: pbTasks,
+		Tasks:         pbTasks,
+		NextPageToken: tasks.NextPageToken,
 	}, nil
 }
 
 func (s *RpcServer) GetTaskById(ctx context.Context, in *pb.GetTasksByIdRequest) (*pb.GetTasksByIdResponse, error) {
-	task, err := s.taskRepository.FindByID(int(in.Id))
+	task, err := s.taskRepository.FindByID(ctx, int(in.Id))
 	if err != nil {
 		return nil, err
 	}
 
 	return &pb.GetTasksByIdResponse{
-		Task: &pb.Task{
-			Id:        int32(task.ID),
-			Name:      task.Name,
-			Completed: task.Completed,
-		},
+		Task: toPbTask(task),
 	}, nil
 }
 
 func (s *RpcServer) CreateTask(ctx context.Context, in *pb.CreateTaskRequest) (*pb.CreateTaskResponse, error) {
-	var task = model.Task{
-		Name:      in.GetTask().Name,
-		Completed: in.GetTask().Completed,
-	}
+	var task = fromPbTask(in.GetTask())
+	task.ID = 0
 
-	createdTask, err := s.taskService.Create(task)
+	createdTask, replayed, err := s.taskService.CreateOnce(ctx, task, in.GetIdempotencyKey())
 	if err != nil {
 		return nil, err
 	}
+	if replayed {
+		grpc.SetHeader(ctx, metadata.Pairs("idempotent-replayed", "true"))
+	}
 
 	return &pb.CreateTaskResponse{
-		Task: &pb.Task{
-			Id:        int32(createdTask.ID),
-			Name:      createdTask.Name,
-			Completed: createdTask.Completed,
-		},
+		Task: toPbTask(createdTask),
 	}, nil
 }
 
 func (s *RpcServer) UpdateTask(ctx context.Context, in *pb.UpdateTaskRequest) (*pb.UpdateTaskResponse, error) {
-	var task = model.Task{
-		ID:        int(in.GetTask().Id),
-		Name:      in.GetTask().Name,
-		Completed: in.GetTask().Completed,
-	}
+	var task = fromPbTask(in.GetTask())
 
-	updatedTask, err := s.taskService.Update(task)
+	updatedTask, err := s.taskService.UpdateWith(ctx, task, toCompletionMode(in.GetCompletion()))
 	if err != nil {
 		return nil, err
 	}
 
 	return &pb.UpdateTaskResponse{
-		Task: &pb.Task{
-			Id:        int32(updatedTask.ID),
-			Name:      updatedTask.Name,
-			Completed: updatedTask.Completed,
-		},
+		Task: toPbTask(updatedTask),
 	}, nil
 }
 
 func (s *RpcServer) DeleteTask(ctx context.Context, in *pb.DeleteTaskRequest) (*empty.Empty, error) {
-	err := s.taskService.Delete(int(in.Id))
+	err := s.taskService.Delete(ctx, int(in.GetId()), int(in.GetVersion()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &empty.Empty{}, nil
+}
+
+func (s *RpcServer) GetTrash(ctx context.Context, in *pb.GetTrashRequest) (*pb.GetTasksResponse, error) {
+	page := model.PageRequest{Size: int(in.GetPageSize()), Token: in.GetPageToken()}
+	tasks, err := s.taskRepository.FindTrash(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbTasks []*pb.Task
+	for _, task := range tasks.Tasks {
+		pbTasks = append(pbTasks, toPbTask(task))
+	}
+
+	return &pb.GetTasksResponse{
+		Tasks:         pbTasks,
+		NextPageToken: tasks.NextPageToken,
+	}, nil
+}
+
+func (s *RpcServer) RestoreTask(ctx context.Context, in *pb.RestoreTaskRequest) (*pb.RestoreTaskResponse, error) {
+	restoredTask, err := s.taskService.Restore(ctx, int(in.GetId()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.RestoreTaskResponse{
+		Task: toPbTask(restoredTask),
+	}, nil
+}
+
+func (s *RpcServer) GetSubtasks(ctx context.Context, in *pb.GetSubtasksRequest) (*pb.GetTasksResponse, error) {
+	if in.GetId() <= 0 {
+		return nil, model.ErrInvalidTaskId
+	}
+
+	q, err := query.Parse(in.GetFilter(), in.GetOrderBy())
+	if err != nil {
+		return nil, err
+	}
+	if in.Completed != nil {
+		q.Filter = query.AndAlso(q.Filter, query.Comparison{Field: "completed", Op: query.Eq, Value: in.GetCompleted()})
+	}
+
+	page := model.PageRequest{Size: int(in.GetPageSize()), Token: in.GetPageToken()}
+	tasks, err := s.taskService.Subtasks(ctx, int(in.GetId()), q, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbTasks []*pb.Task
+	for _, task := range tasks.Tasks {
+		pbTasks = append(pbTasks, toPbTask(task))
+	}
+
+	return &pb.GetTasksResponse{
+		Tasks:         pbTasks,
+		NextPageToken: tasks.NextPageToken,
+	}, nil
+}
+
+func (s *RpcServer) GetTaskTree(ctx context.Context, in *pb.GetTaskTreeRequest) (*pb.TaskTree, error) {
+	if in.GetId() <= 0 {
+		return nil, model.ErrInvalidTaskId
+	}
+
+	tree, err := s.taskService.Tree(ctx, int(in.GetId()))
+	if err != nil {
+		return nil, err
+	}
+	return toPbTaskTree(tree), nil
+}
+
+func (s *RpcServer) GetTaskHistory(ctx context.Context, in *pb.GetTaskHistoryRequest) (*pb.GetAuditRecordsResponse, error) {
+	if in.GetId() <= 0 {
+		return nil, model.ErrInvalidTaskId
+	}
+
+	page := model.PageRequest{Size: int(in.GetPageSize()), Token: in.GetPageToken()}
+	records, err := s.taskService.History(ctx, model.AuditFilter{TaskID: int(in.GetId())}, page)
+	if err != nil {
+		return nil, err
+	}
+	return toPbAuditRecords(records), nil
+}
+
+func (s *RpcServer) GetAuditLog(ctx context.Context, in *pb.GetAuditLogRequest) (*pb.GetAuditRecordsResponse, error) {
+	filter := model.AuditFilter{
+		ActorID:   int(in.GetActorId()),
+		From:      fromPbTimestamp(in.GetFrom()),
+		To:        fromPbTimestamp(in.GetTo()),
+		AllOwners: true,
+	}
+	page := model.PageRequest{Size: int(in.GetPageSize()), Token: in.GetPageToken()}
+	records, err := s.taskService.History(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	return toPbAuditRecords(records), nil
+}
+
+func (s *RpcServer) BatchCreateTasks(ctx context.Context, in *pb.BatchCreateTasksRequest) (*pb.BatchTasksResponse, error) {
+	batch := model.Batch{Mode: toBatchMode(in.GetMode())}
+	for _, pbTask := range in.GetTasks() {
+		task := fromPbTask(pbTask)
+		task.ID = 0
+		batch.Create = append(batch.Create, task)
+	}
+
+	results, err := s.taskService.Batch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	return toPbBatch(results.Create), nil
+}
+
+func (s *RpcServer) BatchUpdateTasks(ctx context.Context, in *pb.BatchUpdateTasksRequest) (*pb.BatchTasksResponse, error) {
+	batch := model.Batch{Mode: toBatchMode(in.GetMode())}
+	for _, pbTask := range in.GetTasks() {
+		batch.Update = append(batch.Update, fromPbTask(pbTask))
+	}
+
+	results, err := s.taskService.Batch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	return toPbBatch(results.Update), nil
+}
+
+func (s *RpcServer) BatchDeleteTasks(ctx context.Context, in *pb.BatchDeleteTasksRequest) (*pb.BatchTasksResponse, error) {
+	batch := model.Batch{Mode: toBatchMode(in.GetMode())}
+	for _, ref := range in.GetTasks() {
+		batch.Delete = append(batch.Delete, model.TaskRef{ID: int(ref.GetId()), Version: int(ref.GetVersion())})
+	}
+
+	results, err := s.taskService.Batch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	return toPbBatch(results.Delete), nil
+}
+
+// ImportTasks receives a chunk at a time, so flow control holds the client back meanwhile.
+func (s *RpcServer) ImportTasks(stream pb.TasksService_ImportTasksServer) error {
+	summary, err := s.taskService.Import(stream.Context(), func() (model.Task, error) {
+		pbTask, err := stream.Recv()
+		if err != nil {
+			return model.Task{}, err
+		}
+		return fromPbTask(pbTask), nil
+	}, s.importChunkSize)
+	if err != nil {
+		return err
+	}
+
+	response := &pb.ImportTasksResponse{
+		Received: int32(summary.Received),
+		Imported: int32(summary.Imported),
+		Failed:   int32(summary.Failed),
+	}
+	for _, importErr := range summary.Errors {
+		response.Errors = append(response.Errors, &pb.ImportError{Row: int32(importErr.Row), Error: problem.Status(importErr.Err).Proto()})
+	}
+	return stream.SendAndClose(response)
+}
+
+func (s *RpcServer) CreateWebhook(ctx context.Context, in *pb.CreateWebhookRequest) (*pb.CreateWebhookResponse, error) {
+	webhook, err := s.webhookService.Register(ctx, model.Webhook{URL: in.GetUrl(), Events: in.GetEvents(), Secret: in.GetSecret()})
 	if err != nil {
 		return nil, err
 	}
+	return &pb.CreateWebhookResponse{Webhook: toPbWebhook(webhook)}, nil
+}
 
+func (s *RpcServer) ListWebhooks(ctx context.Context, in *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	webhooks, err := s.webhookService.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.ListWebhooksResponse{}
+	for _, webhook := range webhooks {
+		response.Webhooks = append(response.Webhooks, toPbWebhook(webhook))
+	}
+	return response, nil
+}
+
+func (s *RpcServer) DeleteWebhook(ctx context.Context, in *pb.DeleteWebhookRequest) (*empty.Empty, error) {
+	if err := s.webhookService.Delete(ctx, int(in.GetId())); err != nil {
+		return nil, err
+	}
 	return &empty.Empty{}, nil
 }
+
+func (s *RpcServer) ListWebhookDeliveries(ctx context.Context, in *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	deliveries, err := s.webhookService.Deliveries(ctx, int(in.GetId()), toDeliveryStatus(in.GetStatus()))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.ListWebhookDeliveriesResponse{}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, toPbDelivery(delivery))
+	}
+	return response, nil
+}
+
+func (s *RpcServer) RedeliverWebhookDelivery(ctx context.Context, in *pb.RedeliverWebhookDeliveryRequest) (*pb.RedeliverWebhookDeliveryResponse, error) {
+	delivery, err := s.webhookService.Redeliver(ctx, int(in.GetWebhookId()), int(in.GetId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RedeliverWebhookDeliveryResponse{Delivery: toPbDelivery(delivery)}, nil
+}
+
+// WatchTasks subscribes before it reads the snapshot, so no change in between is lost.
+func (s *RpcServer) WatchTasks(in *pb.WatchTasksRequest, stream pb.TasksService_WatchTasksServer) error {
+	ctx := stream.Context()
+
+	user, _ := model.UserFromContext(ctx)
+	filter := event.Filter{OwnerID: user.ID}
+	if in.Completed != nil {
+		completed := in.GetCompleted()
+		filter.Completed = &completed
+	}
+
+	var lastID uint64
+	resumable := false
+	if in.GetResumeToken() != "" {
+		lastID, resumable = s.events.ParseToken(in.GetResumeToken())
+	}
+	sub, missed, complete := s.events.Subscribe(lastID)
+	defer sub.Close()
+
+	if resumable && complete {
+		for _, e := range missed {
+			if err := s.sendEvent(stream, filter, e); err != nil {
+				return err
+			}
+		}
+	} else if err := s.sendSnapshot(stream, in, s.events.Token(sub.Start)); err != nil {
+		return err
+	}
+
+	current := &pb.WatchTasksResponse{Type: pb.WatchTasksResponse_CURRENT, ResumeToken: s.events.Token(sub.Start)}
+	if err := stream.Send(current); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, open := <-sub.C:
+			if !open {
+				return model.ErrWatchFellBehind
+			}
+			if err := s.sendEvent(stream, filter, e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *RpcServer) sendSnapshot(stream pb.TasksService_WatchTasksServer, in *pb.WatchTasksRequest, resumeToken string) error {
+	q, err := query.Parse("", "")
+	if err != nil {
+		return err
+	}
+	if in.Completed != nil {
+		q.Filter = query.AndAlso(q.Filter, query.Comparison{Field: "completed", Op: query.Eq, Value: in.GetCompleted()})
+	}
+
+	page := model.PageRequest{Size: model.MaxPageSize}
+	for {
+		tasks, err := s.taskRepository.Find(stream.Context(), q, page)
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks.Tasks {
+			if err := stream.Send(&pb.WatchTasksResponse{Type: pb.WatchTasksResponse_SNAPSHOT, Task: toPbTask(task), ResumeToken: resumeToken}); err != nil {
+				return err
+			}
+		}
+
+		if tasks.NextPageToken == "" {
+			return nil
+		}
+		page.Token = tasks.NextPageToken
+	}
+}
+
+func (s *RpcServer) sendEvent(stream pb.TasksService_WatchTasksServer, filter event.Filter, e event.Event) error {
+	if !filter.Sees(e) {
+		return nil
+	}
+	return stream.Send(&pb.WatchTasksResponse{Type: watchTypes[e.Type], Task: toPbTask(e.Task), ResumeToken: s.events.Token(e.ID)})
+}
+
+func toBatchMode(mode pb.BatchMode) model.BatchMode {
+	if m, ok := batchModes[mode]; ok {
+		return m
+	}
+	return model.BatchMode(mode.String())
+}
+
+func toCompletionMode(mode pb.CompletionMode) model.CompletionMode {
+	if m, ok := completionModes[mode]; ok {
+		return m
+	}
+	return model.CompletionMode(mode.String())
+}
+
+// toDeliveryStatus maps unspecified to no filter and leaves unknown statuses to the service.
+func toDeliveryStatus(status pb.DeliveryStatus) model.DeliveryStatus {
+	if status == pb.DeliveryStatus_DELIVERY_STATUS_UNSPECIFIED {
+		return ""
+	}
+	for s, pbStatus := range deliveryStatuses {
+		if pbStatus == status {
+			return s
+		}
+	}
+	return model.DeliveryStatus(status.String())
+}
+
+func toPbBatch(results []model.BatchResult) *pb.BatchTasksResponse {
+	response := &pb.BatchTasksResponse{}
+	for _, result := range results {
+		if result.Err != nil {
+			response.Results = append(response.Results, &pb.BatchResult{Error: problem.Status(result.Err).Proto()})
+			continue
+		}
+		response.Results = append(response.Results, &pb.BatchResult{Task: toPbTask(result.Task)})
+	}
+	return response
+}
+
+func toPbAuditRecords(page model.AuditPage) *pb.GetAuditRecordsResponse {
+	response := &pb.GetAuditRecordsResponse{NextPageToken: page.NextPageToken}
+	for _, record := range page.Records {
+		pbRecord := &pb.AuditRecord{
+			Id:        int32(record.ID),
+			TaskId:    int32(record.TaskID),
+			OwnerId:   int32(record.OwnerID),
+			ActorId:   int32(record.ActorID),
+			Operation: auditOperations[record.Operation],
+			CreatedAt: timestamppb.New(record.CreatedAt),
+		}
+		if record.Before != nil {
+			pbRecord.Before = toPbTask(*record.Before)
+		}
+		if record.After != nil {
+			pbRecord.After = toPbTask(*record.After)
+		}
+		response.Records = append(response.Records, pbRecord)
+	}
+	return response
+}
+
+func toPbWebhook(webhook model.Webhook) *pb.Webhook {
+	return &pb.Webhook{
+		Id:        int32(webhook.ID),
+		OwnerId:   int32(webhook.OwnerID),
+		Url:       webhook.URL,
+		Events:    webhook.Events,
+		Secret:    webhook.Secret,
+		CreatedAt: timestamppb.New(webhook.CreatedAt),
+	}
+}
+
+func toPbDelivery(delivery model.WebhookDelivery) *pb.WebhookDelivery {
+	return &pb.WebhookDelivery{
+		Id:            int32(delivery.ID),
+		WebhookId:     int32(delivery.WebhookID),
+		OwnerId:       int32(delivery.OwnerID),
+		EventType:     delivery.EventType,
+		Payload:       string(delivery.Payload),
+		Status:        deliveryStatuses[delivery.Status],
+		Attempts:      int32(delivery.Attempts),
+		NextAttemptAt: timestamppb.New(delivery.NextAttemptAt),
+		LastError:     delivery.LastError,
+		CreatedAt:     timestamppb.New(delivery.CreatedAt),
+		DeliveredAt:   toPbTimestamp(delivery.DeliveredAt),
+	}
+}
+
+func toPbTaskTree(tree model.TaskTree) *pb.TaskTree {
+	pbTree := &pb.TaskTree{Task: toPbTask(tree.Task)}
+	for _, subtree := range tree.Subtasks {
+		pbTree.Subtasks = append(pbTree.Subtasks, toPbTaskTree(subtree))
+	}
+	return pbTree
+}
+
+func toPbTask(task model.Task) *pb.Task {
+	return &pb.Task{
+		ParentId:    toPbId(task.ParentID),
+		ProjectId:   toPbId(task.ProjectID),
+		Id:          int32(task.ID),
+		OwnerId:     int32(task.OwnerID),
+		Name:        task.Name,
+		Completed:   task.Completed,
+		Description: task.Description,
+		DueAt:       toPbTimestamp(task.DueAt),
+		Priority:    pb.Priority(task.Priority),
+		CreatedAt:   timestamppb.New(task.CreatedAt),
+		UpdatedAt:   timestamppb.New(task.UpdatedAt),
+		CompletedAt: toPbTimestamp(task.CompletedAt),
+		DeletedAt:   toPbTimestamp(task.DeletedAt),
+		Version:     int32(task.Version),
+	}
+}
+
+// fromPbTask only reads the fields a client may set.
+func fromPbTask(task *pb.Task) model.Task {
+	return model.Task{
+		ID:          int(task.GetId()),
+		ParentID:    fromPbId(task.ParentId),
+		ProjectID:   fromPbId(task.ProjectId),
+		Name:        task.GetName(),
+		Completed:   task.GetCompleted(),
+		Description: task.GetDescription(),
+		DueAt:       fromPbTimestamp(task.GetDueAt()),
+		Priority:    model.Priority(task.GetPriority()),
+		Version:     int(task.GetVersion()),
+	}
+}
+
+func toPbId(id *int) *int32 {
+	if id == nil {
+		return nil
+	}
+	pbId := int32(*id)
+	return &pbId
+}
+
+func fromPbId(id *int32) *int {
+	if id == nil {
+		return nil
+	}
+	i := int(*id)
+	return &i
+}
+
+func toPbTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func fromPbTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
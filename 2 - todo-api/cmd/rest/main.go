@@ -1,41 +1,69 @@
 package main
 
 import (
+	"context"
 	"gochallenges/internal/api"
+	"gochallenges/internal/auth"
 	"gochallenges/internal/repository"
+	"gochallenges/internal/service"
 	"gochallenges/pkg"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
-	server := api.NewServer(loadTaskRepository())
+	store, userRepository := loadRepositories()
+	server := api.NewServer(store, userRepository, loadJwt())
+	startTrashPurge(store)
+	startWebhookDispatch(store.Webhooks)
 	err := http.ListenAndServe(":5000", server.Handler)
 	if err != nil {
 		log.Fatalf("Could not start server: %s", err)
 	}
 }
 
-func loadTaskRepository() repository.Task {
+// Purging publishes no events, so the service needs no bus.
+func startTrashPurge(store repository.Store) {
+	days := pkg.GetTrashRetentionDays()
+	if days == 0 {
+		return
+	}
+	taskService := service.NewTask(store, nil)
+	go taskService.PurgeTrashEvery(context.Background(), time.Duration(days)*24*time.Hour, time.Hour)
+}
+
+func startWebhookDispatch(webhookRepository repository.Webhooks) {
+	interval := pkg.GetWebhookDispatchInterval()
+	if interval == 0 {
+		return
+	}
+	webhookService := service.NewWebhook(webhookRepository)
+	go webhookService.DispatchEvery(context.Background(), interval)
+}
+
+func loadRepositories() (repository.Store, repository.User) {
 	dbConfig := pkg.GetDbConfig()
-	dbConnn := pkg.GetMysqlDbConnection(dbConfig)
 	dbImpl := pkg.GetDbImplementation()
 
-	var taskRepository repository.Task
-	var err error
-
-	switch dbImpl {
-	case repository.DbVanilla:
-		taskRepository, err = repository.NewTaskSql(dbConfig.Driver, dbConnn)
-	case repository.DbOrm:
-		taskRepository, err = repository.NewTaskOrm(dbConnn)
-	default:
-		panic("Invalid db implementation")
+	store, userRepository, err := repository.Open(dbImpl, dbConfig)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %s", err)
 	}
 
+	return store, userRepository
+}
+
+func loadJwt() *auth.JWT {
+	config := pkg.GetJwtConfig()
+
+	jwt, err := auth.Load(config)
 	if err != nil {
-		log.Fatalf("Could not connect to database: %s", err)
+		log.Fatalf("Could not load the JWT keys: %s", err)
+	}
+	if config.KeysFile == "" {
+		log.Printf("JWT_KEYS_FILE is not set, JWTs are signed with a key that is lost on restart")
 	}
 
-	return taskRepository
+	return jwt
 }
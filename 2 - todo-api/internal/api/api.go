@@ -1,19 +1,94 @@
 package api
 
 import (
+	"gochallenges/internal/auth"
 	"gochallenges/internal/controller"
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
 	"gochallenges/internal/repository"
 	"net/http"
 )
 
 type HttpServer struct {
 	http.Handler
-	tasksController controller.Task
+	tasksController    controller.Task
+	usersController    controller.User
+	webhooksController controller.Webhook
+	projectsController controller.Project
 }
 
-func NewServer(tasksRepository repository.Task) *HttpServer {
+func NewServer(store repository.Store, usersRepository repository.User, jwt *auth.JWT) *HttpServer {
 	s := new(HttpServer)
-	s.tasksController = controller.NewTask(tasksRepository)
-	s.Handler = http.HandlerFunc(s.tasksController.ServeHTTP)
+	events := event.NewBus(event.DefaultReplaySize)
+	s.tasksController = controller.NewTask(store, events)
+	s.usersController = controller.NewUser(usersRepository, jwt)
+	s.webhooksController = controller.NewWebhook(store.Webhooks)
+	s.projectsController = controller.NewProject(store, events)
+	authorized := func(scope model.Scopes, handler http.HandlerFunc) http.Handler {
+		return s.usersController.Authorized(scope, handler)
+	}
+
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/tasks", authorized(model.ScopeTasksRead, s.tasksController.List))
+	router.Handle(http.MethodPost, "/tasks", authorized(model.ScopeTasksWrite, s.tasksController.Create))
+	router.Handle(http.MethodPost, "/tasks:batch", authorized(model.ScopeTasksWrite, s.tasksController.Batch))
+	router.Handle(http.MethodGet, "/tasks/events", authorized(model.ScopeTasksRead, s.tasksController.Events))
+	router.Handle(http.MethodGet, "/tasks/trash", authorized(model.ScopeTasksRead, s.tasksController.Trash))
+	router.Handle(http.MethodGet, "/tasks/{id}", authorized(model.ScopeTasksRead, withTaskId(s.tasksController.GetById)))
+	router.Handle(http.MethodPut, "/tasks/{id}", authorized(model.ScopeTasksWrite, withTaskId(s.tasksController.Update)))
+	router.Handle(http.MethodPatch, "/tasks/{id}", authorized(model.ScopeTasksWrite, withTaskId(s.tasksController.Patch)))
+	router.Handle(http.MethodDelete, "/tasks/{id}", authorized(model.ScopeTasksDelete, withTaskId(s.tasksController.Delete)))
+	router.Handle(http.MethodPost, "/tasks/{id}:restore", authorized(model.ScopeTasksDelete, withTaskId(s.tasksController.Restore)))
+	router.Handle(http.MethodGet, "/tasks/{id}/history", authorized(model.ScopeTasksRead, withTaskId(s.tasksController.History)))
+	router.Handle(http.MethodGet, "/tasks/{id}/subtasks", authorized(model.ScopeTasksRead, withTaskId(s.tasksController.Subtasks)))
+	router.Handle(http.MethodGet, "/tasks/{id}/tree", authorized(model.ScopeTasksRead, withTaskId(s.tasksController.Tree)))
+	router.Handle(http.MethodGet, "/projects", authorized(model.ScopeTasksRead, s.projectsController.List))
+	router.Handle(http.MethodPost, "/projects", authorized(model.ScopeTasksWrite, s.projectsController.Create))
+	router.Handle(http.MethodGet, "/projects/{id}", authorized(model.ScopeTasksRead, withProjectId(s.projectsController.GetById)))
+	router.Handle(http.MethodPut, "/projects/{id}", authorized(model.ScopeTasksWrite, withProjectId(s.projectsController.Update)))
+	router.Handle(http.MethodDelete, "/projects/{id}", authorized(model.ScopeTasksDelete, withProjectId(s.projectsController.Delete)))
+	router.Handle(http.MethodPost, "/projects/{id}:unarchive", authorized(model.ScopeTasksWrite, withProjectId(s.projectsController.Unarchive)))
+	router.Handle(http.MethodGet, "/projects/{id}/tasks", authorized(model.ScopeTasksRead, withProjectId(s.projectsController.Tasks)))
+	router.Handle(http.MethodGet, "/projects/{id}/stats", authorized(model.ScopeTasksRead, withProjectId(s.projectsController.Stats)))
+	router.Handle(http.MethodGet, "/audit", authorized(model.ScopeAdmin, s.tasksController.Audit))
+	router.Handle(http.MethodGet, "/webhooks", authorized(model.ScopeAdmin, s.webhooksController.List))
+	router.Handle(http.MethodPost, "/webhooks", authorized(model.ScopeAdmin, s.webhooksController.Create))
+	router.Handle(http.MethodDelete, "/webhooks/{id}", authorized(model.ScopeAdmin, withWebhookId(s.webhooksController.Delete)))
+	router.Handle(http.MethodGet, "/webhooks/{id}/deliveries", authorized(model.ScopeAdmin, withWebhookId(s.webhooksController.Deliveries)))
+	router.Handle(http.MethodPost, "/webhooks/{id}/deliveries/{delivery_id}:redeliver", authorized(model.ScopeAdmin, withDeliveryId(s.webhooksController.Redeliver)))
+
+	// Registering, logging in and the public keys need no token.
+	router.HandleFunc(http.MethodPost, "/users", s.usersController.Register)
+	router.HandleFunc(http.MethodPost, "/tokens", s.usersController.CreateToken)
+	router.HandleFunc(http.MethodPost, "/auth/token", s.usersController.IssueJwt)
+	router.HandleFunc(http.MethodGet, "/.well-known/jwks.json", s.usersController.GetJwks)
+	router.Handle(http.MethodGet, "/tokens", authorized(model.ScopeAdmin, s.usersController.GetTokens))
+	router.Handle(http.MethodDelete, "/tokens/{id}", authorized(model.ScopeAdmin, withTokenId(s.usersController.RevokeToken)))
+
+	s.Handler = router
 	return s
 }
+
+func withTaskId(handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return controller.WithTaskId(func(r *http.Request) string { return PathValue(r, "id") }, handler)
+}
+
+func withProjectId(handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return controller.WithProjectId(func(r *http.Request) string { return PathValue(r, "id") }, handler)
+}
+
+func withWebhookId(handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return controller.WithWebhookId(func(r *http.Request) string { return PathValue(r, "id") }, handler)
+}
+
+func withDeliveryId(handler func(w http.ResponseWriter, r *http.Request, webhookId int, deliveryId int)) http.HandlerFunc {
+	return controller.WithDeliveryId(
+		func(r *http.Request) string { return PathValue(r, "id") },
+		func(r *http.Request) string { return PathValue(r, "delivery_id") },
+		handler,
+	)
+}
+
+func withTokenId(handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return controller.WithTokenId(func(r *http.Request) string { return PathValue(r, "id") }, handler)
+}
@@ -1,10 +1,14 @@
 package api_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"gochallenges/internal/controller"
+	"gochallenges/internal/api"
+	"gochallenges/internal/auth"
 	"gochallenges/internal/model"
+	"gochallenges/internal/problem"
+	"gochallenges/internal/query"
 	"gochallenges/internal/repository"
 	"gochallenges/pkg"
 	"io/ioutil"
@@ -13,42 +17,68 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestUnauthorized(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	repoMock := repository.NewTaskMock(ctrl)
-	ctrlMock := controller.NewTask(repoMock)
+	repoMock := repository.NewStoreMock(ctrl)
+	server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
 
 	w := httptest.NewRecorder()
 	r, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
 
 	r.Header.Set("Authorization", "wrongtoken")
-	ctrlMock.ServeHTTP(w, r)
+	server.ServeHTTP(w, r)
 
 	assertStatusCode(t, w.Result().StatusCode, http.StatusUnauthorized)
 }
 
+func TestRequestContextReachesRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := repository.NewStoreMock(ctrl)
+	server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repoMock.Tasks.EXPECT().FindAll(gomock.Any(), 0, gomock.Any()).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+		if ctx.Err() == nil {
+			t.Errorf("expected the cancelled request context")
+		}
+		return model.TaskPage{}, ctx.Err()
+	}).Times(1)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/tasks", nil)
+	r.Header.Set("Authorization", pkg.GetBearerToken())
+
+	server.ServeHTTP(w, r)
+}
+
 func TestGetAll(t *testing.T) {
 	tasks := []model.Task{{Name: "task mock", Completed: true}}
 	cases := []struct {
 		caseName           string
 		expectedError      error
 		expectedStatusCode int
-		expectedBehavior   func(m *repository.TaskMock)
+		expectedBehavior   func(m repository.StoreMock)
 		expectedBody       []model.Task
 	}{
 		{
 			caseName:           "successfully retrieve all tasks",
 			expectedError:      nil,
 			expectedStatusCode: http.StatusOK,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindAll().DoAndReturn(func() ([]model.Task, error) {
-					return tasks, nil
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 0, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{Tasks: tasks}, nil
 				}).Times(1)
 			},
 			expectedBody: tasks,
@@ -57,9 +87,9 @@ func TestGetAll(t *testing.T) {
 			caseName:           "error retrieving all tasks",
 			expectedError:      model.ErrExecuteQuery,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindAll().DoAndReturn(func() ([]model.Task, error) {
-					return nil, model.ErrExecuteQuery
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 0, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{}, model.ErrExecuteQuery
 				}).Times(1)
 			},
 		},
@@ -67,19 +97,19 @@ func TestGetAll(t *testing.T) {
 			caseName:           "internal server error - failed scanning rows",
 			expectedError:      model.ErrScanningRows,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindAll().DoAndReturn(func() ([]model.Task, error) {
-					return nil, model.ErrScanningRows
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 0, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{}, model.ErrScanningRows
 				}).Times(1)
 			},
 		},
 		{
 			caseName:           "internal server error - failed connect to database",
 			expectedError:      model.ErrConnectDatabase,
-			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindAll().DoAndReturn(func() ([]model.Task, error) {
-					return nil, model.ErrConnectDatabase
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 0, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{}, model.ErrConnectDatabase
 				}).Times(1)
 			},
 		},
@@ -90,8 +120,8 @@ func TestGetAll(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			repoMock := repository.NewTaskMock(ctrl)
-			ctrlMock := controller.NewTask(repoMock)
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
 
 			testCase.expectedBehavior(repoMock)
 
@@ -99,7 +129,7 @@ func TestGetAll(t *testing.T) {
 			r, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
 			r.Header.Set("Authorization", pkg.GetBearerToken())
 
-			ctrlMock.ServeHTTP(w, r)
+			server.ServeHTTP(w, r)
 
 			if testCase.expectedError != nil {
 				errorMessage, _ := ioutil.ReadAll(w.Body)
@@ -116,21 +146,183 @@ func TestGetAll(t *testing.T) {
 	}
 }
 
+func TestGetAllPaginated(t *testing.T) {
+	tasks := []model.Task{{ID: 1, Name: "task mock"}, {ID: 2, Name: "task mock"}}
+	cases := []struct {
+		caseName              string
+		query                 string
+		expectedStatusCode    int
+		expectedBehavior      func(m repository.StoreMock)
+		expectedBody          []model.Task
+		expectedNextPageToken string
+	}{
+		{
+			caseName:           "page size and token reach the repository",
+			query:              "?page_size=2&page_token=abc",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 0, model.PageRequest{Size: 2, Token: "abc"}).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{Tasks: tasks, NextPageToken: "next"}, nil
+				}).Times(1)
+			},
+			expectedBody:          tasks,
+			expectedNextPageToken: "next",
+		},
+		{
+			caseName:           "page size and token are combined with the status filter",
+			query:              "?completed=false&page_size=2",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByStatus(gomock.Any(), 0, false, model.PageRequest{Size: 2}).DoAndReturn(func(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{Tasks: tasks}, nil
+				}).Times(1)
+			},
+			expectedBody: tasks,
+		},
+		{
+			caseName:           "invalid page size",
+			query:              "?page_size=many",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "invalid page token",
+			query:              "?page_token=abc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 0, model.PageRequest{Token: "abc"}).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{}, model.ErrInvalidPageToken
+				}).Times(1)
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(http.MethodGet, "/tasks"+testCase.query, nil)
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+
+			server.ServeHTTP(w, r)
+
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			if testCase.expectedBody != nil {
+				var got []model.Task
+				json.NewDecoder(w.Body).Decode(&got)
+
+				assertResponseBody(t, got, testCase.expectedBody)
+				assertResponseBody(t, w.Header().Get("X-Next-Page-Token"), testCase.expectedNextPageToken)
+			}
+		})
+	}
+}
+
+func TestGetByQuery(t *testing.T) {
+	tasks := []model.Task{{ID: 1, Name: "deploy api"}}
+	cases := []struct {
+		caseName           string
+		query              string
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedBody       []model.Task
+	}{
+		{
+			caseName:           "filter and order by are parsed",
+			query:              `?filter=completed%3Dfalse+AND+name~%22deploy%22&order_by=name+desc&page_size=1`,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				q := query.Query{
+					Filter: query.And{
+						Left:  query.Comparison{Field: "completed", Op: query.Eq, Value: false},
+						Right: query.Comparison{Field: "name", Op: query.Contains, Value: "deploy"},
+					},
+					OrderBy: []query.Order{{Field: "name", Desc: true}},
+				}
+				m.Tasks.EXPECT().Find(gomock.Any(), q, model.PageRequest{Size: 1}).DoAndReturn(func(ctx context.Context, q query.Query, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{Tasks: tasks}, nil
+				}).Times(1)
+			},
+			expectedBody: tasks,
+		},
+		{
+			caseName:           "completed is added to the filter",
+			query:              `?order_by=id&completed=true`,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				q := query.Query{
+					Filter:  query.Comparison{Field: "completed", Op: query.Eq, Value: true},
+					OrderBy: []query.Order{{Field: "id"}},
+				}
+				m.Tasks.EXPECT().Find(gomock.Any(), q, model.PageRequest{}).DoAndReturn(func(ctx context.Context, q query.Query, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{Tasks: tasks}, nil
+				}).Times(1)
+			},
+			expectedBody: tasks,
+		},
+		{
+			caseName:           "invalid filter",
+			query:              `?filter=name%3D`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "invalid order by",
+			query:              `?order_by=name+sideways`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(http.MethodGet, "/tasks"+testCase.query, nil)
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+
+			server.ServeHTTP(w, r)
+
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			if testCase.expectedBody != nil {
+				var got []model.Task
+				json.NewDecoder(w.Body).Decode(&got)
+
+				assertResponseBody(t, got, testCase.expectedBody)
+			}
+		})
+	}
+}
+
 func TestGetById(t *testing.T) {
 	task := model.Task{ID: 1, Name: "task mock", Completed: true}
 	cases := []struct {
 		caseName           string
 		expectedError      error
 		expectedStatusCode int
-		expectedBehavior   func(m *repository.TaskMock)
+		expectedBehavior   func(m repository.StoreMock)
 		expectedBody       model.Task
 	}{
 		{
 			caseName:           "successfully retrieve a task",
 			expectedError:      nil,
 			expectedStatusCode: http.StatusOK,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
 			},
@@ -140,18 +332,28 @@ func TestGetById(t *testing.T) {
 			caseName:           "error retrieving a task",
 			expectedError:      model.ErrExecuteQuery,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, model.ErrExecuteQuery
 				}).Times(1)
 			},
 		},
+		{
+			caseName:           "task not found",
+			expectedError:      model.ErrTaskNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
+					return model.Task{}, model.ErrTaskNotFound
+				}).Times(1)
+			},
+		},
 		{
 			caseName:           "internal server error - failed scanning row",
 			expectedError:      model.ErrScanningRows,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, model.ErrScanningRows
 				}).Times(1)
 			},
@@ -159,9 +361,9 @@ func TestGetById(t *testing.T) {
 		{
 			caseName:           "internal server error - failed connect to database",
 			expectedError:      model.ErrConnectDatabase,
-			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, model.ErrConnectDatabase
 				}).Times(1)
 			},
@@ -173,8 +375,8 @@ func TestGetById(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			repoMock := repository.NewTaskMock(ctrl)
-			ctrlMock := controller.NewTask(repoMock)
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
 
 			testCase.expectedBehavior(repoMock)
 
@@ -182,7 +384,7 @@ func TestGetById(t *testing.T) {
 			r, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/tasks/%d", task.ID), nil)
 			r.Header.Set("Authorization", pkg.GetBearerToken())
 
-			ctrlMock.ServeHTTP(w, r)
+			server.ServeHTTP(w, r)
 
 			if testCase.expectedError != nil {
 				errorMessage, _ := ioutil.ReadAll(w.Body)
@@ -206,7 +408,7 @@ func TestGetByStatus(t *testing.T) {
 		caseName           string
 		expectedError      error
 		expectedStatusCode int
-		expectedBehavior   func(m *repository.TaskMock)
+		expectedBehavior   func(m repository.StoreMock)
 		expectedBody       []model.Task
 		expectedStatus     bool
 	}{
@@ -214,9 +416,9 @@ func TestGetByStatus(t *testing.T) {
 			caseName:           "successfully retrieve completed",
 			expectedError:      nil,
 			expectedStatusCode: http.StatusOK,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByStatus(true).DoAndReturn(func(completed bool) ([]model.Task, error) {
-					return completedTasks, nil
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByStatus(gomock.Any(), 0, true, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{Tasks: completedTasks}, nil
 				}).Times(1)
 			},
 			expectedBody:   completedTasks,
@@ -226,9 +428,9 @@ func TestGetByStatus(t *testing.T) {
 			caseName:           "successfully retrieve uncompleted",
 			expectedError:      nil,
 			expectedStatusCode: http.StatusOK,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByStatus(false).DoAndReturn(func(completed bool) ([]model.Task, error) {
-					return uncomplemtedTasks, nil
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByStatus(gomock.Any(), 0, false, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{Tasks: uncomplemtedTasks}, nil
 				}).Times(1)
 			},
 			expectedBody:   uncomplemtedTasks,
@@ -238,9 +440,9 @@ func TestGetByStatus(t *testing.T) {
 			caseName:           "error retrieving all tasks by status",
 			expectedError:      model.ErrExecuteQuery,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByStatus(false).DoAndReturn(func(completed bool) ([]model.Task, error) {
-					return nil, model.ErrExecuteQuery
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByStatus(gomock.Any(), 0, false, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{}, model.ErrExecuteQuery
 				}).Times(1)
 			},
 		},
@@ -248,19 +450,19 @@ func TestGetByStatus(t *testing.T) {
 			caseName:           "internal server error - failed scanning rows",
 			expectedError:      model.ErrScanningRows,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByStatus(false).DoAndReturn(func(completed bool) ([]model.Task, error) {
-					return nil, model.ErrScanningRows
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByStatus(gomock.Any(), 0, false, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{}, model.ErrScanningRows
 				}).Times(1)
 			},
 		},
 		{
 			caseName:           "internal server error - failed connect to database",
 			expectedError:      model.ErrConnectDatabase,
-			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByStatus(false).DoAndReturn(func(completed bool) ([]model.Task, error) {
-					return nil, model.ErrConnectDatabase
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByStatus(gomock.Any(), 0, false, model.PageRequest{}).DoAndReturn(func(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+					return model.TaskPage{}, model.ErrConnectDatabase
 				}).Times(1)
 			},
 		},
@@ -271,8 +473,8 @@ func TestGetByStatus(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			repoMock := repository.NewTaskMock(ctrl)
-			ctrlMock := controller.NewTask(repoMock)
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
 
 			testCase.expectedBehavior(repoMock)
 
@@ -280,7 +482,7 @@ func TestGetByStatus(t *testing.T) {
 			r, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/tasks?completed=%t", testCase.expectedStatus), nil)
 			r.Header.Set("Authorization", pkg.GetBearerToken())
 
-			ctrlMock.ServeHTTP(w, r)
+			server.ServeHTTP(w, r)
 
 			if testCase.expectedError != nil {
 				errorMessage, _ := ioutil.ReadAll(w.Body)
@@ -303,7 +505,7 @@ func TestCreate(t *testing.T) {
 		caseName           string
 		expectedError      error
 		expectedStatusCode int
-		expectedBehavior   func(m *repository.TaskMock)
+		expectedBehavior   func(m repository.StoreMock)
 		expectedBody       model.Task
 		requestBody        string
 	}{
@@ -311,8 +513,8 @@ func TestCreate(t *testing.T) {
 			caseName:           "successfully creating a task",
 			expectedError:      nil,
 			expectedStatusCode: http.StatusCreated,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().Create(task).DoAndReturn(func(newTask model.Task) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Create(gomock.Any(), task).DoAndReturn(func(ctx context.Context, newTask model.Task) (model.Task, error) {
 					return task, nil
 				}).Times(1)
 			},
@@ -323,8 +525,8 @@ func TestCreate(t *testing.T) {
 			caseName:           "error creating a task",
 			expectedError:      model.ErrExecuteQuery,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().Create(task).DoAndReturn(func(newTask model.Task) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Create(gomock.Any(), task).DoAndReturn(func(ctx context.Context, newTask model.Task) (model.Task, error) {
 					return task, model.ErrExecuteQuery
 				}).Times(1)
 			},
@@ -334,8 +536,8 @@ func TestCreate(t *testing.T) {
 			caseName:           "internal server error - failed preparing statement",
 			expectedError:      model.ErrPreparingStatemant,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().Create(task).DoAndReturn(func(newTask model.Task) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Create(gomock.Any(), task).DoAndReturn(func(ctx context.Context, newTask model.Task) (model.Task, error) {
 					return task, model.ErrPreparingStatemant
 				}).Times(1)
 			},
@@ -344,14 +546,46 @@ func TestCreate(t *testing.T) {
 		{
 			caseName:           "internal server error - failed connect to database",
 			expectedError:      model.ErrConnectDatabase,
-			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().Create(task).DoAndReturn(func(newTask model.Task) (model.Task, error) {
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Create(gomock.Any(), task).DoAndReturn(func(ctx context.Context, newTask model.Task) (model.Task, error) {
 					return task, model.ErrConnectDatabase
 				}).Times(1)
 			},
 			requestBody: `{"name": "study golang unit testing", "completed": false}`,
 		},
+		{
+			caseName:           "creating a completed task sets its completion time",
+			expectedError:      nil,
+			expectedStatusCode: http.StatusCreated,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, newTask model.Task) (model.Task, error) {
+					if newTask.CompletedAt == nil {
+						t.Errorf("expected completed_at to be set")
+					}
+					newTask.CompletedAt = nil
+					return newTask, nil
+				}).Times(1)
+			},
+			expectedBody: model.Task{Name: "done", Completed: true, Description: "details", Priority: model.PriorityHigh},
+			requestBody:  `{"name": "done", "completed": true, "description": "details", "priority": "high"}`,
+		},
+		{
+			caseName:           "conflict - task already exists",
+			expectedError:      model.ErrTaskAlreadyExists,
+			expectedStatusCode: http.StatusConflict,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Create(gomock.Any(), task).Return(model.Task{}, model.ErrTaskAlreadyExists).Times(1)
+			},
+			requestBody: `{"name": "study golang unit testing", "completed": false}`,
+		},
+		{
+			caseName:           "bad request - invalid priority",
+			expectedError:      model.ErrInvalidRequestBody,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+			requestBody:        `{"name": "study golang unit testing", "priority": "urgent"}`,
+		},
 	}
 
 	for _, testCase := range cases {
@@ -359,17 +593,18 @@ func TestCreate(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			repoMock := repository.NewTaskMock(ctrl)
-			ctrlMock := controller.NewTask(repoMock)
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
 
 			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
 
 			newReader := strings.NewReader(testCase.requestBody)
 			w := httptest.NewRecorder()
 			r, _ := http.NewRequest(http.MethodPost, "/tasks", newReader)
 			r.Header.Set("Authorization", pkg.GetBearerToken())
 
-			ctrlMock.ServeHTTP(w, r)
+			server.ServeHTTP(w, r)
 
 			if testCase.expectedError != nil {
 				errorMessage, _ := ioutil.ReadAll(w.Body)
@@ -386,13 +621,87 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestIdempotentCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := repository.NewStoreMock(ctrl)
+	server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+	created := model.Task{ID: 1, OwnerID: model.DefaultUserID, Name: "Task 1", Version: 1}
+	repoMock.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	repoMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+		r.Header.Set("Authorization", pkg.GetBearerToken())
+		r.Header.Set("Idempotency-Key", key)
+		server.ServeHTTP(w, r)
+		return w
+	}
+	assertCreated := func(w *httptest.ResponseRecorder, replayed string) {
+		t.Helper()
+		assertStatusCode(t, w.Result().StatusCode, http.StatusCreated)
+		var got model.Task
+		json.NewDecoder(w.Body).Decode(&got)
+		assertResponseBody(t, got, created)
+		if header := w.Header().Get("Idempotent-Replayed"); header != replayed {
+			t.Errorf("got Idempotent-Replayed %q want %q", header, replayed)
+		}
+	}
+
+	var stored model.IdempotencyKey
+	repoMock.Idempotency.EXPECT().FindIdempotencyKey(gomock.Any(), "retry-1").Return(model.IdempotencyKey{}, model.ErrIdempotencyKeyNotFound).Times(1)
+	repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+		return fn(repoMock.Store())
+	}).Times(1)
+	repoMock.Tasks.EXPECT().Create(gomock.Any(), model.Task{Name: "Task 1"}).Return(created, nil).Times(1)
+	repoMock.Idempotency.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, key model.IdempotencyKey) error {
+		if key.Key != "retry-1" || key.Task != created || !key.ExpiresAt.After(key.CreatedAt) {
+			t.Errorf("expected the key to hold the created task until it expires, got %+v", key)
+		}
+		stored = key
+		return nil
+	}).Times(1)
+	assertCreated(post("retry-1", `{"name": "Task 1"}`), "")
+
+	repoMock.Idempotency.EXPECT().FindIdempotencyKey(gomock.Any(), "retry-1").DoAndReturn(func(ctx context.Context, key string) (model.IdempotencyKey, error) {
+		return stored, nil
+	}).Times(2)
+	assertCreated(post("retry-1", `{"name": "Task 1", "completed": false}`), "true")
+
+	w := post("retry-1", `{"name": "Task 2"}`)
+	assertStatusCode(t, w.Result().StatusCode, http.StatusUnprocessableEntity)
+	body, _ := ioutil.ReadAll(w.Body)
+	assertError(t, string(body), model.ErrIdempotencyKeyReused.Error())
+
+	w = post(strings.Repeat("k", model.MaxIdempotencyKeyLength+1), `{"name": "Task 1"}`)
+	assertStatusCode(t, w.Result().StatusCode, http.StatusBadRequest)
+	body, _ = ioutil.ReadAll(w.Body)
+	assertError(t, string(body), model.ErrInvalidIdempotencyKey.Error())
+
+	// A retry racing the first request loses on the key and replays it.
+	gomock.InOrder(
+		repoMock.Idempotency.EXPECT().FindIdempotencyKey(gomock.Any(), "retry-2").Return(model.IdempotencyKey{}, model.ErrIdempotencyKeyNotFound).Times(1),
+		repoMock.Idempotency.EXPECT().FindIdempotencyKey(gomock.Any(), "retry-2").DoAndReturn(func(ctx context.Context, key string) (model.IdempotencyKey, error) {
+			return stored, nil
+		}).Times(1),
+	)
+	repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+		return fn(repoMock.Store())
+	}).Times(1)
+	repoMock.Tasks.EXPECT().Create(gomock.Any(), model.Task{Name: "Task 1"}).Return(model.Task{ID: 2, Name: "Task 1", Version: 1}, nil).Times(1)
+	repoMock.Idempotency.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(model.ErrIdempotencyKeyExists).Times(1)
+	assertCreated(post("retry-2", `{"name": "Task 1"}`), "true")
+}
+
 func TestUpdate(t *testing.T) {
 	task := model.Task{ID: 1, Name: "study golang unit testing", Completed: true}
 	cases := []struct {
 		caseName           string
 		expectedError      error
 		expectedStatusCode int
-		expectedBehavior   func(m *repository.TaskMock)
+		expectedBehavior   func(m repository.StoreMock)
 		expectedBody       model.Task
 		requestBody        string
 	}{
@@ -400,11 +709,11 @@ func TestUpdate(t *testing.T) {
 			caseName:           "successfully updating a task",
 			expectedError:      nil,
 			expectedStatusCode: http.StatusOK,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
-				m.EXPECT().Update(task).DoAndReturn(func(modifiedTask model.Task) (model.Task, error) {
+				m.Tasks.EXPECT().Update(gomock.Any(), task).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
 					return task, nil
 				}).Times(1)
 			},
@@ -415,11 +724,11 @@ func TestUpdate(t *testing.T) {
 			caseName:           "error updating a task",
 			expectedError:      model.ErrExecuteQuery,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
-				m.EXPECT().Update(task).DoAndReturn(func(modifiedTask model.Task) (model.Task, error) {
+				m.Tasks.EXPECT().Update(gomock.Any(), task).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
 					return task, model.ErrExecuteQuery
 				}).Times(1)
 			},
@@ -429,11 +738,11 @@ func TestUpdate(t *testing.T) {
 			caseName:           "internal server error - failed preparing statement",
 			expectedError:      model.ErrPreparingStatemant,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
-				m.EXPECT().Update(task).DoAndReturn(func(modifiedTask model.Task) (model.Task, error) {
+				m.Tasks.EXPECT().Update(gomock.Any(), task).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
 					return task, model.ErrPreparingStatemant
 				}).Times(1)
 			},
@@ -442,17 +751,68 @@ func TestUpdate(t *testing.T) {
 		{
 			caseName:           "internal server error - failed connect to database",
 			expectedError:      model.ErrConnectDatabase,
-			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
-				m.EXPECT().Update(task).DoAndReturn(func(modifiedTask model.Task) (model.Task, error) {
+				m.Tasks.EXPECT().Update(gomock.Any(), task).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
 					return task, model.ErrConnectDatabase
 				}).Times(1)
 			},
 			requestBody: `{"id": 1, "name": "study golang unit testing", "completed": true}`,
 		},
+		{
+			caseName:           "completing a task sets its completion time",
+			expectedError:      nil,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
+					return model.Task{ID: task.ID, Name: task.Name}, nil
+				}).Times(1)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
+					if modifiedTask.CompletedAt == nil {
+						t.Errorf("expected completed_at to be set")
+					}
+					modifiedTask.CompletedAt = nil
+					return modifiedTask, nil
+				}).Times(1)
+			},
+			expectedBody: task,
+			requestBody:  `{"id": 1, "name": "study golang unit testing", "completed": true}`,
+		},
+		{
+			caseName:           "task not found",
+			expectedError:      model.ErrTaskNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(model.Task{}, model.ErrTaskNotFound).Times(1)
+			},
+			requestBody: `{"id": 1, "name": "study golang unit testing", "completed": true}`,
+		},
+		{
+			caseName:           "bad request - id in the body differs from the path",
+			expectedError:      model.ErrInvalidTaskId,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+			requestBody:        `{"id": 2, "name": "study golang unit testing", "completed": true}`,
+		},
+		{
+			caseName:           "reopening a task clears its completion time",
+			expectedError:      nil,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				completedAt := model.Now()
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
+					return model.Task{ID: task.ID, Name: task.Name, Completed: true, CompletedAt: &completedAt}, nil
+				}).Times(1)
+				m.Tasks.EXPECT().Update(gomock.Any(), model.Task{ID: task.ID, Name: task.Name}).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
+					return modifiedTask, nil
+				}).Times(1)
+			},
+			expectedBody: model.Task{ID: task.ID, Name: task.Name},
+			requestBody:  `{"id": 1, "name": "study golang unit testing", "completed": false}`,
+		},
 	}
 
 	for _, testCase := range cases {
@@ -460,17 +820,18 @@ func TestUpdate(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			repoMock := repository.NewTaskMock(ctrl)
-			ctrlMock := controller.NewTask(repoMock)
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
 
 			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
 
 			newReader := strings.NewReader(testCase.requestBody)
 			w := httptest.NewRecorder()
 			r, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/tasks/%d", task.ID), newReader)
 			r.Header.Set("Authorization", pkg.GetBearerToken())
 
-			ctrlMock.ServeHTTP(w, r)
+			server.ServeHTTP(w, r)
 
 			if testCase.expectedError != nil {
 				errorMessage, _ := ioutil.ReadAll(w.Body)
@@ -493,17 +854,17 @@ func TestDelete(t *testing.T) {
 		caseName           string
 		expectedError      error
 		expectedStatusCode int
-		expectedBehavior   func(m *repository.TaskMock)
+		expectedBehavior   func(m repository.StoreMock)
 	}{
 		{
 			caseName:           "success deleteting a task",
 			expectedError:      nil,
 			expectedStatusCode: http.StatusOK,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
-				m.EXPECT().Delete(task.ID).DoAndReturn(func(id int) error {
+				m.Tasks.EXPECT().Delete(gomock.Any(), task.ID, 0).DoAndReturn(func(ctx context.Context, id int, version int) error {
 					return nil
 				}).Times(1)
 			},
@@ -512,11 +873,11 @@ func TestDelete(t *testing.T) {
 			caseName:           "error deleteting a task",
 			expectedError:      model.ErrExecuteQuery,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
-				m.EXPECT().Delete(task.ID).DoAndReturn(func(id int) error {
+				m.Tasks.EXPECT().Delete(gomock.Any(), task.ID, 0).DoAndReturn(func(ctx context.Context, id int, version int) error {
 					return model.ErrExecuteQuery
 				}).Times(1)
 			},
@@ -525,11 +886,11 @@ func TestDelete(t *testing.T) {
 			caseName:           "internal server error - failed preparing statement",
 			expectedError:      model.ErrPreparingStatemant,
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
-				m.EXPECT().Delete(task.ID).DoAndReturn(func(id int) error {
+				m.Tasks.EXPECT().Delete(gomock.Any(), task.ID, 0).DoAndReturn(func(ctx context.Context, id int, version int) error {
 					return model.ErrPreparingStatemant
 				}).Times(1)
 			},
@@ -537,12 +898,12 @@ func TestDelete(t *testing.T) {
 		{
 			caseName:           "internal server error - failed connect to database",
 			expectedError:      model.ErrConnectDatabase,
-			expectedStatusCode: http.StatusInternalServerError,
-			expectedBehavior: func(m *repository.TaskMock) {
-				m.EXPECT().FindByID(task.ID).DoAndReturn(func(id int) (model.Task, error) {
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).DoAndReturn(func(ctx context.Context, id int) (model.Task, error) {
 					return task, nil
 				}).Times(1)
-				m.EXPECT().Delete(task.ID).DoAndReturn(func(id int) error {
+				m.Tasks.EXPECT().Delete(gomock.Any(), task.ID, 0).DoAndReturn(func(ctx context.Context, id int, version int) error {
 					return model.ErrConnectDatabase
 				}).Times(1)
 			},
@@ -554,16 +915,17 @@ func TestDelete(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			repoMock := repository.NewTaskMock(ctrl)
-			ctrlMock := controller.NewTask(repoMock)
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
 
 			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
 
 			w := httptest.NewRecorder()
 			r, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/tasks/%d", task.ID), nil)
 			r.Header.Set("Authorization", pkg.GetBearerToken())
 
-			ctrlMock.ServeHTTP(w, r)
+			server.ServeHTTP(w, r)
 
 			if testCase.expectedError != nil {
 				errorMessage, _ := ioutil.ReadAll(w.Body)
@@ -577,11 +939,1742 @@ func TestDelete(t *testing.T) {
 	}
 }
 
-func assertError(t testing.TB, got, want string) {
-	t.Helper()
+func TestTrash(t *testing.T) {
+	deletedAt := model.Now()
+	tasks := []model.Task{{ID: 1, Name: "task mock", DeletedAt: &deletedAt, Version: 2}}
+	cases := []struct {
+		caseName           string
+		path               string
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedBody       []model.Task
+	}{
+		{
+			caseName:           "successfully list the trash",
+			path:               "/tasks/trash?page_size=10",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindTrash(gomock.Any(), model.PageRequest{Size: 10}).Return(model.TaskPage{Tasks: tasks}, nil).Times(1)
+			},
+			expectedBody: tasks,
+		},
+		{
+			caseName:           "error listing the trash",
+			path:               "/tasks/trash",
+			expectedError:      model.ErrExecuteQuery,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindTrash(gomock.Any(), model.PageRequest{}).Return(model.TaskPage{}, model.ErrExecuteQuery).Times(1)
+			},
+		},
+	}
 
-	if got != "" && want != "" && want != got {
-		t.Errorf("got %v want %v", got, want)
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(http.MethodGet, testCase.path, nil)
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+
+			server.ServeHTTP(w, r)
+
+			if testCase.expectedError != nil {
+				errorMessage, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(errorMessage), testCase.expectedError.Error())
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			} else {
+				var got []model.Task
+				json.NewDecoder(w.Body).Decode(&got)
+
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+				assertResponseBody(t, got, testCase.expectedBody)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	before := model.Task{ID: 1, Name: "task mock", Version: 1}
+	after := model.Task{ID: 1, Name: "renamed", Version: 2}
+	records := []model.AuditRecord{{ID: 1, TaskID: 1, ActorID: model.DefaultUserID, Operation: model.AuditUpdate, Before: &before, After: &after, CreatedAt: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)}}
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		caseName           string
+		path               string
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedBody       []model.AuditRecord
+	}{
+		{
+			caseName:           "successfully get the history of a task",
+			path:               "/tasks/1/history?page_size=10",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Audit.EXPECT().FindAuditRecords(gomock.Any(), model.AuditFilter{TaskID: 1}, model.PageRequest{Size: 10}).Return(model.AuditPage{Records: records}, nil).Times(1)
+			},
+			expectedBody: records,
+		},
+		{
+			caseName:           "successfully query the audit log",
+			path:               "/audit?actor_id=1&from=2030-01-01T00:00:00Z",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Audit.EXPECT().FindAuditRecords(gomock.Any(), model.AuditFilter{ActorID: 1, From: &from, AllOwners: true}, model.PageRequest{}).Return(model.AuditPage{Records: records}, nil).Times(1)
+			},
+			expectedBody: records,
+		},
+		{
+			caseName:           "invalid actor",
+			path:               "/audit?actor_id=bob",
+			expectedError:      fmt.Errorf("%w: invalid actor_id %q", model.ErrInvalidAuditFilter, "bob"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "invalid time",
+			path:               "/audit?to=yesterday",
+			expectedError:      fmt.Errorf("%w: invalid to %q", model.ErrInvalidAuditFilter, "yesterday"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "time range ending before it starts",
+			path:               "/audit?from=2030-01-02T00:00:00Z&to=2030-01-01T00:00:00Z",
+			expectedError:      fmt.Errorf("%w: from must be before to", model.ErrInvalidAuditFilter),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "error getting the history",
+			path:               "/tasks/1/history",
+			expectedError:      model.ErrExecuteQuery,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Audit.EXPECT().FindAuditRecords(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.AuditPage{}, model.ErrExecuteQuery).Times(1)
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(http.MethodGet, testCase.path, nil)
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+
+			server.ServeHTTP(w, r)
+
+			if testCase.expectedError != nil {
+				errorMessage, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(errorMessage), testCase.expectedError.Error())
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			} else {
+				var got []model.AuditRecord
+				json.NewDecoder(w.Body).Decode(&got)
+
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+				assertResponseBody(t, got, testCase.expectedBody)
+			}
+		})
+	}
+}
+
+func TestSubtasks(t *testing.T) {
+	one := 1
+	parent := model.Task{ID: 1, Name: "parent"}
+	child := model.Task{ID: 2, ParentID: &one, Name: "child"}
+	open, err := query.Parse("completed = false", "")
+	if err != nil {
+		t.Fatalf("Error was not expected while parsing the filter, got %s", err)
+	}
+	open.Filter = query.AndAlso(open.Filter, query.Comparison{Field: "parent_id", Op: query.Eq, Value: 1})
+	cases := []struct {
+		caseName           string
+		method             string
+		path               string
+		requestBody        string
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedBody       any
+	}{
+		{
+			caseName:           "successfully list the open subtasks of a task",
+			method:             http.MethodGet,
+			path:               "/tasks/1/subtasks?filter=completed%20%3D%20false&page_size=5",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().Find(gomock.Any(), open, model.PageRequest{Size: 5}).Return(model.TaskPage{Tasks: []model.Task{child}}, nil).Times(1)
+			},
+			expectedBody: []model.Task{child},
+		},
+		{
+			caseName:           "subtasks of a missing task",
+			method:             http.MethodGet,
+			path:               "/tasks/9/subtasks",
+			expectedError:      model.ErrTaskNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 9).Return(model.Task{}, model.ErrTaskNotFound).Times(1)
+			},
+		},
+		{
+			caseName:           "successfully get the tree of a task",
+			method:             http.MethodGet,
+			path:               "/tasks/1/tree",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, false).Return([]model.Task{child}, nil).Times(1)
+			},
+			expectedBody: model.TaskTree{Task: parent, Subtasks: []model.TaskTree{{Task: child, Subtasks: []model.TaskTree{}}}},
+		},
+		{
+			caseName:           "completing a task with open subtasks",
+			method:             http.MethodPut,
+			path:               "/tasks/1",
+			requestBody:        `{"id": 1, "name": "parent", "completed": true}`,
+			expectedError:      fmt.Errorf("%w: 1 of them", model.ErrOpenSubtasks),
+			expectedStatusCode: http.StatusConflict,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, false).Return([]model.Task{child}, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "successfully complete a task and its subtasks",
+			method:             http.MethodPut,
+			path:               "/tasks/1?completion=cascade",
+			requestBody:        `{"id": 1, "name": "parent", "completed": true}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, false).Return([]model.Task{child}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 2).Return(child, nil).Times(1)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, task model.Task) (model.Task, error) {
+					task.CompletedAt = nil
+					return task, nil
+				}).Times(2)
+			},
+			expectedBody: model.Task{ID: 1, Name: "parent", Completed: true},
+		},
+		{
+			caseName:           "unknown completion mode",
+			method:             http.MethodPut,
+			path:               "/tasks/1?completion=later",
+			requestBody:        `{"id": 1, "name": "parent", "completed": true}`,
+			expectedError:      fmt.Errorf("%w %q", model.ErrInvalidCompletionMode, "later"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "moving a task under its own subtask",
+			method:             http.MethodPatch,
+			path:               "/tasks/1",
+			requestBody:        `{"parent_id": 2}`,
+			expectedError:      model.ErrTaskParentCycle,
+			expectedStatusCode: http.StatusConflict,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(3)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 2).Return(child, nil).Times(1)
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.requestBody))
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+			r.Header.Set("Content-Type", "application/json")
+
+			server.ServeHTTP(w, r)
+
+			if testCase.expectedError != nil {
+				errorMessage, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(errorMessage), testCase.expectedError.Error())
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			} else {
+				want, _ := json.Marshal(testCase.expectedBody)
+				got, _ := ioutil.ReadAll(w.Body)
+
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+				assertResponseBody(t, strings.TrimSpace(string(got)), string(want))
+			}
+		})
+	}
+}
+
+func TestProjects(t *testing.T) {
+	one := 1
+	archivedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	home := model.Project{ID: 1, Name: "home"}
+	old := model.Project{ID: 3, Name: "old", ArchivedAt: &archivedAt}
+	dishes := model.Task{ID: 1, ProjectID: &one, Name: "dishes"}
+	cases := []struct {
+		caseName           string
+		method             string
+		path               string
+		requestBody        string
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedBody       any
+	}{
+		{
+			caseName:           "successfully create a project",
+			method:             http.MethodPost,
+			path:               "/projects",
+			requestBody:        `{"name": "home"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().CreateProject(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, project model.Project) (model.Project, error) {
+					return model.Project{ID: 1, Name: project.Name}, nil
+				}).Times(1)
+			},
+			expectedBody: home,
+		},
+		{
+			caseName:           "create a project without a name",
+			method:             http.MethodPost,
+			path:               "/projects",
+			requestBody:        `{"description": "chores"}`,
+			expectedError:      fmt.Errorf("%w: from 1 to %d characters", model.ErrInvalidProjectName, model.MaxProjectNameLength),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "successfully list the archived projects",
+			method:             http.MethodGet,
+			path:               "/projects?archived=true",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProjects(gomock.Any(), true).Return([]model.Project{old}, nil).Times(1)
+			},
+			expectedBody: []model.Project{old},
+		},
+		{
+			caseName:           "successfully list the open tasks of a project",
+			method:             http.MethodGet,
+			path:               "/projects/1/tasks?completed=false",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Tasks.EXPECT().FindByStatus(gomock.Any(), 1, false, model.PageRequest{}).Return(model.TaskPage{Tasks: []model.Task{dishes}}, nil).Times(1)
+			},
+			expectedBody: []model.Task{dishes},
+		},
+		{
+			caseName:           "tasks of a missing project",
+			method:             http.MethodGet,
+			path:               "/projects/9/tasks",
+			expectedError:      model.ErrProjectNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 9).Return(model.Project{}, model.ErrProjectNotFound).Times(1)
+			},
+		},
+		{
+			caseName:           "successfully get the stats of a project",
+			method:             http.MethodGet,
+			path:               "/projects/1/stats",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Tasks.EXPECT().FindProjectStats(gomock.Any(), 1, gomock.Any()).Return(model.ProjectStats{ProjectID: 1, Total: 4, Completed: 1, Open: 3, Overdue: 2, CompletionRate: 0.25}, nil).Times(1)
+			},
+			expectedBody: model.ProjectStats{ProjectID: 1, Total: 4, Completed: 1, Open: 3, Overdue: 2, CompletionRate: 0.25},
+		},
+		{
+			caseName:           "creating a task in an archived project",
+			method:             http.MethodPost,
+			path:               "/tasks",
+			requestBody:        `{"name": "dishes", "project_id": 3}`,
+			expectedError:      fmt.Errorf("%w: project 3", model.ErrProjectArchived),
+			expectedStatusCode: http.StatusConflict,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 3).Return(old, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "successfully delete a project with its tasks",
+			method:             http.MethodDelete,
+			path:               "/projects/1?mode=cascade",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 1, model.PageRequest{Size: model.MaxPageSize}).Return(model.TaskPage{Tasks: []model.Task{dishes}}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(dishes, nil).Times(1)
+				m.Tasks.EXPECT().Delete(gomock.Any(), 1, 0).Return(nil).Times(1)
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 1, model.PageRequest{Size: model.MaxPageSize}).Return(model.TaskPage{Tasks: []model.Task{}}, nil).Times(1)
+				m.Projects.EXPECT().DeleteProject(gomock.Any(), 1).Return(nil).Times(1)
+			},
+			expectedBody: nil,
+		},
+		{
+			caseName:           "reassigning the tasks to an archived project",
+			method:             http.MethodDelete,
+			path:               "/projects/1?mode=reassign&to=3",
+			expectedError:      fmt.Errorf("%w: project 3", model.ErrProjectArchived),
+			expectedStatusCode: http.StatusConflict,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Projects.EXPECT().FindProject(gomock.Any(), 3).Return(old, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "unknown delete mode",
+			method:             http.MethodDelete,
+			path:               "/projects/1?mode=shred",
+			expectedError:      fmt.Errorf("%w %q", model.ErrInvalidProjectDeleteMode, "shred"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "successfully unarchive a project",
+			method:             http.MethodPost,
+			path:               "/projects/3:unarchive",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 3).Return(old, nil).Times(1)
+				m.Projects.EXPECT().UpdateProject(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, project model.Project) (model.Project, error) {
+					return model.Project{ID: project.ID, Name: project.Name, ArchivedAt: project.ArchivedAt}, nil
+				}).Times(1)
+			},
+			expectedBody: model.Project{ID: 3, Name: "old"},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.requestBody))
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+			r.Header.Set("Content-Type", "application/json")
+
+			server.ServeHTTP(w, r)
+
+			if testCase.expectedError != nil {
+				errorMessage, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(errorMessage), testCase.expectedError.Error())
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			} else {
+				want, _ := json.Marshal(testCase.expectedBody)
+				got, _ := ioutil.ReadAll(w.Body)
+
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+				assertResponseBody(t, strings.TrimSpace(string(got)), string(want))
+			}
+		})
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	createdAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	webhook := model.Webhook{ID: 1, OwnerID: model.DefaultUserID, URL: "https://example.com/hook", Events: []string{"task.created"}, Secret: "a-secret-of-some-length", CreatedAt: createdAt}
+	dead := model.WebhookDelivery{ID: 7, WebhookID: 1, OwnerID: model.DefaultUserID, EventType: "task.created", Payload: []byte(`{"type":"task.created"}`), Status: model.DeliveryDead, Attempts: model.MaxDeliveryAttempts, LastError: "receiver answered 500 Internal Server Error", CreatedAt: createdAt}
+	cases := []struct {
+		caseName           string
+		method             string
+		path               string
+		body               string
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedBody       string
+	}{
+		{
+			caseName:           "successfully register a webhook",
+			method:             http.MethodPost,
+			path:               "/webhooks",
+			body:               `{"url": "https://example.com/hook", "events": ["task.created"], "secret": "a-secret-of-some-length"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Webhooks.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Return(webhook, nil).Times(1)
+			},
+			expectedBody: `{"id":1,"owner_id":1,"url":"https://example.com/hook","events":["task.created"],"secret":"a-secret-of-some-length","created_at":"2030-01-02T03:04:05Z"}`,
+		},
+		{
+			caseName:           "register a webhook for an unknown event",
+			method:             http.MethodPost,
+			path:               "/webhooks",
+			body:               `{"url": "https://example.com/hook", "events": ["task.renamed"]}`,
+			expectedError:      fmt.Errorf("%w %q", model.ErrInvalidWebhookEvent, "task.renamed"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "list webhooks without their secrets",
+			method:             http.MethodGet,
+			path:               "/webhooks",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return([]model.Webhook{webhook}, nil).Times(1)
+			},
+			expectedBody: `[{"id":1,"owner_id":1,"url":"https://example.com/hook","events":["task.created"],"created_at":"2030-01-02T03:04:05Z"}]`,
+		},
+		{
+			caseName:           "delete a missing webhook",
+			method:             http.MethodDelete,
+			path:               "/webhooks/2",
+			expectedError:      model.ErrWebhookNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Webhooks.EXPECT().DeleteWebhook(gomock.Any(), 2).Return(model.ErrWebhookNotFound).Times(1)
+			},
+		},
+		{
+			caseName:           "list the dead deliveries of a webhook",
+			method:             http.MethodGet,
+			path:               "/webhooks/1/deliveries?status=dead",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return([]model.Webhook{webhook}, nil).Times(1)
+				m.Webhooks.EXPECT().FindDeliveries(gomock.Any(), model.DeliveryFilter{WebhookID: 1, Status: model.DeliveryDead}, model.MaxPageSize).Return([]model.WebhookDelivery{dead}, nil).Times(1)
+			},
+			expectedBody: `[{"id":7,"webhook_id":1,"owner_id":1,"event_type":"task.created","payload":{"type":"task.created"},"status":"dead","attempts":10,"next_attempt_at":"0001-01-01T00:00:00Z","last_error":"receiver answered 500 Internal Server Error","created_at":"2030-01-02T03:04:05Z","delivered_at":null}]`,
+		},
+		{
+			caseName:           "list deliveries with an unknown status",
+			method:             http.MethodGet,
+			path:               "/webhooks/1/deliveries?status=lost",
+			expectedError:      fmt.Errorf("%w %q", model.ErrInvalidDeliveryStatus, "lost"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "successfully redeliver a dead delivery",
+			method:             http.MethodPost,
+			path:               "/webhooks/1/deliveries/7:redeliver",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return([]model.Webhook{webhook}, nil).Times(1)
+				m.Webhooks.EXPECT().FindDeliveries(gomock.Any(), model.DeliveryFilter{ID: 7, WebhookID: 1}, 1).Return([]model.WebhookDelivery{dead}, nil).Times(1)
+				m.Webhooks.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+		},
+		{
+			caseName:           "redeliver with an invalid delivery id",
+			method:             http.MethodPost,
+			path:               "/webhooks/1/deliveries/seven:redeliver",
+			expectedError:      model.ErrInvalidDeliveryId,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+
+			server.ServeHTTP(w, r)
+
+			body, _ := ioutil.ReadAll(w.Body)
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			if testCase.expectedError != nil {
+				assertError(t, string(body), testCase.expectedError.Error())
+			} else if testCase.expectedBody != "" && strings.TrimSpace(string(body)) != testCase.expectedBody {
+				t.Errorf("got %s want %s", body, testCase.expectedBody)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	restored := model.Task{ID: 1, Name: "task mock", Version: 3}
+	cases := []struct {
+		caseName           string
+		path               string
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedBody       *model.Task
+	}{
+		{
+			caseName:           "successfully restore a task",
+			path:               "/tasks/1:restore",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Restore(gomock.Any(), 1).Return(restored, nil).Times(1)
+			},
+			expectedBody: &restored,
+		},
+		{
+			caseName:           "restore a task that is not in the trash",
+			path:               "/tasks/2:restore",
+			expectedError:      model.ErrTaskNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Restore(gomock.Any(), 2).Return(model.Task{}, model.ErrTaskNotFound).Times(1)
+			},
+		},
+		{
+			caseName:           "restore a task with an invalid id",
+			path:               "/tasks/0:restore",
+			expectedError:      model.ErrInvalidTaskId,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(http.MethodPost, testCase.path, nil)
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+
+			server.ServeHTTP(w, r)
+
+			if testCase.expectedError != nil {
+				errorMessage, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(errorMessage), testCase.expectedError.Error())
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			} else {
+				var got model.Task
+				json.NewDecoder(w.Body).Decode(&got)
+
+				assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+				assertResponseBody(t, &got, testCase.expectedBody)
+				if etag := w.Result().Header.Get("ETag"); etag != `"3"` {
+					t.Errorf("got ETag %q want %q", etag, `"3"`)
+				}
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	inTransaction := func(m repository.StoreMock) {
+		m.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+			return fn(m.Store())
+		}).Times(1)
+	}
+	stored := model.Task{ID: 2, Name: "Task 2", Version: 3}
+
+	cases := []struct {
+		caseName           string
+		body               string
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedResults    []string
+	}{
+		{
+			caseName:           "all or nothing",
+			body:               `{"create": [{"name": "Task 1"}], "update": [{"id": 2, "name": "renamed", "version": 3}], "delete": [{"id": 2, "version": 4}]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				inTransaction(m)
+				m.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(model.Task{ID: 1, Name: "Task 1", Version: 1}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 2).Return(stored, nil).Times(1)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).Return(model.Task{ID: 2, Name: "renamed", Version: 4}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 2).Return(model.Task{ID: 2, Name: "renamed", Version: 4}, nil).Times(1)
+				m.Tasks.EXPECT().Delete(gomock.Any(), 2, 4).Return(nil).Times(1)
+			},
+			expectedResults: []string{"create Task 1", "update renamed", "delete 2"},
+		},
+		{
+			caseName:           "all or nothing stops at the first failure",
+			body:               `{"create": [{"name": "Task 1"}, {"name": ""}, {"name": "Task 3"}]}`,
+			expectedError:      fmt.Errorf("%w: at create[1]", model.ErrInvalidTaskName),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior: func(m repository.StoreMock) {
+				inTransaction(m)
+				m.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(model.Task{ID: 1, Name: "Task 1", Version: 1}, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "per item",
+			body:               `{"mode": "per_item", "create": [{"name": "Task 1"}, {"name": ""}], "delete": [{"id": 2, "version": 1}]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				// The batch and a savepoint per item.
+				m.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+					return fn(m.Store())
+				}).Times(4)
+				m.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(model.Task{ID: 1, Name: "Task 1", Version: 1}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 2).Return(stored, nil).Times(1)
+			},
+			expectedResults: []string{"create Task 1", "create error invalid task name", "delete error task was modified since it was read"},
+		},
+		{
+			caseName:           "failed commit",
+			body:               `{"create": [{"name": "Task 1"}]}`,
+			expectedError:      model.ErrExecuteQuery,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+					if err := fn(m.Store()); err != nil {
+						return err
+					}
+					return model.ErrExecuteQuery
+				}).Times(1)
+				m.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(model.Task{ID: 1, Name: "Task 1", Version: 1}, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "invalid mode",
+			body:               `{"mode": "some", "create": [{"name": "Task 1"}]}`,
+			expectedError:      model.ErrInvalidBatchMode,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "too many operations",
+			body:               `{"delete": [` + strings.Repeat(`{"id": 1},`, model.MaxBatchSize) + `{"id": 1}]}`,
+			expectedError:      fmt.Errorf("%w: at most %d", model.ErrBatchTooLarge, model.MaxBatchSize),
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "invalid body",
+			body:               `{"create": {"name": "Task 1"}}`,
+			expectedError:      model.ErrInvalidRequestBody,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(http.MethodPost, "/tasks:batch", strings.NewReader(testCase.body))
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+
+			server.ServeHTTP(w, r)
+
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			if testCase.expectedError != nil {
+				errorMessage, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(errorMessage), testCase.expectedError.Error())
+				return
+			}
+
+			type result struct {
+				Task  *model.Task
+				Error *problem.Problem
+			}
+			var body map[string][]result
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("Error was not expected while decoding the results, got %s", err)
+			}
+			var results []string
+			for _, op := range []string{"create", "update", "delete"} {
+				for _, r := range body[op] {
+					switch {
+					case r.Error != nil:
+						results = append(results, op+" error "+r.Error.Detail)
+					case op == "delete":
+						results = append(results, fmt.Sprintf("%s %d", op, r.Task.ID))
+					default:
+						results = append(results, op+" "+r.Task.Name)
+					}
+				}
+			}
+			assertResponseBody(t, results, testCase.expectedResults)
+		})
+	}
+}
+
+func TestRouting(t *testing.T) {
+	cases := []struct {
+		caseName           string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedAllow      string
+	}{
+		{
+			caseName:           "unknown path",
+			method:             http.MethodGet,
+			path:               "/foo/tasks",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			caseName:           "nested path under a task",
+			method:             http.MethodGet,
+			path:               "/tasks/1/foo",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			caseName:           "put without an id",
+			method:             http.MethodPut,
+			path:               "/tasks",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedAllow:      "GET, POST",
+		},
+		{
+			caseName:           "post to a task",
+			method:             http.MethodPost,
+			path:               "/tasks/1/",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedAllow:      "DELETE, GET, PATCH, PUT",
+		},
+		{
+			caseName:           "delete the event stream",
+			method:             http.MethodDelete,
+			path:               "/tasks/events",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedAllow:      "GET",
+		},
+		{
+			caseName:           "delete the trash",
+			method:             http.MethodDelete,
+			path:               "/tasks/trash",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedAllow:      "GET",
+		},
+		{
+			caseName:           "get a custom verb",
+			method:             http.MethodGet,
+			path:               "/tasks/1:restore",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedAllow:      "POST",
+		},
+		{
+			caseName:           "post to the history of a task",
+			method:             http.MethodPost,
+			path:               "/tasks/1/history",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedAllow:      "GET",
+		},
+		{
+			caseName:           "unknown custom verb",
+			method:             http.MethodPost,
+			path:               "/tasks/1:undo",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			caseName:           "id that is not a number",
+			method:             http.MethodGet,
+			path:               "/tasks/abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := api.NewServer(repository.NewStoreMock(ctrl).Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(testCase.method, testCase.path, nil)
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+
+			server.ServeHTTP(w, r)
+
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			if allow := w.Result().Header.Get("Allow"); allow != testCase.expectedAllow {
+				t.Errorf("got Allow %q want %q", allow, testCase.expectedAllow)
+			}
+			if contentType := w.Result().Header.Get("Content-Type"); contentType != problem.ContentType {
+				t.Errorf("got Content-Type %q want %q", contentType, problem.ContentType)
+			}
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	dueAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	task := model.Task{ID: 1, Name: "study golang unit testing", Description: "chapter 3", DueAt: &dueAt, Priority: model.PriorityLow}
+	cases := []struct {
+		caseName           string
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+		expectedBody       model.Task
+		contentType        string
+		requestBody        string
+	}{
+		{
+			caseName:           "successfully completing a task",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(2)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
+					modifiedTask.CompletedAt = nil
+					return modifiedTask, nil
+				}).Times(1)
+			},
+			expectedBody: model.Task{ID: 1, Name: task.Name, Completed: true, Description: "chapter 3", DueAt: &dueAt, Priority: model.PriorityLow},
+			contentType:  "application/merge-patch+json",
+			requestBody:  `{"completed": true}`,
+		},
+		{
+			caseName:           "null removes a field",
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(2)
+				m.Tasks.EXPECT().Update(gomock.Any(), model.Task{ID: 1, Name: task.Name, Priority: model.PriorityHigh}).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
+					return modifiedTask, nil
+				}).Times(1)
+			},
+			expectedBody: model.Task{ID: 1, Name: task.Name, Priority: model.PriorityHigh},
+			contentType:  "application/json",
+			requestBody:  `{"due_at": null, "description": null, "priority": "high"}`,
+		},
+		{
+			caseName:           "not found",
+			expectedStatusCode: http.StatusNotFound,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(model.Task{}, model.ErrTaskNotFound).Times(1)
+			},
+			contentType: "application/merge-patch+json",
+			requestBody: `{"completed": true}`,
+		},
+		{
+			caseName:           "bad request - removing the name",
+			expectedError:      model.ErrInvalidTaskName,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+			contentType: "application/merge-patch+json",
+			requestBody: `{"name": null}`,
+		},
+		{
+			caseName:           "bad request - changing the id",
+			expectedError:      model.ErrInvalidTaskId,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+			contentType: "application/merge-patch+json",
+			requestBody: `{"id": 2}`,
+		},
+		{
+			caseName:           "bad request - patch is not an object",
+			expectedError:      model.ErrInvalidRequestBody,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+			contentType: "application/merge-patch+json",
+			requestBody: `[{"op": "replace", "path": "/completed", "value": true}]`,
+		},
+		{
+			caseName:           "unsupported media type",
+			expectedError:      model.ErrUnsupportedMediaType,
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+			expectedBehavior:   func(m repository.StoreMock) {},
+			contentType:        "application/json-patch+json",
+			requestBody:        `[{"op": "replace", "path": "/completed", "value": true}]`,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
+
+			newReader := strings.NewReader(testCase.requestBody)
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/tasks/%d", task.ID), newReader)
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+			r.Header.Set("Content-Type", testCase.contentType)
+
+			server.ServeHTTP(w, r)
+
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			if testCase.expectedError != nil {
+				errorMessage, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(errorMessage), testCase.expectedError.Error())
+			} else if testCase.expectedStatusCode == http.StatusOK {
+				var got model.Task
+				json.NewDecoder(w.Body).Decode(&got)
+
+				assertResponseBody(t, got, testCase.expectedBody)
+			}
+		})
+	}
+}
+
+// assertError checks the problem document in got carries the want error.
+func TestPreconditions(t *testing.T) {
+	task := model.Task{ID: 1, Name: "study golang unit testing", Version: 3}
+	cases := []struct {
+		caseName           string
+		method             string
+		header             string
+		value              string
+		requestBody        string
+		expectedError      error
+		expectedStatusCode int
+		expectedETag       string
+		expectedBehavior   func(m repository.StoreMock)
+	}{
+		{
+			caseName:           "get sets the etag",
+			method:             http.MethodGet,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "get with a matching If-None-Match is not modified",
+			method:             http.MethodGet,
+			header:             "If-None-Match",
+			value:              `"2", "3"`,
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       `"3"`,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "get with a stale If-None-Match",
+			method:             http.MethodGet,
+			header:             "If-None-Match",
+			value:              `"2"`,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "put with a matching If-Match",
+			method:             http.MethodPut,
+			header:             "If-Match",
+			value:              `"3"`,
+			requestBody:        `{"name": "study golang unit testing", "completed": true}`,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"4"`,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, modifiedTask model.Task) (model.Task, error) {
+					modifiedTask.Version++
+					return modifiedTask, nil
+				}).Times(1)
+			},
+		},
+		{
+			caseName:           "put with a stale If-Match",
+			method:             http.MethodPut,
+			header:             "If-Match",
+			value:              `"2"`,
+			requestBody:        `{"name": "study golang unit testing", "completed": true}`,
+			expectedError:      model.ErrTaskVersionMismatch,
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "put with a stale version in the body",
+			method:             http.MethodPut,
+			requestBody:        `{"name": "study golang unit testing", "version": 2}`,
+			expectedError:      model.ErrTaskVersionMismatch,
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "put with a weak If-Match",
+			method:             http.MethodPut,
+			header:             "If-Match",
+			value:              `W/"3"`,
+			requestBody:        `{"name": "study golang unit testing"}`,
+			expectedError:      model.ErrTaskVersionMismatch,
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "patch with a stale If-Match",
+			method:             http.MethodPatch,
+			header:             "If-Match",
+			value:              `"2"`,
+			requestBody:        `{"completed": true}`,
+			expectedError:      model.ErrTaskVersionMismatch,
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "patch losing a race to another writer",
+			method:             http.MethodPatch,
+			requestBody:        `{"completed": true}`,
+			expectedError:      model.ErrTaskVersionMismatch,
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(2)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).Return(model.Task{}, model.ErrTaskVersionMismatch).Times(1)
+			},
+		},
+		{
+			caseName:           "delete with a matching If-Match",
+			method:             http.MethodDelete,
+			header:             "If-Match",
+			value:              `"3"`,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+				m.Tasks.EXPECT().Delete(gomock.Any(), task.ID, 3).Return(nil).Times(1)
+			},
+		},
+		{
+			caseName:           "delete with a stale If-Match",
+			method:             http.MethodDelete,
+			header:             "If-Match",
+			value:              `"2"`,
+			expectedError:      model.ErrTaskVersionMismatch,
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+
+			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(testCase.method, fmt.Sprintf("/tasks/%d", task.ID), strings.NewReader(testCase.requestBody))
+			r.Header.Set("Authorization", pkg.GetBearerToken())
+			r.Header.Set("Content-Type", "application/json")
+			if testCase.header != "" {
+				r.Header.Set(testCase.header, testCase.value)
+			}
+
+			server.ServeHTTP(w, r)
+
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			if testCase.expectedError != nil {
+				errorMessage, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(errorMessage), testCase.expectedError.Error())
+			}
+			if etag := w.Header().Get("ETag"); etag != testCase.expectedETag {
+				t.Errorf("got ETag %q want %q", etag, testCase.expectedETag)
+			}
+			if testCase.expectedStatusCode == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("got body %q want none", w.Body)
+			}
+		})
+	}
+}
+
+func TestUsers(t *testing.T) {
+	alice := model.User{ID: 2, Name: "alice"}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	aliceWithPassword := alice
+	aliceWithPassword.PasswordHash = string(hash)
+	aliceToken := "Bearer gct_alicesToken"
+	justUsed := model.Now()
+	aliceKey := model.Token{ID: 1, UserID: alice.ID, Scopes: model.AllScopes, LastUsedAt: &justUsed}
+	readOnlyKey := model.Token{ID: 2, UserID: alice.ID, Scopes: model.ScopeTasksRead, LastUsedAt: &justUsed}
+
+	cases := []struct {
+		caseName           string
+		method             string
+		path               string
+		authorization      string
+		requestBody        string
+		expectedError      error
+		expectedStatusCode int
+		expectedInBody     string
+		expectedBehavior   func(m *repository.UserMock)
+	}{
+		{
+			caseName:           "register",
+			method:             http.MethodPost,
+			path:               "/users",
+			requestBody:        `{"name": "alice", "password": "correct horse"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, user model.User) (model.User, error) {
+					if user.Name != "alice" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("correct horse")) != nil {
+						t.Errorf("expected alice with a hash of her password, got %+v", user)
+					}
+					return alice, nil
+				}).Times(1)
+			},
+		},
+		{
+			caseName:           "register with a short password",
+			method:             http.MethodPost,
+			path:               "/users",
+			requestBody:        `{"name": "alice", "password": "horse"}`,
+			expectedError:      model.ErrInvalidPassword,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m *repository.UserMock) {},
+		},
+		{
+			caseName:           "register with a colon in the name",
+			method:             http.MethodPost,
+			path:               "/users",
+			requestBody:        `{"name": "ali:ce", "password": "correct horse"}`,
+			expectedError:      model.ErrInvalidUserName,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior:   func(m *repository.UserMock) {},
+		},
+		{
+			caseName:           "register a taken name",
+			method:             http.MethodPost,
+			path:               "/users",
+			requestBody:        `{"name": "alice", "password": "correct horse"}`,
+			expectedError:      model.ErrUserAlreadyExists,
+			expectedStatusCode: http.StatusConflict,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(model.User{}, model.ErrUserAlreadyExists).Times(1)
+			},
+		},
+		{
+			caseName:           "create a token with a password",
+			method:             http.MethodPost,
+			path:               "/tokens",
+			authorization:      basicAuth("alice", "correct horse"),
+			requestBody:        `{"name": "laptop"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedInBody:     `"token":"gct_`,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByName(gomock.Any(), "alice").Return(aliceWithPassword, nil).Times(1)
+				m.EXPECT().CreateToken(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, token model.Token) (model.Token, error) {
+					if token.UserID != alice.ID || token.Name != "laptop" || len(token.Hash) != 64 || token.Scopes != model.AllScopes || token.ExpiresAt != nil {
+						t.Errorf("expected a hashed token for alice with every scope, got %+v", token)
+					}
+					token.ID = 1
+					return token, nil
+				}).Times(1)
+			},
+		},
+		{
+			caseName:           "create a read-only token that expires",
+			method:             http.MethodPost,
+			path:               "/tokens",
+			authorization:      basicAuth("alice", "correct horse"),
+			requestBody:        `{"name": "ci", "scopes": ["tasks:read"], "expires_at": "2099-01-02T03:04:05Z"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedInBody:     `"scopes":["tasks:read"],"expires_at":"2099-01-02T03:04:05Z"`,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByName(gomock.Any(), "alice").Return(aliceWithPassword, nil).Times(1)
+				m.EXPECT().CreateToken(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, token model.Token) (model.Token, error) {
+					token.ID = 2
+					return token, nil
+				}).Times(1)
+			},
+		},
+		{
+			caseName:           "create a token with an unknown scope",
+			method:             http.MethodPost,
+			path:               "/tokens",
+			authorization:      basicAuth("alice", "correct horse"),
+			requestBody:        `{"scopes": ["tasks:archive"]}`,
+			expectedError:      fmt.Errorf(`%w "tasks:archive"`, model.ErrInvalidScope),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByName(gomock.Any(), "alice").Return(aliceWithPassword, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "create a token that has already expired",
+			method:             http.MethodPost,
+			path:               "/tokens",
+			authorization:      basicAuth("alice", "correct horse"),
+			requestBody:        `{"expires_at": "2001-01-02T03:04:05Z"}`,
+			expectedError:      model.ErrInvalidTokenExpiry,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByName(gomock.Any(), "alice").Return(aliceWithPassword, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "create a token with a wrong password",
+			method:             http.MethodPost,
+			path:               "/tokens",
+			authorization:      basicAuth("alice", "battery staple"),
+			expectedError:      model.ErrInvalidCredentials,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByName(gomock.Any(), "alice").Return(aliceWithPassword, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "create a token for an unknown user",
+			method:             http.MethodPost,
+			path:               "/tokens",
+			authorization:      basicAuth("bob", "correct horse"),
+			expectedError:      model.ErrInvalidCredentials,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByName(gomock.Any(), "bob").Return(model.User{}, model.ErrUserNotFound).Times(1)
+			},
+		},
+		{
+			caseName:           "create a token without credentials",
+			method:             http.MethodPost,
+			path:               "/tokens",
+			expectedError:      model.ErrInvalidCredentials,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBehavior:   func(m *repository.UserMock) {},
+		},
+		{
+			caseName:           "list tokens",
+			method:             http.MethodGet,
+			path:               "/tokens",
+			authorization:      aliceToken,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByToken(gomock.Any(), gomock.Any()).Return(alice, aliceKey, nil).Times(1)
+				m.EXPECT().FindTokens(gomock.Any(), alice.ID).Return([]model.Token{{ID: 1, UserID: alice.ID, Name: "laptop"}}, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "list tokens with an unknown token",
+			method:             http.MethodGet,
+			path:               "/tokens",
+			authorization:      aliceToken,
+			expectedError:      model.ErrUnauthorized,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByToken(gomock.Any(), gomock.Any()).Return(model.User{}, model.Token{}, model.ErrUserNotFound).Times(1)
+			},
+		},
+		{
+			caseName:           "list tokens with a read-only token",
+			method:             http.MethodGet,
+			path:               "/tokens",
+			authorization:      aliceToken,
+			expectedError:      fmt.Errorf("%w: needs scope admin", model.ErrForbidden),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByToken(gomock.Any(), gomock.Any()).Return(alice, readOnlyKey, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "revoke a token",
+			method:             http.MethodDelete,
+			path:               "/tokens/1",
+			authorization:      aliceToken,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByToken(gomock.Any(), gomock.Any()).Return(alice, aliceKey, nil).Times(1)
+				m.EXPECT().DeleteToken(gomock.Any(), alice.ID, 1).Return(nil).Times(1)
+			},
+		},
+		{
+			caseName:           "revoke another user's token",
+			method:             http.MethodDelete,
+			path:               "/tokens/7",
+			authorization:      aliceToken,
+			expectedError:      model.ErrTokenNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBehavior: func(m *repository.UserMock) {
+				m.EXPECT().FindByToken(gomock.Any(), gomock.Any()).Return(alice, aliceKey, nil).Times(1)
+				m.EXPECT().DeleteToken(gomock.Any(), alice.ID, 7).Return(model.ErrTokenNotFound).Times(1)
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			usersMock := repository.NewUserMock(ctrl)
+			server := api.NewServer(repository.NewStoreMock(ctrl).Store(), usersMock, newJwt(t))
+
+			testCase.expectedBehavior(usersMock)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.requestBody))
+			if testCase.authorization != "" {
+				r.Header.Set("Authorization", testCase.authorization)
+			}
+
+			server.ServeHTTP(w, r)
+
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			body, _ := ioutil.ReadAll(w.Body)
+			if testCase.expectedError != nil {
+				assertError(t, string(body), testCase.expectedError.Error())
+			}
+			if strings.Contains(string(body), "password_hash") || strings.Contains(string(body), "\"hash\"") {
+				t.Errorf("expected no hashes in the response, got %s", body)
+			}
+			if !strings.Contains(string(body), testCase.expectedInBody) {
+				t.Errorf("expected %s in the response, got %s", testCase.expectedInBody, body)
+			}
+		})
+	}
+}
+
+func TestTokenActsForItsUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	alice := model.User{ID: 2, Name: "alice"}
+	repoMock := repository.NewStoreMock(ctrl)
+	usersMock := repository.NewUserMock(ctrl)
+	server := api.NewServer(repoMock.Store(), usersMock, newJwt(t))
+
+	usersMock.EXPECT().FindByToken(gomock.Any(), gomock.Any()).Return(alice, model.Token{ID: 1, UserID: alice.ID, Scopes: model.AllScopes}, nil).Times(1)
+	usersMock.EXPECT().TouchToken(gomock.Any(), 1, gomock.Any()).Return(nil).Times(1)
+	repoMock.Tasks.EXPECT().FindAll(gomock.Any(), 0, gomock.Any()).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+		if user, ok := model.UserFromContext(ctx); !ok || user.ID != alice.ID {
+			t.Errorf("expected the repository to act for %+v, got %+v", alice, user)
+		}
+		return model.TaskPage{}, nil
+	}).Times(1)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
+	r.Header.Set("Authorization", "Bearer gct_alicesToken")
+
+	server.ServeHTTP(w, r)
+
+	assertStatusCode(t, w.Result().StatusCode, http.StatusOK)
+}
+
+func TestScopes(t *testing.T) {
+	alice := model.User{ID: 2, Name: "alice"}
+	justUsed := model.Now()
+	expired := justUsed.Add(-time.Hour)
+	readOnly := model.Token{ID: 1, UserID: alice.ID, Scopes: model.ScopeTasksRead, LastUsedAt: &justUsed}
+
+	cases := []struct {
+		caseName           string
+		method             string
+		path               string
+		body               string
+		token              model.Token
+		expectedError      error
+		expectedStatusCode int
+		expectedBehavior   func(m repository.StoreMock)
+	}{
+		{
+			caseName:           "read with a read-only token",
+			method:             http.MethodGet,
+			path:               "/tasks/1",
+			token:              readOnly,
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(model.Task{ID: 1, Name: "Task 1", Version: 1}, nil).Times(1)
+			},
+		},
+		{
+			caseName:           "create with a read-only token",
+			method:             http.MethodPost,
+			path:               "/tasks",
+			token:              readOnly,
+			expectedError:      fmt.Errorf("%w: needs scope tasks:write", model.ErrForbidden),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "delete with a read-only token",
+			method:             http.MethodDelete,
+			path:               "/tasks/1",
+			token:              readOnly,
+			expectedError:      fmt.Errorf("%w: needs scope tasks:delete", model.ErrForbidden),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "delete with a token that can delete",
+			method:             http.MethodDelete,
+			path:               "/tasks/1",
+			token:              model.Token{ID: 1, UserID: alice.ID, Scopes: model.ScopeTasksDelete, LastUsedAt: &justUsed},
+			expectedStatusCode: http.StatusOK,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(model.Task{ID: 1, Name: "Task 1", Version: 1}, nil).Times(1)
+				m.Tasks.EXPECT().Delete(gomock.Any(), 1, 0).Return(nil).Times(1)
+			},
+		},
+		{
+			caseName:           "audit log with a read-only token",
+			method:             http.MethodGet,
+			path:               "/audit",
+			token:              readOnly,
+			expectedError:      fmt.Errorf("%w: needs scope admin", model.ErrForbidden),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "register a webhook with a read-only token",
+			method:             http.MethodPost,
+			path:               "/webhooks",
+			body:               `{"url": "https://example.com/hook"}`,
+			token:              readOnly,
+			expectedError:      fmt.Errorf("%w: needs scope admin", model.ErrForbidden),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "batch delete without the delete scope",
+			method:             http.MethodPost,
+			path:               "/tasks:batch",
+			body:               `{"delete": [{"id": 1}]}`,
+			token:              model.Token{ID: 1, UserID: alice.ID, Scopes: model.ScopeTasksWrite, LastUsedAt: &justUsed},
+			expectedError:      fmt.Errorf("%w: needs scope tasks:delete", model.ErrForbidden),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "restore without the delete scope",
+			method:             http.MethodPost,
+			path:               "/tasks/1:restore",
+			token:              model.Token{ID: 1, UserID: alice.ID, Scopes: model.ScopeTasksWrite, LastUsedAt: &justUsed},
+			expectedError:      fmt.Errorf("%w: needs scope tasks:delete", model.ErrForbidden),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+		{
+			caseName:           "read with an expired token",
+			method:             http.MethodGet,
+			path:               "/tasks/1",
+			token:              model.Token{ID: 1, UserID: alice.ID, Scopes: model.AllScopes, ExpiresAt: &expired},
+			expectedError:      fmt.Errorf("%w: token expired", model.ErrUnauthorized),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBehavior:   func(m repository.StoreMock) {},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			usersMock := repository.NewUserMock(ctrl)
+			server := api.NewServer(repoMock.Store(), usersMock, newJwt(t))
+
+			usersMock.EXPECT().FindByToken(gomock.Any(), gomock.Any()).Return(alice, testCase.token, nil).Times(1)
+			testCase.expectedBehavior(repoMock)
+			audited(repoMock)
+
+			w := httptest.NewRecorder()
+			body := testCase.body
+			if body == "" {
+				body = `{"name": "Task 1"}`
+			}
+			r, _ := http.NewRequest(testCase.method, testCase.path, strings.NewReader(body))
+			r.Header.Set("Authorization", "Bearer gct_alicesToken")
+
+			server.ServeHTTP(w, r)
+
+			assertStatusCode(t, w.Result().StatusCode, testCase.expectedStatusCode)
+			if testCase.expectedError != nil {
+				body, _ := ioutil.ReadAll(w.Body)
+				assertError(t, string(body), testCase.expectedError.Error())
+			}
+		})
+	}
+}
+
+func TestJwt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	alice := model.User{ID: 2, Name: "alice", PasswordHash: string(hash)}
+	repoMock := repository.NewStoreMock(ctrl)
+	usersMock := repository.NewUserMock(ctrl)
+	jwt := newJwt(t)
+	server := api.NewServer(repoMock.Store(), usersMock, jwt)
+
+	usersMock.EXPECT().FindByName(gomock.Any(), "alice").Return(alice, nil).Times(1)
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodPost, "/auth/token", nil)
+	r.SetBasicAuth("alice", "correct horse")
+	server.ServeHTTP(w, r)
+
+	assertStatusCode(t, w.Result().StatusCode, http.StatusOK)
+	var token auth.AccessToken
+	json.NewDecoder(w.Body).Decode(&token)
+	if token.TokenType != "Bearer" || token.ExpiresIn != 60 || token.AccessToken == "" {
+		t.Fatalf("expected a bearer token for a minute, got %+v", token)
+	}
+
+	usersMock.EXPECT().FindById(gomock.Any(), alice.ID).Return(alice, nil).Times(1)
+	repoMock.Tasks.EXPECT().FindAll(gomock.Any(), 0, gomock.Any()).DoAndReturn(func(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+		if user, _ := model.UserFromContext(ctx); user.ID != alice.ID || user.Name != alice.Name {
+			t.Errorf("expected the repository to act for alice, got %+v", user)
+		}
+		return model.TaskPage{}, nil
+	}).Times(1)
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/tasks", nil)
+	r.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	server.ServeHTTP(w, r)
+
+	assertStatusCode(t, w.Result().StatusCode, http.StatusOK)
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/tasks", nil)
+	r.Header.Set("Authorization", "Bearer "+token.AccessToken+"x")
+	server.ServeHTTP(w, r)
+
+	assertStatusCode(t, w.Result().StatusCode, http.StatusUnauthorized)
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	server.ServeHTTP(w, r)
+
+	assertStatusCode(t, w.Result().StatusCode, http.StatusOK)
+	want, _ := jwt.Keys().Public()
+	if got, _ := ioutil.ReadAll(w.Body); string(got) != string(want) || strings.Contains(string(got), `"d"`) {
+		t.Errorf("got keys %s want %s", got, want)
+	}
+}
+
+func TestJwtOfRevokedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	alice := model.User{ID: 2, Name: "alice"}
+	aliceKey := model.Token{ID: 5, UserID: alice.ID, Scopes: model.AllScopes}
+	usersMock := repository.NewUserMock(ctrl)
+	server := api.NewServer(repository.NewStoreMock(ctrl).Store(), usersMock, newJwt(t))
+
+	usersMock.EXPECT().FindByToken(gomock.Any(), gomock.Any()).Return(alice, aliceKey, nil).Times(1)
+	usersMock.EXPECT().TouchToken(gomock.Any(), aliceKey.ID, gomock.Any()).Return(nil).Times(1)
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodPost, "/auth/token", nil)
+	r.Header.Set("Authorization", "Bearer gct_alicesToken")
+	server.ServeHTTP(w, r)
+
+	assertStatusCode(t, w.Result().StatusCode, http.StatusOK)
+	var token auth.AccessToken
+	json.NewDecoder(w.Body).Decode(&token)
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodPost, "/auth/token", nil)
+	r.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	server.ServeHTTP(w, r)
+
+	assertStatusCode(t, w.Result().StatusCode, http.StatusUnauthorized)
+
+	usersMock.EXPECT().FindToken(gomock.Any(), alice.ID, aliceKey.ID).Return(model.Token{}, model.ErrTokenNotFound).Times(1)
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/tasks", nil)
+	r.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	server.ServeHTTP(w, r)
+
+	assertStatusCode(t, w.Result().StatusCode, http.StatusUnauthorized)
+}
+
+// audited lets the service run and audit changes; it goes after the expected behavior.
+func audited(m repository.StoreMock) {
+	m.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+		return fn(m.Store())
+	}).AnyTimes()
+	m.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	m.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
+	m.Tasks.EXPECT().FindSubtree(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+}
+
+func newJwt(t testing.TB) *auth.JWT {
+	key, err := auth.GenerateKey(auth.EdDSA)
+	if err != nil {
+		t.Fatalf("Error was not expected while generating a key, got %s", err)
+	}
+	return auth.NewJWT(&auth.KeySet{Keys: []auth.Key{key}}, pkg.JwtConfig{Issuer: "issuer", Audience: "audience", TTL: time.Minute})
+}
+
+func basicAuth(name, password string) string {
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(name, password)
+	return r.Header.Get("Authorization")
+}
+
+func assertError(t testing.TB, got, want string) {
+	t.Helper()
+
+	var p problem.Problem
+	if err := json.Unmarshal([]byte(got), &p); err != nil {
+		t.Fatalf("got %q, which is not a problem document", got)
+	}
+	if p.Status >= http.StatusInternalServerError {
+		if p.Detail != "" {
+			t.Errorf("got detail %q on a server error", p.Detail)
+		}
+		return
+	}
+	if want != "" && p.Detail != want {
+		t.Errorf("got %v want %v", p.Detail, want)
 	}
 }
 
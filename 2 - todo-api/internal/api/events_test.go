@@ -0,0 +1,134 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"gochallenges/internal/api"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/pkg"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+type sseEvent struct {
+	id, event, data string
+}
+
+func TestEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := repository.NewStoreMock(ctrl)
+	server := api.NewServer(repoMock.Store(), repository.NewUserMock(ctrl), newJwt(t))
+	audited(repoMock)
+	streamsDone := make(chan struct{}, 3)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r)
+		if r.URL.Path == "/tasks/events" {
+			streamsDone <- struct{}{}
+		}
+	}))
+	defer httpServer.Close()
+
+	created := func(owner int, name string) {
+		repoMock.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(model.Task{ID: 1, OwnerID: owner, Name: name, Version: 1}, nil).Times(1)
+		response := send(t, httpServer, http.MethodPost, "/tasks", `{"name": "`+name+`"}`, "")
+		response.Body.Close()
+		assertStatusCode(t, response.StatusCode, http.StatusCreated)
+	}
+
+	stream := send(t, httpServer, http.MethodGet, "/tasks/events", "", "")
+	assertStatusCode(t, stream.StatusCode, http.StatusOK)
+	if contentType := stream.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("got Content-Type %q want text/event-stream", contentType)
+	}
+	events := bufio.NewReader(stream.Body)
+
+	created(2, "someone else's task")
+	created(model.DefaultUserID, "Task 1")
+	first := readEvent(t, events)
+	if first.event != "task.created" || !strings.Contains(first.data, `"name":"Task 1"`) {
+		t.Errorf("expected only the user's own task to be streamed, got %+v", first)
+	}
+
+	stream.Body.Close()
+	waitForStream(t, streamsDone)
+
+	created(model.DefaultUserID, "Task 2")
+	created(model.DefaultUserID, "Task 3")
+
+	resumed := send(t, httpServer, http.MethodGet, "/tasks/events", "", first.id)
+	events = bufio.NewReader(resumed.Body)
+	for _, name := range []string{"Task 2", "Task 3"} {
+		if missed := readEvent(t, events); missed.event != "task.created" || !strings.Contains(missed.data, name) {
+			t.Errorf("expected to replay %s, got %+v", name, missed)
+		}
+	}
+	resumed.Body.Close()
+	waitForStream(t, streamsDone)
+
+	fromAnotherProcess := send(t, httpServer, http.MethodGet, "/tasks/events", "", "1-1")
+	if reset := readEvent(t, bufio.NewReader(fromAnotherProcess.Body)); reset.event != "reset" {
+		t.Errorf("expected an unknown event id to reset the client, got %+v", reset)
+	}
+	fromAnotherProcess.Body.Close()
+	waitForStream(t, streamsDone)
+}
+
+func send(t testing.TB, server *httptest.Server, method, path, body, lastEventId string) *http.Response {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	r, _ := http.NewRequestWithContext(ctx, method, server.URL+path, strings.NewReader(body))
+	r.Header.Set("Authorization", pkg.GetBearerToken())
+	if lastEventId != "" {
+		r.Header.Set("Last-Event-ID", lastEventId)
+	}
+
+	response, err := server.Client().Do(r)
+	if err != nil {
+		t.Fatalf("Error was not expected while sending %s %s, got %s", method, path, err)
+	}
+	return response
+}
+
+// readEvent skips comments and returns the next event.
+func readEvent(t testing.TB, r *bufio.Reader) sseEvent {
+	t.Helper()
+
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error was not expected while reading the stream, got %s", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && e.event != "":
+			return e
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func waitForStream(t testing.TB, done <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stream to end when its client went away")
+	}
+}
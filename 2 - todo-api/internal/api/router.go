@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"gochallenges/internal/model"
+	"gochallenges/internal/problem"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Router matches paths to patterns such as "/tasks/{id}:restore"; those with fewer {name} segments win.
+type Router struct {
+	routes []route
+}
+
+type route struct {
+	method   string
+	segments []string
+	handler  http.Handler
+}
+
+type pathValuesKey struct{}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+func (rt *Router) Handle(method, pattern string, handler http.Handler) {
+	rt.routes = append(rt.routes, route{method: method, segments: splitPath(pattern), handler: handler})
+}
+
+func (rt *Router) HandleFunc(method, pattern string, handler http.HandlerFunc) {
+	rt.Handle(method, pattern, handler)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := splitPath(r.URL.Path)
+
+	wildcards := -1
+	for _, route := range rt.routes {
+		if _, ok := route.match(segments); ok && (wildcards < 0 || route.wildcards() < wildcards) {
+			wildcards = route.wildcards()
+		}
+	}
+
+	var allowed []string
+	for _, route := range rt.routes {
+		values, ok := route.match(segments)
+		if !ok || route.wildcards() != wildcards {
+			continue
+		}
+		if route.method != r.Method {
+			allowed = append(allowed, route.method)
+			continue
+		}
+
+		route.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), pathValuesKey{}, values)))
+		return
+	}
+
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		problem.Write(w, r, model.ErrMethodNotAllowed)
+		return
+	}
+	problem.Write(w, r, model.ErrRouteNotFound)
+}
+
+func PathValue(r *http.Request, name string) string {
+	values, _ := r.Context().Value(pathValuesKey{}).(map[string]string)
+	return values[name]
+}
+
+func (rt route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+
+	values := map[string]string{}
+	for i, pattern := range rt.segments {
+		name, verb, ok := wildcard(pattern)
+		if !ok {
+			if pattern != segments[i] {
+				return nil, false
+			}
+			continue
+		}
+
+		value := strings.TrimSuffix(segments[i], verb)
+		if value == "" || value+verb != segments[i] || strings.Contains(value, ":") {
+			return nil, false
+		}
+		values[name] = value
+	}
+	return values, true
+}
+
+func (rt route) wildcards() int {
+	count := 0
+	for _, segment := range rt.segments {
+		if _, _, ok := wildcard(segment); ok {
+			count++
+		}
+	}
+	return count
+}
+
+// wildcard splits a "{name}" or "{name}:verb" pattern segment.
+func wildcard(segment string) (name string, verb string, ok bool) {
+	end := strings.Index(segment, "}")
+	if !strings.HasPrefix(segment, "{") || end < 0 {
+		return "", "", false
+	}
+	verb = segment[end+1:]
+	if verb != "" && !strings.HasPrefix(verb, ":") {
+		return "", "", false
+	}
+	return segment[1:end], verb, true
+}
+
+// splitPath ignores a trailing slash, so "/tasks/" is "/tasks".
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
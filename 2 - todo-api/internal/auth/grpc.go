@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"gochallenges/internal/model"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// authorizationKey is the metadata the gateway fills from the Authorization header.
+const authorizationKey = "authorization"
+
+type Authenticator func(ctx context.Context, authorization string) (model.User, error)
+
+type Authorizer func(ctx context.Context, user model.User, fullMethod string) error
+
+// Interceptors authenticate every gRPC call and put its user in the context.
+type Interceptors struct {
+	authenticate Authenticator
+	authorize    Authorizer
+}
+
+// NewInterceptors lets any authenticated user call any method when authorize is nil.
+func NewInterceptors(authenticate Authenticator, authorize Authorizer) Interceptors {
+	return Interceptors{authenticate: authenticate, authorize: authorize}
+}
+
+func (i Interceptors) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := i.authenticated(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (i Interceptors) Stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.authenticated(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+func (i Interceptors) authenticated(ctx context.Context, fullMethod string) (context.Context, error) {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(authorizationKey); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	user, err := i.authenticate(ctx, authorization)
+	if err != nil {
+		return ctx, err
+	}
+	if i.authorize != nil {
+		if err := i.authorize(ctx, user, fullMethod); err != nil {
+			return ctx, err
+		}
+	}
+	return model.ContextWithUser(ctx, user), nil
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
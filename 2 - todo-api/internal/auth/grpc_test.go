@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"gochallenges/internal/model"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const fullMethod = "/tasks.TasksService/GetTasks"
+
+var alice = model.User{ID: 2, Name: "alice"}
+
+func TestInterceptors(t *testing.T) {
+	authenticate := func(ctx context.Context, authorization string) (model.User, error) {
+		if authorization != "Bearer alice" {
+			return model.User{}, model.ErrUnauthorized
+		}
+		return alice, nil
+	}
+	denyAll := func(ctx context.Context, user model.User, method string) error {
+		if method != fullMethod {
+			t.Errorf("expected to authorize %s, got %s", fullMethod, method)
+		}
+		return model.ErrForbidden
+	}
+
+	cases := []struct {
+		caseName      string
+		authorization []string
+		authorize     Authorizer
+		expectedErr   error
+	}{
+		{
+			caseName:    "no metadata",
+			expectedErr: model.ErrUnauthorized,
+		},
+		{
+			caseName:      "invalid token",
+			authorization: []string{"Bearer bob"},
+			expectedErr:   model.ErrUnauthorized,
+		},
+		{
+			caseName:      "valid token",
+			authorization: []string{"Bearer alice"},
+		},
+		{
+			caseName:      "valid token for a method the user may not call",
+			authorization: []string{"Bearer alice"},
+			authorize:     denyAll,
+			expectedErr:   model.ErrForbidden,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			interceptors := NewInterceptors(authenticate, testCase.authorize)
+			ctx := context.Background()
+			if testCase.authorization != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authorizationKey, testCase.authorization[0]))
+			}
+
+			var unaryUser, streamUser model.User
+			_, unaryErr := interceptors.Unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req any) (any, error) {
+				unaryUser, _ = model.UserFromContext(ctx)
+				return nil, nil
+			})
+			streamErr := interceptors.Stream(nil, &stream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: fullMethod}, func(srv any, ss grpc.ServerStream) error {
+				streamUser, _ = model.UserFromContext(ss.Context())
+				return nil
+			})
+
+			for _, err := range []error{unaryErr, streamErr} {
+				if !errors.Is(err, testCase.expectedErr) {
+					t.Errorf("got %v want %v", err, testCase.expectedErr)
+				}
+			}
+			if testCase.expectedErr == nil && (unaryUser != alice || streamUser != alice) {
+				t.Errorf("expected handlers to act for %+v, got %+v and %+v", alice, unaryUser, streamUser)
+			}
+		})
+	}
+}
+
+func TestGatewayForwardsAuthorization(t *testing.T) {
+	r := httptest.NewRequest("GET", "/tasks", nil)
+	r.Header.Set("Authorization", "Bearer alice")
+
+	ctx, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(), r, fullMethod)
+	if err != nil {
+		t.Fatalf("Error was not expected while annotating, got %s", err)
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if values := md.Get(authorizationKey); len(values) != 1 || values[0] != "Bearer alice" {
+		t.Errorf("expected the authorization metadata to hold the header, got %v", values)
+	}
+}
+
+type stream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stream) Context() context.Context {
+	return s.ctx
+}
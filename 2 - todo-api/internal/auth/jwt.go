@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"fmt"
+	"gochallenges/internal/model"
+	"gochallenges/pkg"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// leeway allows for clocks that are slightly apart.
+const leeway = 30 * time.Second
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Name string `json:"name,omitempty"`
+	// Scope holds the space-separated scopes the token was issued for.
+	Scope   string `json:"scope,omitempty"`
+	TokenID int    `json:"tid,omitempty"`
+}
+
+type AccessToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// JWT issues tokens for one issuer and audience and only accepts those.
+type JWT struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+func NewJWT(keys *KeySet, config pkg.JwtConfig) *JWT {
+	return &JWT{
+		keys:     keys,
+		issuer:   config.Issuer,
+		audience: config.Audience,
+		ttl:      config.TTL,
+		now:      time.Now,
+	}
+}
+
+// Load signs with a key generated for this process when there is no key set.
+func Load(config pkg.JwtConfig) (*JWT, error) {
+	if config.KeysFile == "" {
+		key, err := GenerateKey(EdDSA)
+		if err != nil {
+			return nil, err
+		}
+		return NewJWT(&KeySet{Keys: []Key{key}}, config), nil
+	}
+
+	keys, err := LoadKeySet(config.KeysFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewJWT(keys, config), nil
+}
+
+func (j *JWT) Keys() *KeySet {
+	return j.keys
+}
+
+// Issue signs a token for the user whose tid is the personal token it was issued for.
+func (j *JWT) Issue(user model.User) (AccessToken, error) {
+	key, err := j.keys.Signing()
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	now := j.now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    j.issuer,
+			Subject:   strconv.Itoa(user.ID),
+			Audience:  jwt.ClaimStrings{j.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Name:    user.Name,
+		Scope:   user.Scopes.String(),
+		TokenID: user.TokenID,
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.signingKey())
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	return AccessToken{AccessToken: signed, TokenType: "Bearer", ExpiresIn: int(j.ttl.Seconds())}, nil
+}
+
+// Verify checks the signature with the key named by kid, then the registered claims.
+func (j *JWT) Verify(token string) (model.User, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{HS256, RS256, EdDSA}),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithAudience(j.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+		jwt.WithTimeFunc(j.now),
+	)
+
+	var claims Claims
+	_, err := parser.ParseWithClaims(token, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.keys.Find(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if key.Algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("kid %q is not a %s key", kid, token.Method.Alg())
+		}
+		return key.verificationKey(), nil
+	})
+	if err != nil {
+		return model.User{}, fmt.Errorf("%w: %s", model.ErrUnauthorized, err)
+	}
+
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil || id <= 0 {
+		return model.User{}, fmt.Errorf("%w: invalid subject", model.ErrUnauthorized)
+	}
+
+	scopes, err := model.ParseScopes(strings.Fields(claims.Scope))
+	if err != nil {
+		return model.User{}, fmt.Errorf("%w: %s", model.ErrUnauthorized, err)
+	}
+
+	return model.User{ID: id, Name: claims.Name, Scopes: scopes, TokenID: claims.TokenID}, nil
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"errors"
+	"gochallenges/internal/model"
+	"gochallenges/pkg"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var config = pkg.JwtConfig{Issuer: "issuer", Audience: "audience", TTL: 15 * time.Minute}
+
+func TestIssueAndVerify(t *testing.T) {
+	for _, algorithm := range []string{HS256, RS256, EdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			issuer := NewJWT(&KeySet{Keys: []Key{mustGenerate(t, algorithm)}}, config)
+
+			alice := model.User{ID: 2, Name: "alice", Scopes: model.ScopeTasksRead | model.ScopeTasksWrite, TokenID: 5}
+			token, err := issuer.Issue(alice)
+			if err != nil {
+				t.Fatalf("Error was not expected while issuing, got %s", err)
+			}
+
+			user, err := issuer.Verify(token.AccessToken)
+			if err != nil {
+				t.Fatalf("Error was not expected while verifying, got %s", err)
+			}
+			if user != alice {
+				t.Errorf("got %+v want %+v", user, alice)
+			}
+		})
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := mustGenerate(t, EdDSA)
+	other := mustGenerate(t, HS256)
+	issuer := NewJWT(&KeySet{Keys: []Key{key, other}}, config)
+	now := time.Now()
+
+	valid := func() Claims {
+		return Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "issuer",
+			Subject:   "2",
+			Audience:  jwt.ClaimStrings{"audience"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			NotBefore: jwt.NewNumericDate(now),
+		}}
+	}
+
+	cases := []struct {
+		caseName string
+		token    string
+	}{
+		{
+			caseName: "expired",
+			token:    sign(t, key, func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Hour)) }, valid),
+		},
+		{
+			caseName: "without an expiry",
+			token:    sign(t, key, func(c *Claims) { c.ExpiresAt = nil }, valid),
+		},
+		{
+			caseName: "not yet valid",
+			token:    sign(t, key, func(c *Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour)) }, valid),
+		},
+		{
+			caseName: "for another audience",
+			token:    sign(t, key, func(c *Claims) { c.Audience = jwt.ClaimStrings{"elsewhere"} }, valid),
+		},
+		{
+			caseName: "from another issuer",
+			token:    sign(t, key, func(c *Claims) { c.Issuer = "elsewhere" }, valid),
+		},
+		{
+			caseName: "without a subject",
+			token:    sign(t, key, func(c *Claims) { c.Subject = "" }, valid),
+		},
+		{
+			caseName: "with an unknown scope",
+			token:    sign(t, key, func(c *Claims) { c.Scope = "tasks:read everything" }, valid),
+		},
+		{
+			caseName: "signed by an unknown key",
+			token:    sign(t, mustGenerate(t, EdDSA), func(c *Claims) {}, valid),
+		},
+		{
+			caseName: "claiming another key's algorithm",
+			token:    signWith(t, jwt.SigningMethodHS256, key.ID, other.hmac, valid()),
+		},
+		{
+			caseName: "unsigned",
+			token:    signWith(t, jwt.SigningMethodNone, key.ID, jwt.UnsafeAllowNoneSignatureType, valid()),
+		},
+	}
+
+	if _, err := issuer.Verify(sign(t, key, func(c *Claims) {}, valid)); err != nil {
+		t.Fatalf("Error was not expected for a valid token, got %s", err)
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			if _, err := issuer.Verify(testCase.token); !errors.Is(err, model.ErrUnauthorized) {
+				t.Errorf("got %v want %v", err, model.ErrUnauthorized)
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old := mustGenerate(t, RS256)
+	keys := &KeySet{Keys: []Key{old}}
+	issuer := NewJWT(keys, config)
+
+	before, err := issuer.Issue(model.User{ID: 2})
+	if err != nil {
+		t.Fatalf("Error was not expected while issuing, got %s", err)
+	}
+
+	keys.Add(mustGenerate(t, EdDSA))
+	after, err := issuer.Issue(model.User{ID: 2})
+	if err != nil {
+		t.Fatalf("Error was not expected while issuing, got %s", err)
+	}
+	if kid := header(t, after.AccessToken)["kid"]; kid != keys.Keys[0].ID {
+		t.Errorf("expected the new key to sign, got kid %v", kid)
+	}
+	for _, token := range []string{before.AccessToken, after.AccessToken} {
+		if _, err := issuer.Verify(token); err != nil {
+			t.Errorf("Error was not expected while both keys are in the set, got %s", err)
+		}
+	}
+
+	keys.Remove(old.ID)
+	if _, err := issuer.Verify(before.AccessToken); !errors.Is(err, model.ErrUnauthorized) {
+		t.Errorf("expected tokens of a removed key to fail, got %v", err)
+	}
+	if _, err := issuer.Verify(after.AccessToken); err != nil {
+		t.Errorf("Error was not expected for the new key, got %s", err)
+	}
+}
+
+func TestKeySetRoundTrip(t *testing.T) {
+	keys := &KeySet{Keys: []Key{mustGenerate(t, EdDSA), mustGenerate(t, RS256), mustGenerate(t, HS256)}}
+	path := t.TempDir() + "/jwks.json"
+	if err := keys.Save(path); err != nil {
+		t.Fatalf("Error was not expected while saving, got %s", err)
+	}
+
+	loaded, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatalf("Error was not expected while loading, got %s", err)
+	}
+	for i, key := range keys.Keys {
+		token, err := NewJWT(&KeySet{Keys: []Key{key}}, config).Issue(model.User{ID: 2})
+		if err != nil {
+			t.Fatalf("Error was not expected while issuing, got %s", err)
+		}
+		if _, err := NewJWT(&KeySet{Keys: loaded.Keys[i : i+1]}, config).Verify(token.AccessToken); err != nil {
+			t.Errorf("expected the loaded %s key to verify, got %s", key.Algorithm, err)
+		}
+	}
+
+	public, err := keys.Public()
+	if err != nil {
+		t.Fatalf("Error was not expected while publishing, got %s", err)
+	}
+	published, err := ParseKeySet(public)
+	if err != nil {
+		t.Fatalf("Error was not expected while parsing the public keys, got %s", err)
+	}
+	if len(published.Keys) != 2 || strings.Contains(string(public), `"d"`) || strings.Contains(string(public), `"k"`) {
+		t.Errorf("expected only the public asymmetric keys, got %s", public)
+	}
+	if _, err := published.Signing(); !errors.Is(err, model.ErrNoSigningKey) {
+		t.Errorf("expected public keys not to sign, got %v", err)
+	}
+
+	if _, err := ParseKeySet([]byte(`{"keys": [{"kid": "a", "kty": "oct", "alg": "HS256", "k": "c2hvcnQ"}]}`)); !errors.Is(err, model.ErrInvalidSigningKey) {
+		t.Errorf("expected a short HS256 key to be refused, got %v", err)
+	}
+}
+
+func mustGenerate(t testing.TB, algorithm string) Key {
+	t.Helper()
+
+	key, err := GenerateKey(algorithm)
+	if err != nil {
+		t.Fatalf("Error was not expected while generating a %s key, got %s", algorithm, err)
+	}
+	return key
+}
+
+func sign(t testing.TB, key Key, change func(c *Claims), valid func() Claims) string {
+	t.Helper()
+
+	claims := valid()
+	change(&claims)
+	return signWith(t, jwt.GetSigningMethod(key.Algorithm), key.ID, key.signingKey(), claims)
+}
+
+func signWith(t testing.TB, method jwt.SigningMethod, kid string, key any, claims Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Error was not expected while signing, got %s", err)
+	}
+	return signed
+}
+
+func header(t testing.TB, token string) map[string]any {
+	t.Helper()
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("Error was not expected while parsing, got %s", err)
+	}
+	return parsed.Header
+}
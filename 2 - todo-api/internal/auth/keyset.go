@@ -0,0 +1,271 @@
+// Package auth signs and verifies JWTs with keys kept in a local JWKS-style file.
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gochallenges/internal/model"
+	"math/big"
+	"os"
+	"time"
+)
+
+const (
+	HS256, RS256, EdDSA = "HS256", "RS256", "EdDSA"
+)
+
+const rsaKeyBits = 2048
+const hmacKeyBytes = 32
+
+// Key is one JSON Web Key; without its private part it can only verify.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	hmac    []byte
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet signs with its first key and verifies with any, so a key rotates by adding one in front.
+type KeySet struct {
+	Keys []Key
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	K   string `json:"k,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+func GenerateKey(algorithm string) (Key, error) {
+	key := Key{ID: fmt.Sprintf("%s-%d", algorithm, time.Now().UnixNano()), Algorithm: algorithm}
+
+	var err error
+	switch algorithm {
+	case HS256:
+		key.hmac = make([]byte, hmacKeyBytes)
+		_, err = rand.Read(key.hmac)
+	case RS256:
+		key.private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case EdDSA:
+		_, key.private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return key, fmt.Errorf("%w: unsupported algorithm %q", model.ErrInvalidSigningKey, algorithm)
+	}
+	if key.private != nil {
+		key.public = key.private.Public()
+	}
+
+	return key, err
+}
+
+func LoadKeySet(path string) (*KeySet, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeySet(content)
+}
+
+func ParseKeySet(content []byte) (*KeySet, error) {
+	var set jwks
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, fmt.Errorf("%w: %s", model.ErrInvalidSigningKey, err)
+	}
+
+	keys := &KeySet{}
+	for _, k := range set.Keys {
+		key, err := k.key()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := keys.Find(key.ID); ok {
+			return nil, fmt.Errorf("%w: duplicate kid %q", model.ErrInvalidSigningKey, key.ID)
+		}
+		keys.Keys = append(keys.Keys, key)
+	}
+	return keys, nil
+}
+
+// Save writes the key set, private parts included, readable by its owner only.
+func (s *KeySet) Save(path string) error {
+	content, err := json.MarshalIndent(s.jwks(false), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o600)
+}
+
+// Add puts key in front, so it signs from now on.
+func (s *KeySet) Add(key Key) {
+	s.Keys = append([]Key{key}, s.Keys...)
+}
+
+func (s *KeySet) Remove(kid string) bool {
+	for i, key := range s.Keys {
+		if key.ID == kid {
+			s.Keys = append(s.Keys[:i], s.Keys[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Public leaves out private parts and HS256 keys, which are secret either way.
+func (s *KeySet) Public() ([]byte, error) {
+	return json.Marshal(s.jwks(true))
+}
+
+func (s *KeySet) Find(kid string) (Key, bool) {
+	for _, key := range s.Keys {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+func (s *KeySet) Signing() (Key, error) {
+	if len(s.Keys) == 0 || !s.Keys[0].canSign() {
+		return Key{}, model.ErrNoSigningKey
+	}
+	return s.Keys[0], nil
+}
+
+func (s *KeySet) jwks(public bool) jwks {
+	set := jwks{Keys: []jwk{}}
+	for _, key := range s.Keys {
+		if public && key.Algorithm == HS256 {
+			continue
+		}
+		set.Keys = append(set.Keys, key.jwk(public))
+	}
+	return set
+}
+
+func (k Key) canSign() bool {
+	return k.hmac != nil || k.private != nil
+}
+
+func (k Key) signingKey() any {
+	if k.Algorithm == HS256 {
+		return k.hmac
+	}
+	return k.private
+}
+
+func (k Key) verificationKey() any {
+	if k.Algorithm == HS256 {
+		return k.hmac
+	}
+	return k.public
+}
+
+func (k Key) jwk(public bool) jwk {
+	out := jwk{Kid: k.ID, Alg: k.Algorithm, Use: "sig"}
+	switch key := k.public.(type) {
+	case nil:
+		out.Kty, out.K = "oct", encode(k.hmac)
+	case *rsa.PublicKey:
+		out.Kty = "RSA"
+		out.N, out.E = encode(key.N.Bytes()), encode(big.NewInt(int64(key.E)).Bytes())
+		if private, ok := k.private.(*rsa.PrivateKey); ok && !public {
+			out.D, out.P, out.Q = encode(private.D.Bytes()), encode(private.Primes[0].Bytes()), encode(private.Primes[1].Bytes())
+		}
+	case ed25519.PublicKey:
+		out.Kty, out.Crv, out.X = "OKP", "Ed25519", encode(key)
+		if private, ok := k.private.(ed25519.PrivateKey); ok && !public {
+			out.D = encode(private.Seed())
+		}
+	}
+	return out
+}
+
+func (k jwk) key() (Key, error) {
+	key := Key{ID: k.Kid, Algorithm: k.Alg}
+	if k.Kid == "" {
+		return key, fmt.Errorf("%w: missing kid", model.ErrInvalidSigningKey)
+	}
+	invalid := fmt.Errorf("%w: %q is not a valid %s key", model.ErrInvalidSigningKey, k.Kid, k.Alg)
+
+	switch {
+	case k.Alg == HS256 && k.Kty == "oct":
+		secret, err := decode(k.K)
+		if err != nil || len(secret) < hmacKeyBytes {
+			return key, invalid
+		}
+		key.hmac = secret
+	case k.Alg == RS256 && k.Kty == "RSA":
+		n, errN := decode(k.N)
+		e, errE := decode(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return key, invalid
+		}
+		publicKey := rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		key.public = &publicKey
+		if k.D == "" {
+			return key, nil
+		}
+		d, errD := decode(k.D)
+		p, errP := decode(k.P)
+		q, errQ := decode(k.Q)
+		if errD != nil || errP != nil || errQ != nil {
+			return key, invalid
+		}
+		private := &rsa.PrivateKey{PublicKey: publicKey, D: new(big.Int).SetBytes(d), Primes: []*big.Int{new(big.Int).SetBytes(p), new(big.Int).SetBytes(q)}}
+		if err := private.Validate(); err != nil {
+			return key, invalid
+		}
+		private.Precompute()
+		key.private = private
+	case k.Alg == EdDSA && k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := decode(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return key, invalid
+		}
+		key.public = ed25519.PublicKey(x)
+		if k.D == "" {
+			return key, nil
+		}
+		seed, err := decode(k.D)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return key, invalid
+		}
+		private := ed25519.NewKeyFromSeed(seed)
+		if !private.Public().(ed25519.PublicKey).Equal(key.public) {
+			return key, invalid
+		}
+		key.private = private
+	default:
+		return key, fmt.Errorf("%w: %q has unsupported algorithm %q", model.ErrInvalidSigningKey, k.Kid, k.Alg)
+	}
+
+	return key, nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"fmt"
+	"gochallenges/internal/model"
+	"gochallenges/internal/problem"
+	"net/http"
+)
+
+type batchResponse struct {
+	Create []batchResult `json:"create,omitempty"`
+	Update []batchResult `json:"update,omitempty"`
+	Delete []batchResult `json:"delete,omitempty"`
+}
+
+type batchResult struct {
+	Task  *model.Task      `json:"task,omitempty"`
+	Error *problem.Problem `json:"error,omitempty"`
+}
+
+// Batch checks tasks:delete itself, as only batches that delete need it.
+func (c *Task) Batch(w http.ResponseWriter, r *http.Request) {
+	batch := model.Batch{}
+	if err := parseJsonBody(w, r, &batch); err != nil {
+		writeErrorResponse(w, r, model.ErrInvalidRequestBody)
+		return
+	}
+
+	if user, _ := model.UserFromContext(r.Context()); len(batch.Delete) > 0 && !user.Scopes.Has(model.ScopeTasksDelete) {
+		writeErrorResponse(w, r, fmt.Errorf("%w: needs scope %s", model.ErrForbidden, model.ScopeTasksDelete))
+		return
+	}
+
+	results, err := c.service.Batch(r.Context(), batch)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, batchResponse{
+		Create: toBatchResults(results.Create),
+		Update: toBatchResults(results.Update),
+		Delete: toBatchResults(results.Delete),
+	})
+}
+
+func toBatchResults(results []model.BatchResult) []batchResult {
+	converted := make([]batchResult, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			p := problem.From(result.Err)
+			converted[i].Error = &p
+			continue
+		}
+		task := result.Task
+		converted[i].Task = &task
+	}
+	return converted
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"gochallenges/internal/model"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const etagHeader = "ETag"
+const ifMatchHeader = "If-Match"
+const ifNoneMatchHeader = "If-None-Match"
+
+func taskETag(task model.Task) string {
+	return strconv.Quote(strconv.Itoa(task.Version))
+}
+
+func setETag(w http.ResponseWriter, task model.Task) {
+	w.Header().Set(etagHeader, taskETag(task))
+}
+
+// GetIfMatchVersion fails weak or unparseable tags, which never match a version.
+func GetIfMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get(ifMatchHeader))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, model.ErrTaskVersionMismatch
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, model.ErrTaskVersionMismatch
+	}
+	return version, nil
+}
+
+// isNotModified uses weak comparison, as RFC 9110 asks.
+func isNotModified(r *http.Request, task model.Task) bool {
+	header := r.Header.Get(ifNoneMatchHeader)
+	if header == "" {
+		return false
+	}
+
+	etag := taskETag(task)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
+	"net/http"
+	"time"
+)
+
+const eventStreamContentType = "text/event-stream"
+const lastEventIdHeader = "Last-Event-ID"
+
+// resetEvent tells a client that events were lost and it should list the tasks again.
+const resetEvent = "reset"
+
+var keepAliveInterval = 15 * time.Second
+
+// Events first sends a client reconnecting with Last-Event-ID the events it missed.
+func (c *Task) Events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorResponse(w, r, model.ErrInternalServerError)
+		return
+	}
+
+	var lastID uint64
+	resumable := true
+	if header := r.Header.Get(lastEventIdHeader); header != "" {
+		lastID, resumable = c.events.ParseToken(header)
+	}
+	sub, missed, complete := c.events.Subscribe(lastID)
+	defer sub.Close()
+
+	w.Header().Set("content-type", eventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if !resumable || !complete {
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: {}\n\n", c.events.Token(sub.Start), resetEvent)
+	}
+	user, _ := model.UserFromContext(r.Context())
+	filter := event.Filter{OwnerID: user.ID}
+	for _, e := range missed {
+		c.writeEvent(w, filter, e)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, open := <-sub.C:
+			// A closed subscription fell behind; the client catches up when it reconnects.
+			if !open {
+				return
+			}
+			c.writeEvent(w, filter, e)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func (c *Task) writeEvent(w http.ResponseWriter, filter event.Filter, e event.Event) {
+	if !filter.Sees(e) {
+		return
+	}
+
+	data, err := json.Marshal(e.Task)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", c.events.Token(e.ID), e.Type, data)
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"encoding/json"
+	"gochallenges/internal/model"
+	"mime"
+	"net/http"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// isMergePatch also accepts plain JSON, which is what most clients send.
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("content-type"))
+	return err == nil && (mediaType == mergePatchContentType || mediaType == jsonContentType)
+}
+
+func mergePatchTask(task model.Task, patch []byte) (model.Task, error) {
+	var patchedTask model.Task
+
+	current, err := json.Marshal(task)
+	if err != nil {
+		return patchedTask, err
+	}
+
+	var target, changes any
+	if err := json.Unmarshal(current, &target); err != nil {
+		return patchedTask, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return patchedTask, model.ErrInvalidRequestBody
+	}
+	if _, ok := changes.(map[string]any); !ok {
+		return patchedTask, model.ErrInvalidRequestBody
+	}
+
+	patched, err := json.Marshal(mergePatch(target, changes))
+	if err != nil {
+		return patchedTask, err
+	}
+	if err := json.Unmarshal(patched, &patchedTask); err != nil {
+		return patchedTask, model.ErrInvalidRequestBody
+	}
+
+	return patchedTask, nil
+}
+
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
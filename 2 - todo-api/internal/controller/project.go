@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/internal/service"
+	"net/http"
+	"strconv"
+)
+
+type Project struct {
+	service service.Project
+	tasks   Task
+}
+
+type projectRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func NewProject(store repository.Store, events *event.Bus) Project {
+	return Project{service: service.NewProject(store, events), tasks: NewTask(store, events)}
+}
+
+func (c *Project) Create(w http.ResponseWriter, r *http.Request) {
+	request := projectRequest{}
+	if err := parseJsonBody(w, r, &request); err != nil {
+		writeErrorResponse(w, r, model.ErrInvalidRequestBody)
+		return
+	}
+
+	project, err := c.service.Create(r.Context(), model.Project{Name: request.Name, Description: request.Description})
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeCreatedResponse(w, project)
+}
+
+func (c *Project) List(w http.ResponseWriter, r *http.Request) {
+	archived, _ := strconv.ParseBool(r.URL.Query().Get("archived"))
+
+	projects, err := c.service.List(r.Context(), archived)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, projects)
+}
+
+func (c *Project) GetById(w http.ResponseWriter, r *http.Request, id int) {
+	project, err := c.service.Find(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, project)
+}
+
+func (c *Project) Update(w http.ResponseWriter, r *http.Request, id int) {
+	request := projectRequest{}
+	if err := parseJsonBody(w, r, &request); err != nil {
+		writeErrorResponse(w, r, model.ErrInvalidRequestBody)
+		return
+	}
+
+	project, err := c.service.Update(r.Context(), model.Project{ID: id, Name: request.Name, Description: request.Description})
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, project)
+}
+
+// Delete archives the project unless ?mode= says otherwise.
+func (c *Project) Delete(w http.ResponseWriter, r *http.Request, id int) {
+	values := r.URL.Query()
+	var toId *int
+	if toString := values.Get("to"); toString != "" {
+		to, err := strconv.Atoi(toString)
+		if err != nil || to <= 0 {
+			writeErrorResponse(w, r, model.ErrInvalidProjectId)
+			return
+		}
+		toId = &to
+	}
+
+	if err := c.service.Delete(r.Context(), id, model.ProjectDeleteMode(values.Get("mode")), toId); err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, nil)
+}
+
+func (c *Project) Unarchive(w http.ResponseWriter, r *http.Request, id int) {
+	project, err := c.service.Unarchive(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, project)
+}
+
+func (c *Project) Tasks(w http.ResponseWriter, r *http.Request, id int) {
+	if _, err := c.service.Find(r.Context(), id); err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	c.tasks.ListInProject(w, r, id)
+}
+
+func (c *Project) Stats(w http.ResponseWriter, r *http.Request, id int) {
+	stats, err := c.service.Stats(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, stats)
+}
+
+func WithProjectId(pathId func(r *http.Request) string, handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return withId(pathId, model.ErrInvalidProjectId, handler)
+}
@@ -1,153 +1,350 @@
 package controller
 
 import (
-	"errors"
+	"encoding/json"
+	"fmt"
+	"gochallenges/internal/event"
 	"gochallenges/internal/model"
+	"gochallenges/internal/query"
 	"gochallenges/internal/repository"
 	"gochallenges/internal/service"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
-	"strings"
+	"time"
 )
 
 type Task struct {
 	repository repository.Task
 	service    service.Task
+	events     *event.Bus
 }
 
-func NewTask(repository repository.Task) Task {
+func NewTask(store repository.Store, events *event.Bus) Task {
 	return Task{
-		repository: repository,
-		service:    service.NewTask(repository),
+		repository: store.Tasks,
+		service:    service.NewTask(store, events),
+		events:     events,
 	}
 }
 
-func (c *Task) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	authorized := authorizeRequest(w, r)
-	if !authorized {
-		writeUnauthorizedResponse(w, model.ErrUnauthorized)
-		return
+func (c *Task) List(w http.ResponseWriter, r *http.Request) {
+	c.ListInProject(w, r, 0)
+}
+
+func (c *Task) ListInProject(w http.ResponseWriter, r *http.Request, projectId int) {
+	if HasTaskQueryInRequest(r) {
+		c.GetByQuery(w, r, projectId)
+	} else if status, length := GetTaskStatusFromRequest(r); length > 0 {
+		c.GetByStatus(w, r, projectId, status)
+	} else {
+		c.GetAll(w, r, projectId)
 	}
+}
 
-	if !strings.Contains(r.URL.Path, "/tasks") {
-		writeNotFoundResponse(w)
+func (c *Task) GetAll(w http.ResponseWriter, r *http.Request, projectId int) {
+	page, err := GetPageFromRequest(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
 		return
 	}
 
-	switch r.Method {
+	tasks, err := c.repository.FindAll(r.Context(), projectId, page)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	writePageResponse(w, tasks)
+}
 
-	case http.MethodGet:
-		if id, length := GetTaskIdFromRequest(r.URL.Path); length > 0 && id != 0 {
-			c.GetById(w, id)
-		} else {
-			if status, length := GetTaskStatusFromRequest(r); length > 0 {
-				c.GetByStatus(w, status)
-			} else {
-				c.GetAll(w)
-			}
-		}
+func (c *Task) GetById(w http.ResponseWriter, r *http.Request, id int) {
+	task, err := c.repository.FindByID(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
 
-	case http.MethodPost:
-		c.Create(w, r)
+	setETag(w, task)
+	if isNotModified(r, task) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeOkResponse(w, task)
+}
 
-	case http.MethodPut:
-		if id, length := GetTaskIdFromRequest(r.URL.Path); length > 0 {
-			c.Update(w, r, id)
-		}
+func (c *Task) GetByStatus(w http.ResponseWriter, r *http.Request, projectId int, completed bool) {
+	page, err := GetPageFromRequest(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
 
-	case http.MethodDelete:
-		if id, length := GetTaskIdFromRequest(r.URL.Path); length > 0 {
-			c.Delete(w, r, id)
-		}
+	tasks, err := c.repository.FindByStatus(r.Context(), projectId, completed, page)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
 	}
+	writePageResponse(w, tasks)
 }
 
-func (c *Task) GetAll(w http.ResponseWriter) {
-	tasks, err := c.repository.FindAll()
+func (c *Task) GetByQuery(w http.ResponseWriter, r *http.Request, projectId int) {
+	q, err := GetTaskQueryFromRequest(r)
 	if err != nil {
-		writeInternalErrorResponse(w, err)
+		writeErrorResponse(w, r, err)
 		return
 	}
-	writeOkResponse(w, tasks)
-}
+	if projectId != 0 {
+		q.Filter = query.AndAlso(q.Filter, query.Comparison{Field: "project_id", Op: query.Eq, Value: projectId})
+	}
 
-func (c *Task) GetById(w http.ResponseWriter, id int) {
-	task, err := c.repository.FindByID(id)
+	page, err := GetPageFromRequest(r)
 	if err != nil {
-		writeInternalErrorResponse(w, err)
+		writeErrorResponse(w, r, err)
 		return
 	}
-	writeOkResponse(w, task)
-}
 
-func (c *Task) GetByStatus(w http.ResponseWriter, completed bool) {
-	task, err := c.repository.FindByStatus(completed)
+	tasks, err := c.repository.Find(r.Context(), q, page)
 	if err != nil {
-		writeInternalErrorResponse(w, err)
+		writeErrorResponse(w, r, err)
 		return
 	}
-	writeOkResponse(w, task)
+	writePageResponse(w, tasks)
 }
 
+// Create gives retries with the same Idempotency-Key the original response.
 func (c *Task) Create(w http.ResponseWriter, r *http.Request) {
 	task := model.Task{}
 	if err := parseJsonBody(w, r, &task); err != nil {
-		writeBadRequestResponse(w, model.ErrInvalidRequestBody)
+		writeErrorResponse(w, r, model.ErrInvalidRequestBody)
 		return
 	}
 
-	createdTask, err := c.service.Create(task)
+	createdTask, replayed, err := c.service.CreateOnce(r.Context(), task, r.Header.Get(idempotencyKeyHeader))
 	if err != nil {
-		if errors.Is(err, model.ErrInvalidTaskName) || errors.Is(err, model.ErrTaskAlreadyExists) {
-			writeBadRequestResponse(w, err)
-			return
-		}
-		writeInternalErrorResponse(w, err)
+		writeErrorResponse(w, r, err)
 		return
 	}
 
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
+	setETag(w, createdTask)
 	writeCreatedResponse(w, createdTask)
 }
 
+// Update takes the precondition from If-Match, or else from the version in the body.
 func (c *Task) Update(w http.ResponseWriter, r *http.Request, id int) {
+	version, err := GetIfMatchVersion(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
 	modifiedTask := model.Task{}
 	if err := parseJsonBody(w, r, &modifiedTask); err != nil {
-		writeBadRequestResponse(w, model.ErrInvalidRequestBody)
+		writeErrorResponse(w, r, model.ErrInvalidRequestBody)
 		return
 	}
 
-	updatedTask, err := c.service.Update(modifiedTask)
+	if modifiedTask.ID == 0 {
+		modifiedTask.ID = id
+	}
+	if modifiedTask.ID != id {
+		writeErrorResponse(w, r, model.ErrInvalidTaskId)
+		return
+	}
+	if version != 0 {
+		modifiedTask.Version = version
+	}
+
+	updatedTask, err := c.service.UpdateWith(r.Context(), modifiedTask, GetCompletionModeFromRequest(r))
 	if err != nil {
-		if errors.Is(err, model.ErrInvalidTaskId) || errors.Is(err, model.ErrInvalidTaskName) || errors.Is(err, model.ErrTaskNotFound) {
-			writeBadRequestResponse(w, err)
-			return
-		}
-		writeInternalErrorResponse(w, err)
+		writeErrorResponse(w, r, err)
 		return
 	}
 
+	setETag(w, updatedTask)
 	writeOkResponse(w, updatedTask)
 }
 
 func (c *Task) Delete(w http.ResponseWriter, r *http.Request, taskId int) {
-	err := c.service.Delete(taskId)
+	version, err := GetIfMatchVersion(r)
 	if err != nil {
-		if errors.Is(err, model.ErrTaskNotFound) {
-			writeBadRequestResponse(w, err)
-			return
-		}
-		writeInternalErrorResponse(w, err)
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	err = c.service.Delete(r.Context(), taskId, version)
+	if err != nil {
+		writeErrorResponse(w, r, err)
 		return
 	}
 
 	writeOkResponse(w, nil)
 }
 
-func GetTaskIdFromRequest(url string) (int, int) {
-	idString := strings.TrimPrefix(url, "/tasks/")
-	idLength := len(idString)
-	id, _ := strconv.Atoi(idString)
-	return id, idLength
+func (c *Task) Subtasks(w http.ResponseWriter, r *http.Request, id int) {
+	q, err := GetTaskQueryFromRequest(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	page, err := GetPageFromRequest(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	tasks, err := c.service.Subtasks(r.Context(), id, q, page)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	writePageResponse(w, tasks)
+}
+
+func (c *Task) Tree(w http.ResponseWriter, r *http.Request, id int) {
+	tree, err := c.service.Tree(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, tree)
+}
+
+func (c *Task) Trash(w http.ResponseWriter, r *http.Request) {
+	page, err := GetPageFromRequest(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	tasks, err := c.repository.FindTrash(r.Context(), page)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	writePageResponse(w, tasks)
+}
+
+func (c *Task) Restore(w http.ResponseWriter, r *http.Request, id int) {
+	restoredTask, err := c.service.Restore(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	setETag(w, restoredTask)
+	writeOkResponse(w, restoredTask)
+}
+
+func (c *Task) History(w http.ResponseWriter, r *http.Request, id int) {
+	page, err := GetPageFromRequest(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	records, err := c.service.History(r.Context(), model.AuditFilter{TaskID: id}, page)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	writeAuditPageResponse(w, records)
+}
+
+// Audit serves GET /audit with the records of every user, filtered by actor and time.
+func (c *Task) Audit(w http.ResponseWriter, r *http.Request) {
+	page, err := GetPageFromRequest(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	filter, err := GetAuditFilterFromRequest(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	filter.AllOwners = true
+
+	records, err := c.service.History(r.Context(), filter, page)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	writeAuditPageResponse(w, records)
+}
+
+// Patch is conditional on the version that was patched, or on If-Match.
+func (c *Task) Patch(w http.ResponseWriter, r *http.Request, id int) {
+	if !isMergePatch(r) {
+		writeErrorResponse(w, r, model.ErrUnsupportedMediaType)
+		return
+	}
+
+	version, err := GetIfMatchVersion(r)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(patch) {
+		writeErrorResponse(w, r, model.ErrInvalidRequestBody)
+		return
+	}
+
+	task, err := c.repository.FindByID(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	if version != 0 && version != task.Version {
+		writeErrorResponse(w, r, model.ErrTaskVersionMismatch)
+		return
+	}
+
+	patchedTask, err := mergePatchTask(task, patch)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+	if patchedTask.ID != id {
+		writeErrorResponse(w, r, model.ErrInvalidTaskId)
+		return
+	}
+	patchedTask.Version = task.Version
+
+	updatedTask, err := c.service.UpdateWith(r.Context(), patchedTask, GetCompletionModeFromRequest(r))
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	setETag(w, updatedTask)
+	writeOkResponse(w, updatedTask)
+}
+
+func WithTaskId(pathId func(r *http.Request) string, handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return withId(pathId, model.ErrInvalidTaskId, handler)
+}
+
+func WithTokenId(pathId func(r *http.Request) string, handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return withId(pathId, model.ErrInvalidTokenId, handler)
+}
+
+func withId(pathId func(r *http.Request) string, invalid error, handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(pathId(r))
+		if err != nil || id <= 0 {
+			writeErrorResponse(w, r, invalid)
+			return
+		}
+		handler(w, r, id)
+	}
 }
 
 func GetTaskStatusFromRequest(r *http.Request) (bool, int) {
@@ -156,3 +353,92 @@ func GetTaskStatusFromRequest(r *http.Request) (bool, int) {
 	status, _ := strconv.ParseBool(paramString)
 	return status, paramLength
 }
+
+func HasTaskQueryInRequest(r *http.Request) bool {
+	values := r.URL.Query()
+	return values.Has("filter") || values.Has("order_by")
+}
+
+// GetTaskQueryFromRequest adds the older ?completed= parameter to the filter.
+func GetTaskQueryFromRequest(r *http.Request) (query.Query, error) {
+	q, err := query.Parse(r.URL.Query().Get("filter"), r.URL.Query().Get("order_by"))
+	if err != nil {
+		return q, err
+	}
+
+	if paramString := r.URL.Query().Get("completed"); paramString != "" {
+		completed, err := strconv.ParseBool(paramString)
+		if err != nil {
+			return q, model.ErrInvalidTaskStatus
+		}
+		q.Filter = query.AndAlso(q.Filter, query.Comparison{Field: "completed", Op: query.Eq, Value: completed})
+	}
+
+	return q, nil
+}
+
+func GetCompletionModeFromRequest(r *http.Request) model.CompletionMode {
+	return model.CompletionMode(r.URL.Query().Get("completion"))
+}
+
+func GetPageFromRequest(r *http.Request) (model.PageRequest, error) {
+	page := model.PageRequest{Token: r.URL.Query().Get("page_token")}
+
+	if sizeString := r.URL.Query().Get("page_size"); sizeString != "" {
+		size, err := strconv.Atoi(sizeString)
+		if err != nil || size < 0 {
+			return page, model.ErrInvalidPageSize
+		}
+		page.Size = size
+	}
+
+	return page, nil
+}
+
+func GetAuditFilterFromRequest(r *http.Request) (model.AuditFilter, error) {
+	var filter model.AuditFilter
+	values := r.URL.Query()
+
+	if actorString := values.Get("actor_id"); actorString != "" {
+		actorId, err := strconv.Atoi(actorString)
+		if err != nil || actorId <= 0 {
+			return filter, fmt.Errorf("%w: invalid actor_id %q", model.ErrInvalidAuditFilter, actorString)
+		}
+		filter.ActorID = actorId
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(values, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseAuditTime(values, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseAuditTime(values url.Values, name string) (*time.Time, error) {
+	timeString := values.Get(name)
+	if timeString == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, timeString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s %q", model.ErrInvalidAuditFilter, name, timeString)
+	}
+	return &t, nil
+}
+
+func writePageResponse(w http.ResponseWriter, page model.TaskPage) {
+	if page.NextPageToken != "" {
+		w.Header().Set(nextPageTokenHeader, page.NextPageToken)
+	}
+	writeOkResponse(w, page.Tasks)
+}
+
+func writeAuditPageResponse(w http.ResponseWriter, page model.AuditPage) {
+	if page.NextPageToken != "" {
+		w.Header().Set(nextPageTokenHeader, page.NextPageToken)
+	}
+	writeOkResponse(w, page.Records)
+}
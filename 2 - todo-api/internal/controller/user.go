@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"gochallenges/internal/auth"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/internal/service"
+	"net/http"
+	"time"
+)
+
+const basicChallenge = `Basic realm="gochallenges"`
+const jwksContentType = "application/jwk-set+json"
+
+type User struct {
+	service service.User
+	jwt     *auth.JWT
+}
+
+type registerRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type tokenRequest struct {
+	Name      string       `json:"name"`
+	Scopes    model.Scopes `json:"scopes"`
+	ExpiresAt *time.Time   `json:"expires_at"`
+}
+
+func NewUser(repository repository.User, jwt *auth.JWT) User {
+	return User{service: service.NewUser(repository, jwt), jwt: jwt}
+}
+
+// Authorized lets next act for the token's user.
+func (c *User) Authorized(scope model.Scopes, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := c.service.Authenticate(r.Context(), r.Header.Get(authHeader))
+		if err != nil {
+			writeErrorResponse(w, r, err)
+			return
+		}
+		if !user.Scopes.Has(scope) {
+			writeErrorResponse(w, r, fmt.Errorf("%w: needs scope %s", model.ErrForbidden, scope))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(model.ContextWithUser(r.Context(), user)))
+	})
+}
+
+func (c *User) Register(w http.ResponseWriter, r *http.Request) {
+	request := registerRequest{}
+	if err := parseJsonBody(w, r, &request); err != nil {
+		writeErrorResponse(w, r, model.ErrInvalidRequestBody)
+		return
+	}
+
+	user, err := c.service.Register(r.Context(), request.Name, request.Password)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeCreatedResponse(w, user)
+}
+
+// CreateToken logs in with HTTP basic credentials.
+func (c *User) CreateToken(w http.ResponseWriter, r *http.Request) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", basicChallenge)
+		writeErrorResponse(w, r, model.ErrInvalidCredentials)
+		return
+	}
+
+	user, err := c.service.Login(r.Context(), name, password)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidCredentials) {
+			w.Header().Set("WWW-Authenticate", basicChallenge)
+		}
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	request := tokenRequest{}
+	if r.ContentLength != 0 {
+		if err := parseJsonBody(w, r, &request); err != nil {
+			if !errors.Is(err, model.ErrInvalidScope) {
+				err = model.ErrInvalidRequestBody
+			}
+			writeErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	token, err := c.service.CreateToken(r.Context(), user, request.Name, request.Scopes, request.ExpiresAt)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeCreatedResponse(w, token)
+}
+
+// IssueJwt exchanges basic credentials or a personal token, never a JWT, for a JWT.
+func (c *User) IssueJwt(w http.ResponseWriter, r *http.Request) {
+	var user model.User
+	var err error
+	if name, password, ok := r.BasicAuth(); ok {
+		user, err = c.service.Login(r.Context(), name, password)
+	} else {
+		user, err = c.service.AuthenticateToken(r.Context(), r.Header.Get(authHeader))
+	}
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := c.service.IssueJwt(user)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeOkResponse(w, token)
+}
+
+func (c *User) GetJwks(w http.ResponseWriter, r *http.Request) {
+	keys, err := c.jwt.Keys().Public()
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("content-type", jwksContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(keys)
+}
+
+func (c *User) GetTokens(w http.ResponseWriter, r *http.Request) {
+	user, _ := model.UserFromContext(r.Context())
+
+	tokens, err := c.service.Tokens(r.Context(), user)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, tokens)
+}
+
+func (c *User) RevokeToken(w http.ResponseWriter, r *http.Request, tokenId int) {
+	user, _ := model.UserFromContext(r.Context())
+
+	if err := c.service.RevokeToken(r.Context(), user, tokenId); err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, nil)
+}
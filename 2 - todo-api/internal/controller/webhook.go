@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/internal/service"
+	"net/http"
+)
+
+type Webhook struct {
+	service service.Webhook
+}
+
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+func NewWebhook(repository repository.Webhooks) Webhook {
+	return Webhook{service: service.NewWebhook(repository)}
+}
+
+func (c *Webhook) Create(w http.ResponseWriter, r *http.Request) {
+	request := webhookRequest{}
+	if err := parseJsonBody(w, r, &request); err != nil {
+		writeErrorResponse(w, r, model.ErrInvalidRequestBody)
+		return
+	}
+
+	webhook, err := c.service.Register(r.Context(), model.Webhook{URL: request.URL, Events: request.Events, Secret: request.Secret})
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeCreatedResponse(w, webhook)
+}
+
+func (c *Webhook) List(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := c.service.List(r.Context())
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, webhooks)
+}
+
+func (c *Webhook) Delete(w http.ResponseWriter, r *http.Request, webhookId int) {
+	if err := c.service.Delete(r.Context(), webhookId); err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, nil)
+}
+
+func (c *Webhook) Deliveries(w http.ResponseWriter, r *http.Request, webhookId int) {
+	status := model.DeliveryStatus(r.URL.Query().Get("status"))
+
+	deliveries, err := c.service.Deliveries(r.Context(), webhookId, status)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, deliveries)
+}
+
+func (c *Webhook) Redeliver(w http.ResponseWriter, r *http.Request, webhookId int, deliveryId int) {
+	delivery, err := c.service.Redeliver(r.Context(), webhookId, deliveryId)
+	if err != nil {
+		writeErrorResponse(w, r, err)
+		return
+	}
+
+	writeOkResponse(w, delivery)
+}
+
+func WithWebhookId(pathId func(r *http.Request) string, handler func(w http.ResponseWriter, r *http.Request, id int)) http.HandlerFunc {
+	return withId(pathId, model.ErrInvalidWebhookId, handler)
+}
+
+func WithDeliveryId(webhookPathId, deliveryPathId func(r *http.Request) string, handler func(w http.ResponseWriter, r *http.Request, webhookId int, deliveryId int)) http.HandlerFunc {
+	return WithWebhookId(webhookPathId, func(w http.ResponseWriter, r *http.Request, webhookId int) {
+		withId(deliveryPathId, model.ErrInvalidDeliveryId, func(w http.ResponseWriter, r *http.Request, deliveryId int) {
+			handler(w, r, webhookId, deliveryId)
+		})(w, r)
+	})
+}
@@ -0,0 +1,153 @@
+package event
+
+import (
+	"fmt"
+	"gochallenges/internal/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TaskCreated  = "task.created"
+	TaskUpdated  = "task.updated"
+	TaskDeleted  = "task.deleted"
+	TaskRestored = "task.restored"
+)
+
+var Types = []string{TaskCreated, TaskUpdated, TaskDeleted, TaskRestored}
+
+const DefaultReplaySize = 1000
+
+// subscriberBuffer is how far a subscriber may fall behind before it is dropped.
+const subscriberBuffer = 64
+
+type Event struct {
+	ID       uint64
+	Type     string
+	Task     model.Task
+	Previous *model.Task
+}
+
+// Filter ignores OwnerID when it is 0 and Completed when it is nil.
+type Filter struct {
+	OwnerID   int
+	Completed *bool
+}
+
+type Bus struct {
+	epoch int64
+
+	mu          sync.Mutex
+	lastID      uint64
+	replay      []Event
+	replaySize  int
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBus(replaySize int) *Bus {
+	return &Bus{epoch: time.Now().UnixNano(), replaySize: replaySize, subscribers: map[*Subscription]struct{}{}}
+}
+
+// Token carries the bus epoch, as event ids start over with every bus.
+func (b *Bus) Token(id uint64) string {
+	return fmt.Sprintf("%d-%d", b.epoch, id)
+}
+
+// ParseToken returns false for a token from before a restart.
+func (b *Bus) ParseToken(token string) (uint64, bool) {
+	parts := strings.SplitN(token, "-", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	epoch, epochErr := strconv.ParseInt(parts[0], 10, 64)
+	id, idErr := strconv.ParseUint(parts[1], 10, 64)
+	if epochErr != nil || idErr != nil || epoch != b.epoch {
+		return 0, false
+	}
+	return id, true
+}
+
+// Subscription closes C when it falls too far behind.
+type Subscription struct {
+	C     <-chan Event
+	Start uint64
+
+	c   chan Event
+	bus *Bus
+}
+
+func (b *Bus) Publish(event Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+
+	b.replay = append(b.replay, event)
+	if len(b.replay) > b.replaySize {
+		b.replay = b.replay[len(b.replay)-b.replaySize:]
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.c <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+
+	return event
+}
+
+// Subscribe also returns the held events after lastID, and whether none are missing.
+func (b *Bus) Subscribe(lastID uint64) (sub *Subscription, missed []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := make(chan Event, subscriberBuffer)
+	sub = &Subscription{C: c, Start: b.lastID, c: c, bus: b}
+	b.subscribers[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, nil, true
+	}
+	if lastID > b.lastID {
+		return sub, nil, false
+	}
+
+	oldest := b.lastID + 1
+	if len(b.replay) > 0 {
+		oldest = b.replay[0].ID
+	}
+	for _, event := range b.replay {
+		if event.ID > lastID {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed, lastID+1 >= oldest
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	if _, ok := s.bus.subscribers[s]; ok {
+		s.bus.drop(s)
+	}
+}
+
+func (f Filter) Matches(task model.Task) bool {
+	return (f.OwnerID == 0 || task.OwnerID == f.OwnerID) && (f.Completed == nil || task.Completed == *f.Completed)
+}
+
+// Sees also matches tasks that stop matching the filter, so watchers learn of them.
+func (f Filter) Sees(e Event) bool {
+	return f.Matches(e.Task) || e.Previous != nil && f.Matches(*e.Previous)
+}
+
+func (b *Bus) drop(sub *Subscription) {
+	delete(b.subscribers, sub)
+	close(sub.c)
+}
@@ -0,0 +1,184 @@
+package event_test
+
+import (
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	cases := []struct {
+		caseName         string
+		published        int
+		lastID           uint64
+		expectedMissed   []uint64
+		expectedComplete bool
+	}{
+		{
+			caseName:         "without a last id",
+			published:        2,
+			expectedComplete: true,
+		},
+		{
+			caseName:         "resume from the replay buffer",
+			published:        3,
+			lastID:           1,
+			expectedMissed:   []uint64{2, 3},
+			expectedComplete: true,
+		},
+		{
+			caseName:         "resume when up to date",
+			published:        3,
+			lastID:           3,
+			expectedComplete: true,
+		},
+		{
+			caseName:         "resume from before the replay buffer",
+			published:        6,
+			lastID:           1,
+			expectedMissed:   []uint64{3, 4, 5, 6},
+			expectedComplete: false,
+		},
+		{
+			caseName:         "resume from the oldest event the buffer lost",
+			published:        6,
+			lastID:           2,
+			expectedMissed:   []uint64{3, 4, 5, 6},
+			expectedComplete: true,
+		},
+		{
+			caseName:         "resume from a previous process",
+			published:        1,
+			lastID:           9,
+			expectedComplete: false,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			bus := event.NewBus(4)
+			for i := 0; i < testCase.published; i++ {
+				bus.Publish(event.Event{Type: event.TaskCreated, Task: model.Task{ID: i + 1}})
+			}
+
+			sub, missed, complete := bus.Subscribe(testCase.lastID)
+			defer sub.Close()
+
+			var ids []uint64
+			for _, e := range missed {
+				ids = append(ids, e.ID)
+			}
+			if len(ids) != len(testCase.expectedMissed) {
+				t.Fatalf("got missed %v want %v", ids, testCase.expectedMissed)
+			}
+			for i := range ids {
+				if ids[i] != testCase.expectedMissed[i] {
+					t.Errorf("got missed %v want %v", ids, testCase.expectedMissed)
+				}
+			}
+			if complete != testCase.expectedComplete {
+				t.Errorf("got complete %v want %v", complete, testCase.expectedComplete)
+			}
+		})
+	}
+}
+
+func TestPublishReachesSubscribers(t *testing.T) {
+	bus := event.NewBus(event.DefaultReplaySize)
+	sub, _, _ := bus.Subscribe(0)
+
+	published := bus.Publish(event.Event{Type: event.TaskUpdated, Task: model.Task{ID: 1, Name: "Task 1"}})
+	if received := <-sub.C; received != published {
+		t.Errorf("got %+v want %+v", received, published)
+	}
+
+	sub.Close()
+	sub.Close()
+	if _, open := <-sub.C; open {
+		t.Errorf("expected a closed subscription to close its channel")
+	}
+	bus.Publish(event.Event{Type: event.TaskDeleted, Task: model.Task{ID: 1}})
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	bus := event.NewBus(event.DefaultReplaySize)
+	slow, _, _ := bus.Subscribe(0)
+	defer slow.Close()
+
+	for i := 0; i < 1000; i++ {
+		bus.Publish(event.Event{Type: event.TaskCreated, Task: model.Task{ID: i + 1}})
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received == 0 || received >= 1000 {
+		t.Errorf("expected the slow subscriber to get some events and then be dropped, got %d", received)
+	}
+}
+
+func TestFilterSees(t *testing.T) {
+	completed, open := true, false
+	aliceOpen := model.Task{ID: 1, OwnerID: 2}
+	aliceDone := model.Task{ID: 1, OwnerID: 2, Completed: true}
+
+	cases := []struct {
+		caseName string
+		filter   event.Filter
+		event    event.Event
+		expected bool
+	}{
+		{
+			caseName: "any task",
+			filter:   event.Filter{},
+			event:    event.Event{Type: event.TaskCreated, Task: aliceOpen},
+			expected: true,
+		},
+		{
+			caseName: "another user's task",
+			filter:   event.Filter{OwnerID: 3},
+			event:    event.Event{Type: event.TaskCreated, Task: aliceOpen},
+			expected: false,
+		},
+		{
+			caseName: "a task that does not match",
+			filter:   event.Filter{OwnerID: 2, Completed: &completed},
+			event:    event.Event{Type: event.TaskCreated, Task: aliceOpen},
+			expected: false,
+		},
+		{
+			caseName: "a task that starts matching",
+			filter:   event.Filter{OwnerID: 2, Completed: &completed},
+			event:    event.Event{Type: event.TaskUpdated, Task: aliceDone, Previous: &aliceOpen},
+			expected: true,
+		},
+		{
+			caseName: "a task that stops matching",
+			filter:   event.Filter{OwnerID: 2, Completed: &open},
+			event:    event.Event{Type: event.TaskUpdated, Task: aliceDone, Previous: &aliceOpen},
+			expected: true,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			if sees := testCase.filter.Sees(testCase.event); sees != testCase.expected {
+				t.Errorf("got %v want %v", sees, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	bus, other := event.NewBus(1), event.NewBus(1)
+
+	if id, ok := bus.ParseToken(bus.Token(42)); !ok || id != 42 {
+		t.Errorf("expected the token to hold 42, got %d %v", id, ok)
+	}
+	for _, token := range []string{other.Token(42), "42", "a-b", ""} {
+		if _, ok := bus.ParseToken(token); ok {
+			t.Errorf("expected %q not to be a token of the bus", token)
+		}
+	}
+}
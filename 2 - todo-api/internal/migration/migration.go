@@ -0,0 +1,311 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"gochallenges/internal/model"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are NNNN_name.up.sql and NNNN_name.down.sql files, one directory per dialect.
+//
+//go:embed mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+type Dialect string
+
+const (
+	MySQL  Dialect = "mysql"
+	SQLite Dialect = "sqlite"
+)
+
+const lockName = "schema_migrations"
+const lockTimeoutSeconds = 60
+
+const schemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at DATETIME NOT NULL
+)`
+
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+func DialectFor(driverName string) (Dialect, error) {
+	switch driverName {
+	case "mysql":
+		return MySQL, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	}
+	return "", model.ErrInvalidDbImplementation
+}
+
+func New(db *sql.DB, dialect Dialect) (*Migrator, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedAt(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return m.migrate(ctx, conn, applied, m.migrations[i].Version-1)
+			}
+		}
+		return nil
+	})
+}
+
+// To applies or rolls back migrations until exactly 1..version are applied.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return model.ErrInvalidMigrationVersion
+	}
+
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedAt(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, applied, version)
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedAt(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if at, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, applied map[int]time.Time, version int) error {
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+		if err := exec(ctx, conn, migration, migration.up); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", migration.Version, migration.Name, model.Now())
+		if err != nil {
+			return fmt.Errorf("%w: recording %s: %v", model.ErrMigrationFailed, migration, err)
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+			continue
+		}
+		if err := exec(ctx, conn, migration, migration.down); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version); err != nil {
+			return fmt.Errorf("%w: recording %s: %v", model.ErrMigrationFailed, migration, err)
+		}
+	}
+
+	return nil
+}
+
+// locked runs fn holding the migration lock, so servers starting together apply each one once.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return model.ErrConnectDatabase
+	}
+	defer conn.Close()
+
+	switch m.dialect {
+	case MySQL:
+		var acquired sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeoutSeconds).Scan(&acquired); err != nil {
+			return model.ErrExecuteQuery
+		}
+		if acquired.Int64 != 1 {
+			return model.ErrMigrationLocked
+		}
+		defer conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", lockName)
+
+		if _, err := conn.ExecContext(ctx, schemaMigrations); err != nil {
+			return model.ErrExecuteQuery
+		}
+		return fn(conn)
+
+	case SQLite:
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			return model.ErrMigrationLocked
+		}
+		if _, err := conn.ExecContext(ctx, schemaMigrations); err != nil {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+			return model.ErrExecuteQuery
+		}
+		if err := fn(conn); err != nil {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+			return model.ErrExecuteQuery
+		}
+		return nil
+	}
+
+	return model.ErrInvalidDbImplementation
+}
+
+func appliedAt(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, model.ErrScanningRows
+		}
+		applied[version] = at.UTC()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, model.ErrScanningRows
+	}
+
+	return applied, nil
+}
+
+func exec(ctx context.Context, conn *sql.Conn, migration Migration, script string) error {
+	for _, statement := range statements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%w: %s: %v", model.ErrMigrationFailed, migration, err)
+		}
+	}
+	return nil
+}
+
+func statements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	var statements []string
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// load checks the migrations are numbered 1..n with both an up and a down script.
+func load(dialect Dialect) ([]Migration, error) {
+	names, err := fs.Glob(files, string(dialect)+"/*.sql")
+	if err != nil || len(names) == 0 {
+		return nil, model.ErrInvalidDbImplementation
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, name := range names {
+		base := path.Base(name)
+		prefix, rest, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s", model.ErrInvalidMigrationVersion, name)
+		}
+
+		content, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version}
+			byVersion[version] = migration
+		}
+		switch {
+		case strings.HasSuffix(rest, ".up.sql"):
+			migration.Name = strings.TrimSuffix(rest, ".up.sql")
+			migration.up = string(content)
+		case strings.HasSuffix(rest, ".down.sql"):
+			migration.down = string(content)
+		default:
+			return nil, fmt.Errorf("%w: %s", model.ErrInvalidMigrationVersion, name)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 || migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("%w: %s", model.ErrInvalidMigrationVersion, migration)
+		}
+	}
+
+	return migrations, nil
+}
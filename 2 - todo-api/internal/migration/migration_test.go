@@ -0,0 +1,177 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gochallenges/internal/migration"
+	"gochallenges/internal/model"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestDialectsDefineTheSameMigrations(t *testing.T) {
+	mysql, err := migration.New(nil, migration.MySQL)
+	if err != nil {
+		t.Fatalf("Error was not expected while loading mysql migrations, got %s", err)
+	}
+	sqlite, err := migration.New(nil, migration.SQLite)
+	if err != nil {
+		t.Fatalf("Error was not expected while loading sqlite migrations, got %s", err)
+	}
+	if mysql.Latest() != sqlite.Latest() {
+		t.Errorf("Expected both dialects to reach the same version, got mysql %d and sqlite %d", mysql.Latest(), sqlite.Latest())
+	}
+}
+
+func TestUpDownAndTo(t *testing.T) {
+	ctx := context.Background()
+	db := openSqlite(t, ":memory:")
+	migrator := newMigrator(t, db)
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Error was not expected while migrating up, got %s", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Error was not expected while migrating up twice, got %s", err)
+	}
+	assertVersion(t, migrator, migrator.Latest())
+	assertColumn(t, db, "task", "project_id", true)
+
+	if err := migrator.Down(ctx); err != nil {
+		t.Fatalf("Error was not expected while migrating down, got %s", err)
+	}
+	assertVersion(t, migrator, migrator.Latest()-1)
+	assertColumn(t, db, "task", "project_id", false)
+	assertColumn(t, db, "task", "parent_id", true)
+
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("Error was not expected while migrating to 0, got %s", err)
+	}
+	assertVersion(t, migrator, 0)
+	assertColumn(t, db, "task", "id", false)
+
+	if err := migrator.To(ctx, migrator.Latest()); err != nil {
+		t.Fatalf("Error was not expected while migrating back up, got %s", err)
+	}
+	assertVersion(t, migrator, migrator.Latest())
+
+	if err := migrator.To(ctx, migrator.Latest()+1); !errors.Is(err, model.ErrInvalidMigrationVersion) {
+		t.Errorf("Expected %v, got %v", model.ErrInvalidMigrationVersion, err)
+	}
+}
+
+func TestUpAdoptsAnExistingTaskTable(t *testing.T) {
+	ctx := context.Background()
+	db := openSqlite(t, ":memory:")
+
+	_, err := db.Exec(`CREATE TABLE task (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(100) NOT NULL, completed BOOLEAN NOT NULL DEFAULT 0);
+		INSERT INTO task (name, completed) VALUES ('open', 0), ('done', 1)`)
+	if err != nil {
+		t.Fatalf("Error was not expected while creating the legacy table, got %s", err)
+	}
+
+	if err := newMigrator(t, db).Up(ctx); err != nil {
+		t.Fatalf("Error was not expected while migrating up, got %s", err)
+	}
+
+	rows, err := db.Query("SELECT name, created_at IS NOT NULL, completed_at IS NOT NULL FROM task ORDER BY id")
+	if err != nil {
+		t.Fatalf("Error was not expected while reading tasks, got %s", err)
+	}
+	defer rows.Close()
+
+	expected := []struct {
+		name         string
+		created      bool
+		hasCompleted bool
+	}{{"open", true, false}, {"done", true, true}}
+	for _, want := range expected {
+		var name string
+		var created, hasCompleted bool
+		if !rows.Next() {
+			t.Fatalf("Expected task %q to survive the migration", want.name)
+		}
+		if err := rows.Scan(&name, &created, &hasCompleted); err != nil {
+			t.Fatalf("Error was not expected while scanning, got %s", err)
+		}
+		if name != want.name || created != want.created || hasCompleted != want.hasCompleted {
+			t.Errorf("Expected %+v, got %s %v %v", want, name, created, hasCompleted)
+		}
+	}
+}
+
+func TestConcurrentUpAppliesEachMigrationOnce(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/todoapi.db"
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		migrator := newMigrator(t, openSqlite(t, path))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = migrator.Up(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Error was not expected while migrating concurrently, got %s", err)
+		}
+	}
+
+	var applied int
+	if err := openSqlite(t, path).QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied); err != nil {
+		t.Fatalf("Error was not expected while counting migrations, got %s", err)
+	}
+	if applied != newMigrator(t, nil).Latest() {
+		t.Errorf("Expected %d applied migrations, got %d", newMigrator(t, nil).Latest(), applied)
+	}
+}
+
+func openSqlite(t *testing.T, path string) *sql.DB {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Error was not expected while opening sqlite, got %s", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newMigrator(t *testing.T, db *sql.DB) *migration.Migrator {
+	migrator, err := migration.New(db, migration.SQLite)
+	if err != nil {
+		t.Fatalf("Error was not expected while loading migrations, got %s", err)
+	}
+	return migrator
+}
+
+func assertVersion(t *testing.T, migrator *migration.Migrator, version int) {
+	t.Helper()
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Error was not expected while reading the status, got %s", err)
+	}
+	for _, status := range statuses {
+		if applied := status.AppliedAt != nil; applied != (status.Version <= version) {
+			t.Errorf("Expected migration %d applied to be %v at version %d", status.Version, !applied, version)
+		}
+	}
+}
+
+func assertColumn(t *testing.T, db *sql.DB, table string, column string, exists bool) {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count); err != nil {
+		t.Fatalf("Error was not expected while reading the schema, got %s", err)
+	}
+	if (count == 1) != exists {
+		t.Errorf("Expected column %s.%s to exist to be %v", table, column, exists)
+	}
+}
@@ -0,0 +1,11 @@
+-- The table scripts/db/script.sql used to create, so databases set up before
+-- migrations existed are adopted as they are.
+CREATE TABLE IF NOT EXISTS task (
+	id INT NOT NULL AUTO_INCREMENT,
+	name VARCHAR(100) NOT NULL,
+	completed BOOL NULL,
+	CONSTRAINT task_PK PRIMARY KEY (id)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,8 @@
+ALTER TABLE task
+	DROP COLUMN completed_at,
+	DROP COLUMN updated_at,
+	DROP COLUMN created_at,
+	DROP COLUMN priority,
+	DROP COLUMN due_at,
+	DROP COLUMN description,
+	MODIFY completed BOOL NULL;
@@ -0,0 +1,13 @@
+UPDATE task SET completed = 0 WHERE completed IS NULL;
+
+ALTER TABLE task
+	MODIFY completed BOOL NOT NULL DEFAULT 0,
+	ADD COLUMN description VARCHAR(1000) NOT NULL DEFAULT '' AFTER completed,
+	ADD COLUMN due_at DATETIME NULL AFTER description,
+	ADD COLUMN priority TINYINT NOT NULL DEFAULT 0 AFTER due_at,
+	ADD COLUMN created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER priority,
+	ADD COLUMN updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER created_at,
+	ADD COLUMN completed_at DATETIME NULL AFTER updated_at;
+
+-- Existing rows are stamped with the time of the upgrade.
+UPDATE task SET completed_at = updated_at WHERE completed AND completed_at IS NULL;
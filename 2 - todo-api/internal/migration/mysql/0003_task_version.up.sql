@@ -0,0 +1 @@
+ALTER TABLE task ADD COLUMN version INT NOT NULL DEFAULT 1;
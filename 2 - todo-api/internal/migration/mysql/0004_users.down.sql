@@ -0,0 +1,6 @@
+ALTER TABLE task
+	DROP INDEX task_owner_id,
+	DROP COLUMN owner_id;
+
+DROP TABLE user_token;
+DROP TABLE users;
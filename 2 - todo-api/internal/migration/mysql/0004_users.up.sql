@@ -0,0 +1,33 @@
+CREATE TABLE users (
+	id INT NOT NULL AUTO_INCREMENT,
+	name VARCHAR(100) NOT NULL,
+	password_hash VARCHAR(100) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	CONSTRAINT users_PK PRIMARY KEY (id),
+	CONSTRAINT users_name UNIQUE (name)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COLLATE=utf8mb4_0900_ai_ci;
+
+-- The default user stands for the shared BEARER_TOKEN and owns the tasks
+-- created before there were users. Without a password it cannot log in.
+INSERT INTO users (id, name, password_hash, created_at) VALUES (1, 'default', '', UTC_TIMESTAMP());
+
+CREATE TABLE user_token (
+	id INT NOT NULL AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	name VARCHAR(100) NOT NULL DEFAULT '',
+	hash CHAR(64) NOT NULL,
+	created_at DATETIME NOT NULL,
+	CONSTRAINT user_token_PK PRIMARY KEY (id),
+	CONSTRAINT user_token_hash UNIQUE (hash),
+	CONSTRAINT user_token_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COLLATE=utf8mb4_0900_ai_ci;
+
+ALTER TABLE task
+	ADD COLUMN owner_id INT NOT NULL DEFAULT 1 AFTER id,
+	ADD INDEX task_owner_id (owner_id);
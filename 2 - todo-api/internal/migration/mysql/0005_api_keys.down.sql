@@ -0,0 +1,4 @@
+ALTER TABLE user_token
+	DROP COLUMN last_used_at,
+	DROP COLUMN expires_at,
+	DROP COLUMN scopes;
@@ -0,0 +1,5 @@
+-- Tokens made before there were scopes keep doing everything.
+ALTER TABLE user_token
+	ADD COLUMN scopes VARCHAR(255) NOT NULL DEFAULT 'tasks:read tasks:write tasks:delete admin' AFTER hash,
+	ADD COLUMN expires_at DATETIME NULL AFTER scopes,
+	ADD COLUMN last_used_at DATETIME NULL AFTER expires_at;
@@ -0,0 +1,16 @@
+-- The tasks created for requests that carried an Idempotency-Key, so a retry
+-- gets the same answer until the key expires.
+CREATE TABLE idempotency_key (
+	owner_id INT NOT NULL,
+	idempotency_key VARCHAR(255) NOT NULL,
+	fingerprint CHAR(64) NOT NULL,
+	response TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	CONSTRAINT idempotency_key_PK PRIMARY KEY (owner_id, idempotency_key),
+	CONSTRAINT idempotency_key_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE,
+	INDEX idempotency_key_expires_at (owner_id, expires_at)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COLLATE=utf8mb4_0900_ai_ci;
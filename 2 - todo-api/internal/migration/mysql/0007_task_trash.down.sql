@@ -0,0 +1,4 @@
+DELETE FROM task WHERE deleted_at IS NOT NULL;
+ALTER TABLE task
+	DROP INDEX task_deleted_at,
+	DROP COLUMN deleted_at;
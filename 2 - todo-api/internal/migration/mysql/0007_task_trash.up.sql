@@ -0,0 +1,5 @@
+-- Deleted tasks stay in the trash, with the time they were deleted, until
+-- they are restored or purged.
+ALTER TABLE task
+	ADD COLUMN deleted_at DATETIME NULL AFTER completed_at,
+	ADD INDEX task_deleted_at (deleted_at);
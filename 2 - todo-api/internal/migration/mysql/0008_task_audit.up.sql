@@ -0,0 +1,19 @@
+-- Every change made to a task, oldest first. Records outlive the task, so
+-- its history is kept once it is purged.
+CREATE TABLE task_audit (
+	id INT NOT NULL AUTO_INCREMENT,
+	task_id INT NOT NULL,
+	owner_id INT NOT NULL,
+	actor_id INT NOT NULL,
+	operation VARCHAR(20) NOT NULL,
+	before_task TEXT NULL,
+	after_task TEXT NULL,
+	created_at DATETIME NOT NULL,
+	CONSTRAINT task_audit_PK PRIMARY KEY (id),
+	CONSTRAINT task_audit_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE,
+	INDEX task_audit_task_id (owner_id, task_id),
+	INDEX task_audit_created_at (owner_id, created_at)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COLLATE=utf8mb4_0900_ai_ci;
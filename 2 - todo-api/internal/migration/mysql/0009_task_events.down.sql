@@ -0,0 +1 @@
+-- Nothing was created by the up migration.
@@ -0,0 +1,2 @@
+-- The events backend keeps its log in sqlite only, so there is nothing to
+-- create here; the version keeps both dialects numbered alike.
@@ -0,0 +1,39 @@
+-- The URLs task changes are sent to. events holds the subscribed event
+-- types, comma-separated, or nothing for every type.
+CREATE TABLE webhook (
+	id INT NOT NULL AUTO_INCREMENT,
+	owner_id INT NOT NULL,
+	url VARCHAR(2048) NOT NULL,
+	events VARCHAR(255) NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL,
+	CONSTRAINT webhook_PK PRIMARY KEY (id),
+	CONSTRAINT webhook_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE,
+	INDEX webhook_owner_id (owner_id)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COLLATE=utf8mb4_0900_ai_ci;
+
+-- The outbox: a row per change and subscribed webhook, written in the
+-- transaction of the change and sent from there by the dispatcher.
+CREATE TABLE webhook_delivery (
+	id INT NOT NULL AUTO_INCREMENT,
+	webhook_id INT NOT NULL,
+	owner_id INT NOT NULL,
+	event_type VARCHAR(20) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	attempts INT NOT NULL,
+	next_attempt_at DATETIME NOT NULL,
+	last_error VARCHAR(1024) NOT NULL,
+	created_at DATETIME NOT NULL,
+	delivered_at DATETIME NULL,
+	CONSTRAINT webhook_delivery_PK PRIMARY KEY (id),
+	CONSTRAINT webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook (id) ON DELETE CASCADE,
+	INDEX webhook_delivery_due (status, next_attempt_at),
+	INDEX webhook_delivery_webhook_id (owner_id, webhook_id)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE task
+	DROP INDEX task_parent_id,
+	DROP COLUMN parent_id;
@@ -0,0 +1,5 @@
+-- Subtasks point at the task they are a step of; top-level tasks have no
+-- parent.
+ALTER TABLE task
+	ADD COLUMN parent_id INT NULL AFTER owner_id,
+	ADD INDEX task_parent_id (parent_id);
@@ -0,0 +1,4 @@
+ALTER TABLE task
+	DROP INDEX task_project_id,
+	DROP COLUMN project_id;
+DROP TABLE project;
@@ -0,0 +1,23 @@
+-- The lists tasks are grouped in. An archived project keeps its tasks but
+-- takes no new ones.
+CREATE TABLE project (
+	id INT NOT NULL AUTO_INCREMENT,
+	owner_id INT NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	description TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	archived_at DATETIME NULL,
+	CONSTRAINT project_PK PRIMARY KEY (id),
+	CONSTRAINT project_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE,
+	INDEX project_owner_id (owner_id)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COLLATE=utf8mb4_0900_ai_ci;
+
+-- Tasks in no project have none. Trashed tasks may still point at a
+-- project that was deleted since, so there is no foreign key.
+ALTER TABLE task
+	ADD COLUMN project_id INT NULL AFTER parent_id,
+	ADD INDEX task_project_id (project_id);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS task (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100) NOT NULL,
+	completed BOOLEAN NOT NULL DEFAULT 0
+);
@@ -0,0 +1,6 @@
+ALTER TABLE task DROP COLUMN completed_at;
+ALTER TABLE task DROP COLUMN updated_at;
+ALTER TABLE task DROP COLUMN created_at;
+ALTER TABLE task DROP COLUMN priority;
+ALTER TABLE task DROP COLUMN due_at;
+ALTER TABLE task DROP COLUMN description;
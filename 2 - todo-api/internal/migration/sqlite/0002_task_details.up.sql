@@ -0,0 +1,14 @@
+-- sqlite cannot add a NOT NULL column without a constant default, so the
+-- timestamps stay nullable and existing rows are stamped with the time of
+-- the upgrade.
+ALTER TABLE task ADD COLUMN description VARCHAR(1000) NOT NULL DEFAULT '';
+ALTER TABLE task ADD COLUMN due_at DATETIME NULL;
+ALTER TABLE task ADD COLUMN priority TINYINT NOT NULL DEFAULT 0;
+ALTER TABLE task ADD COLUMN created_at DATETIME NULL;
+ALTER TABLE task ADD COLUMN updated_at DATETIME NULL;
+ALTER TABLE task ADD COLUMN completed_at DATETIME NULL;
+
+UPDATE task SET
+	created_at = CURRENT_TIMESTAMP,
+	updated_at = CURRENT_TIMESTAMP,
+	completed_at = CASE WHEN completed THEN CURRENT_TIMESTAMP END;
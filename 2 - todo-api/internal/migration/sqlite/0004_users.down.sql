@@ -0,0 +1,5 @@
+DROP INDEX task_owner_id;
+ALTER TABLE task DROP COLUMN owner_id;
+
+DROP TABLE user_token;
+DROP TABLE users;
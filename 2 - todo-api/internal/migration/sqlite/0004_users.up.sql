@@ -0,0 +1,21 @@
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100) NOT NULL UNIQUE,
+	password_hash VARCHAR(100) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL
+);
+
+-- The default user stands for the shared BEARER_TOKEN and owns the tasks
+-- created before there were users. Without a password it cannot log in.
+INSERT INTO users (id, name, password_hash, created_at) VALUES (1, 'default', '', CURRENT_TIMESTAMP);
+
+CREATE TABLE user_token (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL DEFAULT '',
+	hash CHAR(64) NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL
+);
+
+ALTER TABLE task ADD COLUMN owner_id INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX task_owner_id ON task (owner_id);
@@ -0,0 +1,3 @@
+ALTER TABLE user_token DROP COLUMN last_used_at;
+ALTER TABLE user_token DROP COLUMN expires_at;
+ALTER TABLE user_token DROP COLUMN scopes;
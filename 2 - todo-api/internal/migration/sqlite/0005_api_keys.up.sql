@@ -0,0 +1,4 @@
+-- Tokens made before there were scopes keep doing everything.
+ALTER TABLE user_token ADD COLUMN scopes VARCHAR(255) NOT NULL DEFAULT 'tasks:read tasks:write tasks:delete admin';
+ALTER TABLE user_token ADD COLUMN expires_at DATETIME NULL;
+ALTER TABLE user_token ADD COLUMN last_used_at DATETIME NULL;
@@ -0,0 +1,2 @@
+DROP INDEX idempotency_key_expires_at;
+DROP TABLE idempotency_key;
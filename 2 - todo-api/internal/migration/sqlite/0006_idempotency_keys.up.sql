@@ -0,0 +1,12 @@
+-- The tasks created for requests that carried an Idempotency-Key, so a retry
+-- gets the same answer until the key expires.
+CREATE TABLE idempotency_key (
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	idempotency_key VARCHAR(255) NOT NULL,
+	fingerprint CHAR(64) NOT NULL,
+	response TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	PRIMARY KEY (owner_id, idempotency_key)
+);
+CREATE INDEX idempotency_key_expires_at ON idempotency_key (owner_id, expires_at);
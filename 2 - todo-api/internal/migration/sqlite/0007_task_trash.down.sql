@@ -0,0 +1,3 @@
+DELETE FROM task WHERE deleted_at IS NOT NULL;
+DROP INDEX task_deleted_at;
+ALTER TABLE task DROP COLUMN deleted_at;
@@ -0,0 +1,4 @@
+-- Deleted tasks stay in the trash, with the time they were deleted, until
+-- they are restored or purged.
+ALTER TABLE task ADD COLUMN deleted_at DATETIME NULL;
+CREATE INDEX task_deleted_at ON task (deleted_at);
@@ -0,0 +1,14 @@
+-- Every change made to a task, oldest first. Records outlive the task, so
+-- its history is kept once it is purged.
+CREATE TABLE task_audit (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	actor_id INTEGER NOT NULL,
+	operation VARCHAR(20) NOT NULL,
+	before_task TEXT NULL,
+	after_task TEXT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX task_audit_task_id ON task_audit (owner_id, task_id);
+CREATE INDEX task_audit_created_at ON task_audit (owner_id, created_at);
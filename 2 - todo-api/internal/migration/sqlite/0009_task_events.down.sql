@@ -0,0 +1,2 @@
+DROP TABLE task_snapshot;
+DROP TABLE task_event;
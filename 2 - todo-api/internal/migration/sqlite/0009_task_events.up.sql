@@ -0,0 +1,21 @@
+-- The log the events backend keeps tasks in: what happened to each task, in
+-- the order it happened. Rows are only ever appended.
+CREATE TABLE task_event (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	owner_id INTEGER NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	version INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	occurred_at DATETIME NOT NULL
+);
+CREATE INDEX task_event_occurred_at ON task_event (occurred_at);
+
+-- Every task as it was after the event seq, so a rebuild only replays the
+-- events since.
+CREATE TABLE task_snapshot (
+	seq INTEGER PRIMARY KEY,
+	state TEXT NOT NULL,
+	taken_at DATETIME NOT NULL
+);
+CREATE INDEX task_snapshot_taken_at ON task_snapshot (taken_at);
@@ -0,0 +1,29 @@
+-- The URLs task changes are sent to. events holds the subscribed event
+-- types, comma-separated, or nothing for every type.
+CREATE TABLE webhook (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	url VARCHAR(2048) NOT NULL,
+	events VARCHAR(255) NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX webhook_owner_id ON webhook (owner_id);
+
+-- The outbox: a row per change and subscribed webhook, written in the
+-- transaction of the change and sent from there by the dispatcher.
+CREATE TABLE webhook_delivery (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id INTEGER NOT NULL REFERENCES webhook (id) ON DELETE CASCADE,
+	owner_id INTEGER NOT NULL,
+	event_type VARCHAR(20) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt_at DATETIME NOT NULL,
+	last_error VARCHAR(1024) NOT NULL,
+	created_at DATETIME NOT NULL,
+	delivered_at DATETIME NULL
+);
+CREATE INDEX webhook_delivery_due ON webhook_delivery (status, next_attempt_at);
+CREATE INDEX webhook_delivery_webhook_id ON webhook_delivery (owner_id, webhook_id);
@@ -0,0 +1,2 @@
+DROP INDEX task_parent_id;
+ALTER TABLE task DROP COLUMN parent_id;
@@ -0,0 +1,4 @@
+-- Subtasks point at the task they are a step of; top-level tasks have no
+-- parent.
+ALTER TABLE task ADD COLUMN parent_id INTEGER NULL;
+CREATE INDEX task_parent_id ON task (parent_id);
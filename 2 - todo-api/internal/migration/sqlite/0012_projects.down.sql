@@ -0,0 +1,3 @@
+DROP INDEX task_project_id;
+ALTER TABLE task DROP COLUMN project_id;
+DROP TABLE project;
@@ -0,0 +1,17 @@
+-- The lists tasks are grouped in. An archived project keeps its tasks but
+-- takes no new ones.
+CREATE TABLE project (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	description TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	archived_at DATETIME NULL
+);
+CREATE INDEX project_owner_id ON project (owner_id);
+
+-- Tasks in no project have none. Trashed tasks may still point at a
+-- project that was deleted since, so there is no foreign key.
+ALTER TABLE task ADD COLUMN project_id INTEGER NULL;
+CREATE INDEX task_project_id ON task (project_id);
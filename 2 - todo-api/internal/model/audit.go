@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+type AuditOperation string
+
+const (
+	AuditCreate  AuditOperation = "create"
+	AuditUpdate  AuditOperation = "update"
+	AuditDelete  AuditOperation = "delete"
+	AuditRestore AuditOperation = "restore"
+)
+
+// AuditRecord is a change a user made to a task, which may belong to someone else.
+type AuditRecord struct {
+	ID        int            `json:"id"`
+	TaskID    int            `json:"task_id"`
+	OwnerID   int            `json:"owner_id"`
+	ActorID   int            `json:"actor_id"`
+	Operation AuditOperation `json:"operation"`
+	Before    *Task          `json:"before,omitempty"`
+	After     *Task          `json:"after,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// AuditFilter leaves out its zero fields; AllOwners reaches past the user's tasks, for admins.
+type AuditFilter struct {
+	TaskID    int
+	ActorID   int
+	From      *time.Time
+	To        *time.Time
+	AllOwners bool
+}
+
+type AuditPage struct {
+	Records       []AuditRecord `json:"records"`
+	NextPageToken string        `json:"next_page_token,omitempty"`
+}
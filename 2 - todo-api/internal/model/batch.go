@@ -0,0 +1,41 @@
+package model
+
+const MaxBatchSize = 500
+
+type BatchMode string
+
+const (
+	BatchAllOrNothing BatchMode = "all_or_nothing"
+	BatchPerItem      BatchMode = "per_item"
+)
+
+type Batch struct {
+	Mode   BatchMode `json:"mode,omitempty"`
+	Create []Task    `json:"create,omitempty"`
+	Update []Task    `json:"update,omitempty"`
+	Delete []TaskRef `json:"delete,omitempty"`
+}
+
+type TaskRef struct {
+	ID      int `json:"id"`
+	Version int `json:"version,omitempty"`
+}
+
+type BatchResult struct {
+	Task Task
+	Err  error
+}
+
+type BatchResults struct {
+	Create []BatchResult
+	Update []BatchResult
+	Delete []BatchResult
+}
+
+func (m BatchMode) Valid() bool {
+	return m == "" || m == BatchAllOrNothing || m == BatchPerItem
+}
+
+func (b Batch) Size() int {
+	return len(b.Create) + len(b.Update) + len(b.Delete)
+}
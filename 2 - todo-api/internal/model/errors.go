@@ -3,17 +3,69 @@ package model
 import "errors"
 
 var ErrConnectDatabase = errors.New("failed to connect to the database")
+var ErrInvalidDbImplementation = errors.New("invalid db implementation")
 var ErrPreparingStatemant = errors.New("failed preparing statemant")
 var ErrExecuteQuery = errors.New("failed to execute query")
 var ErrInsertingRow = errors.New("failed inserting row")
 var ErrScanningRows = errors.New("failed scanning row")
+var ErrMigrationFailed = errors.New("failed to migrate the database")
+var ErrMigrationLocked = errors.New("database is being migrated by another process")
+var ErrInvalidMigrationVersion = errors.New("invalid migration version")
 
 var ErrInvalidTaskStatus = errors.New("invalid task status")
 var ErrInvalidTaskId = errors.New("invalid task id")
 var ErrInvalidTaskName = errors.New("invalid task name")
+var ErrInvalidTaskPriority = errors.New("invalid task priority")
 var ErrTaskAlreadyExists = errors.New("task already exists")
 var ErrTaskNotFound = errors.New("task not found")
+var ErrTaskVersionMismatch = errors.New("task was modified since it was read")
+var ErrInvalidTaskParent = errors.New("invalid task parent")
+var ErrTaskParentCycle = errors.New("a task cannot be moved under itself or its subtasks")
+var ErrOpenSubtasks = errors.New("task has open subtasks")
+var ErrInvalidCompletionMode = errors.New("invalid completion mode")
+var ErrInvalidTaskProject = errors.New("invalid task project")
+var ErrInvalidPageSize = errors.New("invalid page size")
+var ErrInvalidPageToken = errors.New("invalid page token")
+var ErrInvalidFilter = errors.New("invalid filter")
+var ErrInvalidOrderBy = errors.New("invalid order by")
+var ErrInvalidBatchMode = errors.New("invalid batch mode")
+var ErrBatchTooLarge = errors.New("batch holds too many operations")
+var ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for another request")
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+var ErrInvalidAuditFilter = errors.New("invalid audit filter")
+var ErrInvalidProjectId = errors.New("invalid project id")
+var ErrInvalidProjectName = errors.New("invalid project name")
+var ErrProjectNotFound = errors.New("project not found")
+var ErrProjectArchived = errors.New("project is archived")
+var ErrInvalidProjectDeleteMode = errors.New("invalid project delete mode")
+var ErrInvalidWebhookId = errors.New("invalid webhook id")
+var ErrInvalidWebhookUrl = errors.New("invalid webhook url")
+var ErrInvalidWebhookEvent = errors.New("invalid webhook event")
+var ErrInvalidWebhookSecret = errors.New("invalid webhook secret")
+var ErrWebhookNotFound = errors.New("webhook not found")
+var ErrInvalidDeliveryId = errors.New("invalid delivery id")
+var ErrInvalidDeliveryStatus = errors.New("invalid delivery status")
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+var ErrInvalidUserName = errors.New("invalid user name")
+var ErrInvalidPassword = errors.New("password must have between 8 and 72 characters")
+var ErrUserAlreadyExists = errors.New("user already exists")
+var ErrUserNotFound = errors.New("user not found")
+var ErrInvalidTokenId = errors.New("invalid token id")
+var ErrTokenNotFound = errors.New("token not found")
+var ErrInvalidScope = errors.New("invalid scope")
+var ErrInvalidTokenExpiry = errors.New("token expiry must be in the future")
 
 var ErrUnauthorized = errors.New("invalid token")
+var ErrInvalidCredentials = errors.New("invalid user name or password")
+var ErrForbidden = errors.New("not allowed to do this")
+var ErrInvalidSigningKey = errors.New("invalid signing key")
+var ErrNoSigningKey = errors.New("no key can sign tokens")
 var ErrInvalidRequestBody = errors.New("invalid request body")
+var ErrWatchFellBehind = errors.New("watch fell behind, resume it to catch up")
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+var ErrRouteNotFound = errors.New("no such resource")
+var ErrMethodNotAllowed = errors.New("method not allowed")
 var ErrInternalServerError = errors.New("internal server error")
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+const MaxIdempotencyKeyLength = 255
+
+type IdempotencyKey struct {
+	OwnerID     int
+	Key         string
+	Fingerprint string
+	Task        Task
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
@@ -0,0 +1,19 @@
+package model
+
+const DefaultImportChunkSize = 500
+const MaxImportChunkSize = 1000
+
+// MaxImportErrors is how many failed rows a summary lists; the rest are only counted.
+const MaxImportErrors = 100
+
+type ImportSummary struct {
+	Received int
+	Imported int
+	Failed   int
+	Errors   []ImportError
+}
+
+type ImportError struct {
+	Row int
+	Err error
+}
@@ -1,11 +1,47 @@
 package model
 
+import "time"
+
 type Task struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	Completed bool   `json:"completed"`
+	ID      int `json:"id"`
+	OwnerID int `json:"owner_id"`
+	// ParentID is set on subtasks, to the task they are a step of.
+	ParentID    *int       `json:"parent_id,omitempty"`
+	ProjectID   *int       `json:"project_id,omitempty"`
+	Name        string     `json:"name"`
+	Completed   bool       `json:"completed"`
+	Description string     `json:"description"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	Priority    Priority   `json:"priority"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Version     int        `json:"version"`
+}
+
+type TaskTree struct {
+	Task
+	Subtasks []TaskTree `json:"subtasks"`
+}
+
+type CompletionMode string
+
+const (
+	CompleteStrict  CompletionMode = "strict"
+	CompleteForce   CompletionMode = "force"
+	CompleteCascade CompletionMode = "cascade"
+)
+
+func (m CompletionMode) Valid() bool {
+	return m == "" || m == CompleteStrict || m == CompleteForce || m == CompleteCascade
 }
 
 func (Task) TableName() string {
 	return "task"
 }
+
+// Now is the clock for task timestamps, which are kept in UTC to the second.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
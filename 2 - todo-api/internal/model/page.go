@@ -0,0 +1,14 @@
+package model
+
+const DefaultPageSize = 50
+const MaxPageSize = 100
+
+type PageRequest struct {
+	Size  int
+	Token string
+}
+
+type TaskPage struct {
+	Tasks         []Task `json:"tasks"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+var priorityNames = []string{"none", "low", "medium", "high"}
+
+func ParsePriority(name string) (Priority, error) {
+	if name == "" {
+		return PriorityNone, nil
+	}
+	for i, priorityName := range priorityNames {
+		if strings.EqualFold(name, priorityName) {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, ErrInvalidTaskPriority
+}
+
+func (p Priority) Valid() bool {
+	return p >= PriorityNone && p <= PriorityHigh
+}
+
+func (p Priority) String() string {
+	if !p.Valid() {
+		return "invalid"
+	}
+	return priorityNames[p]
+}
+
+func (p Priority) MarshalJSON() ([]byte, error) {
+	if !p.Valid() {
+		return nil, ErrInvalidTaskPriority
+	}
+	return json.Marshal(p.String())
+}
+
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return ErrInvalidTaskPriority
+	}
+
+	priority, err := ParsePriority(name)
+	if err != nil {
+		return err
+	}
+	*p = priority
+	return nil
+}
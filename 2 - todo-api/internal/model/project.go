@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+const MaxProjectNameLength = 255
+
+// Project groups tasks; an archived one keeps its tasks but takes no new ones.
+type Project struct {
+	ID          int        `json:"id"`
+	OwnerID     int        `json:"owner_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+}
+
+func (Project) TableName() string {
+	return "project"
+}
+
+type ProjectStats struct {
+	ProjectID int `json:"project_id"`
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Open      int `json:"open"`
+	Overdue   int `json:"overdue"`
+	// CompletionRate is Completed out of Total, and 0 without tasks.
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+type ProjectDeleteMode string
+
+const (
+	ProjectArchive  ProjectDeleteMode = "archive"
+	ProjectCascade  ProjectDeleteMode = "cascade"
+	ProjectReassign ProjectDeleteMode = "reassign"
+)
+
+func (m ProjectDeleteMode) Valid() bool {
+	return m == "" || m == ProjectArchive || m == ProjectCascade || m == ProjectReassign
+}
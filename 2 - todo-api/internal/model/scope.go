@@ -0,0 +1,103 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Scopes is written as space-separated names, the way OAuth 2.0 writes scopes.
+type Scopes uint8
+
+const (
+	ScopeTasksRead Scopes = 1 << iota
+	ScopeTasksWrite
+	ScopeTasksDelete
+	// ScopeAdmin manages the user's tokens and implies every other scope.
+	ScopeAdmin
+)
+
+const AllScopes = ScopeTasksRead | ScopeTasksWrite | ScopeTasksDelete | ScopeAdmin
+
+var scopeNames = []string{"tasks:read", "tasks:write", "tasks:delete", "admin"}
+
+func ParseScopes(names []string) (Scopes, error) {
+	var scopes Scopes
+	for _, name := range names {
+		scope, ok := parseScope(name)
+		if !ok {
+			return 0, fmt.Errorf("%w %q", ErrInvalidScope, name)
+		}
+		scopes |= scope
+	}
+	return scopes, nil
+}
+
+func parseScope(name string) (Scopes, bool) {
+	for i, scopeName := range scopeNames {
+		if name == scopeName {
+			return 1 << i, true
+		}
+	}
+	return 0, false
+}
+
+func (s Scopes) Has(scope Scopes) bool {
+	return s&ScopeAdmin != 0 || s&scope == scope
+}
+
+func (s Scopes) Names() []string {
+	names := []string{}
+	for i, name := range scopeNames {
+		if s&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (s Scopes) String() string {
+	return strings.Join(s.Names(), " ")
+}
+
+func (s Scopes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Names())
+}
+
+func (s *Scopes) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return ErrInvalidScope
+	}
+
+	scopes, err := ParseScopes(names)
+	if err != nil {
+		return err
+	}
+	*s = scopes
+	return nil
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return s.String(), nil
+}
+
+func (s *Scopes) Scan(value any) error {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidScope, value)
+	}
+
+	scopes, err := ParseScopes(strings.Fields(text))
+	if err != nil {
+		return err
+	}
+	*s = scopes
+	return nil
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultUserID is the user behind BEARER_TOKEN, who owns the tasks from before users.
+const DefaultUserID = 1
+
+type User struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	Scopes Scopes `json:"-" gorm:"-"`
+	// TokenID is the personal token the user signed in with, if any.
+	TokenID int `json:"-" gorm:"-"`
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+// Token is a personal access token; only its hash is stored, so Secret is set once.
+type Token struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"-"`
+	Secret     string     `json:"token,omitempty" gorm:"-"`
+	Scopes     Scopes     `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t Token) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+func (Token) TableName() string {
+	return "user_token"
+}
+
+type userKey struct{}
+
+// ContextWithUser makes repositories only see the tasks of user.
+func ContextWithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+	return user, ok
+}
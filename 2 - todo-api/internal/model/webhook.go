@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const MaxWebhookSecretLength = 255
+
+const MaxDeliveryAttempts = 10
+
+// Webhook is a URL its owner's task changes are sent to, signed with Secret.
+type Webhook struct {
+	ID      int    `json:"id"`
+	OwnerID int    `json:"owner_id"`
+	URL     string `json:"url"`
+	// Events are the event types sent, or every type when empty.
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (w Webhook) Subscribes(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range w.Events {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending"
+	DeliveryDone    DeliveryStatus = "delivered"
+	// DeliveryDead is only tried again when it is redelivered.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+func (s DeliveryStatus) Valid() bool {
+	return s == DeliveryPending || s == DeliveryDone || s == DeliveryDead
+}
+
+// WebhookDelivery is an outbox row, written in the transaction of the change.
+type WebhookDelivery struct {
+	ID            int             `json:"id"`
+	WebhookID     int             `json:"webhook_id"`
+	OwnerID       int             `json:"owner_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        DeliveryStatus  `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+}
+
+// DeliveryFilter with DueBy lists due deliveries soonest first, the others newest first.
+type DeliveryFilter struct {
+	ID        int
+	WebhookID int
+	Status    DeliveryStatus
+	DueBy     *time.Time
+}
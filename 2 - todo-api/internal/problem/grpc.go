@@ -0,0 +1,50 @@
+package problem
+
+import (
+	"context"
+	"errors"
+	"gochallenges/internal/model"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor turns handler errors into catalogue statuses.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return resp, Status(err).Err()
+	}
+	return resp, nil
+}
+
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := handler(srv, ss); err != nil {
+		return Status(err).Err()
+	}
+	return nil
+}
+
+// GatewayErrorHandler writes the problem document a REST client would get.
+func GatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	var httpStatusErr *runtime.HTTPStatusError
+	if errors.As(err, &httpStatusErr) {
+		GatewayRoutingErrorHandler(ctx, mux, marshaler, w, r, httpStatusErr.HTTPStatus)
+		return
+	}
+	Write(w, r, err)
+}
+
+func GatewayRoutingErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, httpStatus int) {
+	switch httpStatus {
+	case http.StatusNotFound:
+		Write(w, r, model.ErrRouteNotFound)
+	case http.StatusMethodNotAllowed:
+		Write(w, r, model.ErrMethodNotAllowed)
+	case http.StatusBadRequest:
+		Write(w, r, model.ErrInvalidRequestBody)
+	default:
+		Write(w, r, model.ErrInternalServerError)
+	}
+}
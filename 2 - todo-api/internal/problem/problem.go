@@ -0,0 +1,220 @@
+// Package problem maps model errors to RFC 7807 problem types, HTTP statuses and gRPC codes.
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gochallenges/internal/model"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const ContentType = "application/problem+json"
+
+const Domain = "gochallenges"
+
+const typePrefix = "urn:gochallenges:problem:"
+
+// statusClientClosedRequest is the de facto status for a cancelled request.
+const statusClientClosedRequest = 499
+
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	code   codes.Code
+	reason string
+}
+
+type entry struct {
+	err    error
+	reason string
+	title  string
+	status int
+	code   codes.Code
+}
+
+var catalogue = []entry{
+	{model.ErrTaskNotFound, "TASK_NOT_FOUND", "Task not found", http.StatusNotFound, codes.NotFound},
+	{model.ErrTaskAlreadyExists, "TASK_ALREADY_EXISTS", "Task already exists", http.StatusConflict, codes.AlreadyExists},
+	{model.ErrTaskVersionMismatch, "PRECONDITION_FAILED", "Precondition failed", http.StatusPreconditionFailed, codes.FailedPrecondition},
+	{model.ErrInvalidTaskId, "INVALID_TASK_ID", "Invalid task id", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidTaskName, "INVALID_TASK_NAME", "Invalid task name", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidTaskPriority, "INVALID_TASK_PRIORITY", "Invalid task priority", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidTaskStatus, "INVALID_TASK_STATUS", "Invalid task status", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidTaskParent, "INVALID_TASK_PARENT", "Invalid task parent", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrTaskParentCycle, "TASK_PARENT_CYCLE", "Task parent cycle", http.StatusConflict, codes.FailedPrecondition},
+	{model.ErrOpenSubtasks, "OPEN_SUBTASKS", "Task has open subtasks", http.StatusConflict, codes.FailedPrecondition},
+	{model.ErrInvalidCompletionMode, "INVALID_COMPLETION_MODE", "Invalid completion mode", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidTaskProject, "INVALID_TASK_PROJECT", "Invalid task project", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidPageSize, "INVALID_PAGE_SIZE", "Invalid page size", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidPageToken, "INVALID_PAGE_TOKEN", "Invalid page token", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidFilter, "INVALID_FILTER", "Invalid filter", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidOrderBy, "INVALID_ORDER_BY", "Invalid order by", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidBatchMode, "INVALID_BATCH_MODE", "Invalid batch mode", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrBatchTooLarge, "BATCH_TOO_LARGE", "Batch too large", http.StatusRequestEntityTooLarge, codes.InvalidArgument},
+	{model.ErrInvalidIdempotencyKey, "INVALID_IDEMPOTENCY_KEY", "Invalid idempotency key", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrIdempotencyKeyReused, "IDEMPOTENCY_KEY_REUSED", "Idempotency key reused", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{model.ErrInvalidAuditFilter, "INVALID_AUDIT_FILTER", "Invalid audit filter", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrProjectNotFound, "PROJECT_NOT_FOUND", "Project not found", http.StatusNotFound, codes.NotFound},
+	{model.ErrProjectArchived, "PROJECT_ARCHIVED", "Project is archived", http.StatusConflict, codes.FailedPrecondition},
+	{model.ErrInvalidProjectId, "INVALID_PROJECT_ID", "Invalid project id", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidProjectName, "INVALID_PROJECT_NAME", "Invalid project name", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidProjectDeleteMode, "INVALID_PROJECT_DELETE_MODE", "Invalid project delete mode", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrWebhookNotFound, "WEBHOOK_NOT_FOUND", "Webhook not found", http.StatusNotFound, codes.NotFound},
+	{model.ErrDeliveryNotFound, "DELIVERY_NOT_FOUND", "Delivery not found", http.StatusNotFound, codes.NotFound},
+	{model.ErrInvalidWebhookId, "INVALID_WEBHOOK_ID", "Invalid webhook id", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidWebhookUrl, "INVALID_WEBHOOK_URL", "Invalid webhook url", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidWebhookEvent, "INVALID_WEBHOOK_EVENT", "Invalid webhook event", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidWebhookSecret, "INVALID_WEBHOOK_SECRET", "Invalid webhook secret", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidDeliveryId, "INVALID_DELIVERY_ID", "Invalid delivery id", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidDeliveryStatus, "INVALID_DELIVERY_STATUS", "Invalid delivery status", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidRequestBody, "INVALID_REQUEST_BODY", "Invalid request body", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Unsupported media type", http.StatusUnsupportedMediaType, codes.InvalidArgument},
+	{model.ErrUserNotFound, "USER_NOT_FOUND", "User not found", http.StatusNotFound, codes.NotFound},
+	{model.ErrUserAlreadyExists, "USER_ALREADY_EXISTS", "User already exists", http.StatusConflict, codes.AlreadyExists},
+	{model.ErrTokenNotFound, "TOKEN_NOT_FOUND", "Token not found", http.StatusNotFound, codes.NotFound},
+	{model.ErrInvalidUserName, "INVALID_USER_NAME", "Invalid user name", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidPassword, "INVALID_PASSWORD", "Invalid password", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidTokenId, "INVALID_TOKEN_ID", "Invalid token id", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidScope, "INVALID_SCOPE", "Invalid scope", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrInvalidTokenExpiry, "INVALID_TOKEN_EXPIRY", "Invalid token expiry", http.StatusBadRequest, codes.InvalidArgument},
+	{model.ErrUnauthorized, "UNAUTHORIZED", "Unauthorized", http.StatusUnauthorized, codes.Unauthenticated},
+	{model.ErrInvalidCredentials, "INVALID_CREDENTIALS", "Invalid credentials", http.StatusUnauthorized, codes.Unauthenticated},
+	{model.ErrForbidden, "FORBIDDEN", "Forbidden", http.StatusForbidden, codes.PermissionDenied},
+	{model.ErrRouteNotFound, "NOT_FOUND", "Not found", http.StatusNotFound, codes.NotFound},
+	{model.ErrMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed", http.StatusMethodNotAllowed, codes.Unimplemented},
+	{model.ErrWatchFellBehind, "WATCH_FELL_BEHIND", "Watch fell behind", http.StatusServiceUnavailable, codes.Unavailable},
+	{model.ErrMigrationLocked, "MIGRATION_LOCKED", "Database is being migrated", http.StatusServiceUnavailable, codes.Unavailable},
+	{model.ErrConnectDatabase, "DATABASE_UNAVAILABLE", "Database unavailable", http.StatusServiceUnavailable, codes.Unavailable},
+	{model.ErrPreparingStatemant, "DATABASE_ERROR", "Database error", http.StatusInternalServerError, codes.Internal},
+	{model.ErrExecuteQuery, "DATABASE_ERROR", "Database error", http.StatusInternalServerError, codes.Internal},
+	{model.ErrInsertingRow, "DATABASE_ERROR", "Database error", http.StatusInternalServerError, codes.Internal},
+	{model.ErrScanningRows, "DATABASE_ERROR", "Database error", http.StatusInternalServerError, codes.Internal},
+	{context.DeadlineExceeded, "DEADLINE_EXCEEDED", "Deadline exceeded", http.StatusGatewayTimeout, codes.DeadlineExceeded},
+	{context.Canceled, "CANCELLED", "Request cancelled", statusClientClosedRequest, codes.Canceled},
+}
+
+// internal stands for errors outside the catalogue, whose text is not shown.
+var internal = entry{model.ErrInternalServerError, "INTERNAL", "Internal server error", http.StatusInternalServerError, codes.Internal}
+
+// From looks err up in the catalogue; server errors only reach the client by title.
+func From(err error) Problem {
+	for _, e := range catalogue {
+		if !errors.Is(err, e.err) {
+			continue
+		}
+		if e.status >= http.StatusInternalServerError {
+			log.Printf("%s: %v", e.reason, err)
+			return e.problem("")
+		}
+		return e.problem(err.Error())
+	}
+
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		return FromStatus(s)
+	}
+
+	log.Printf("%s: %v", internal.reason, err)
+	return internal.problem("")
+}
+
+// FromStatus rebuilds the problem a status was made from, or a generic one.
+func FromStatus(s *status.Status) Problem {
+	for _, detail := range s.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != Domain {
+			continue
+		}
+		for _, e := range append(catalogue, internal) {
+			if e.reason != info.GetReason() {
+				continue
+			}
+			// GRPCStatus falls back to the title when there is no detail.
+			if s.Message() == e.title {
+				return e.problem("")
+			}
+			return e.problem(s.Message())
+		}
+	}
+
+	if s.Code() == codes.OK || s.Code() == codes.Unknown {
+		return internal.problem("")
+	}
+
+	httpStatus := runtime.HTTPStatusFromCode(s.Code())
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(httpStatus),
+		Status: httpStatus,
+		Detail: s.Message(),
+		code:   s.Code(),
+	}
+}
+
+// Status carries an ErrorInfo detail the problem type can be read back from.
+func Status(err error) *status.Status {
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+	return From(err).GRPCStatus()
+}
+
+func (p Problem) GRPCStatus() *status.Status {
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+
+	s := status.New(p.code, message)
+	if p.reason == "" {
+		return s
+	}
+
+	withDetails, err := s.WithDetails(&errdetails.ErrorInfo{
+		Reason: p.reason,
+		Domain: Domain,
+		Metadata: map[string]string{
+			"type":   p.Type,
+			"title":  p.Title,
+			"status": strconv.Itoa(p.Status),
+		},
+	})
+	if err != nil {
+		return s
+	}
+	return withDetails
+}
+
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, From(err))
+}
+
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	p.Instance = r.URL.Path
+	w.Header().Set("content-type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (e entry) problem(detail string) Problem {
+	return Problem{
+		Type:   typePrefix + strings.ToLower(strings.ReplaceAll(e.reason, "_", "-")),
+		Title:  e.title,
+		Status: e.status,
+		Detail: detail,
+		code:   e.code,
+		reason: e.reason,
+	}
+}
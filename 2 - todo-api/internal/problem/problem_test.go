@@ -0,0 +1,163 @@
+package problem_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gochallenges/internal/model"
+	"gochallenges/internal/problem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFrom(t *testing.T) {
+	cases := []struct {
+		caseName       string
+		err            error
+		expectedStatus int
+		expectedCode   codes.Code
+		expectedType   string
+		expectedDetail string
+	}{
+		{
+			caseName:       "not found",
+			err:            model.ErrTaskNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   codes.NotFound,
+			expectedType:   "urn:gochallenges:problem:task-not-found",
+			expectedDetail: "task not found",
+		},
+		{
+			caseName:       "conflict",
+			err:            model.ErrTaskAlreadyExists,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   codes.AlreadyExists,
+			expectedType:   "urn:gochallenges:problem:task-already-exists",
+			expectedDetail: "task already exists",
+		},
+		{
+			caseName:       "stale version",
+			err:            model.ErrTaskVersionMismatch,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   codes.FailedPrecondition,
+			expectedType:   "urn:gochallenges:problem:precondition-failed",
+			expectedDetail: "task was modified since it was read",
+		},
+		{
+			caseName:       "batch too large",
+			err:            model.ErrBatchTooLarge,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   codes.InvalidArgument,
+			expectedType:   "urn:gochallenges:problem:batch-too-large",
+			expectedDetail: "batch holds too many operations",
+		},
+		{
+			caseName:       "reused idempotency key",
+			err:            model.ErrIdempotencyKeyReused,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codes.FailedPrecondition,
+			expectedType:   "urn:gochallenges:problem:idempotency-key-reused",
+			expectedDetail: "idempotency key was already used for another request",
+		},
+		{
+			caseName:       "forbidden",
+			err:            model.ErrForbidden,
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   codes.PermissionDenied,
+			expectedType:   "urn:gochallenges:problem:forbidden",
+			expectedDetail: "not allowed to do this",
+		},
+		{
+			caseName:       "wrapped validation error keeps its detail",
+			err:            fmt.Errorf("%w: unknown field \"color\" at position 1", model.ErrInvalidFilter),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codes.InvalidArgument,
+			expectedType:   "urn:gochallenges:problem:invalid-filter",
+			expectedDetail: "invalid filter: unknown field \"color\" at position 1",
+		},
+		{
+			caseName:       "unauthorized",
+			err:            model.ErrUnauthorized,
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   codes.Unauthenticated,
+			expectedType:   "urn:gochallenges:problem:unauthorized",
+			expectedDetail: "invalid token",
+		},
+		{
+			caseName:       "database error hides the driver message",
+			err:            fmt.Errorf("%w: %v", model.ErrExecuteQuery, errors.New("no such table: tasks")),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   codes.Internal,
+			expectedType:   "urn:gochallenges:problem:database-error",
+		},
+		{
+			caseName:       "database unavailable",
+			err:            model.ErrConnectDatabase,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   codes.Unavailable,
+			expectedType:   "urn:gochallenges:problem:database-unavailable",
+		},
+		{
+			caseName:       "error outside the catalogue hides its text",
+			err:            errors.New("dial tcp 10.0.0.1:3306: connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   codes.Internal,
+			expectedType:   "urn:gochallenges:problem:internal",
+		},
+		{
+			caseName:       "status from another service",
+			err:            status.Error(codes.PermissionDenied, "not yours"),
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   codes.PermissionDenied,
+			expectedType:   "about:blank",
+			expectedDetail: "not yours",
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			got := problem.From(testCase.err)
+
+			if got.Status != testCase.expectedStatus || got.Type != testCase.expectedType || got.Detail != testCase.expectedDetail {
+				t.Errorf("got %+v want status %d, type %s and detail %q", got, testCase.expectedStatus, testCase.expectedType, testCase.expectedDetail)
+			}
+			if code := problem.Status(testCase.err).Code(); code != testCase.expectedCode {
+				t.Errorf("got code %s want %s", code, testCase.expectedCode)
+			}
+		})
+	}
+}
+
+func TestStatusRoundTrip(t *testing.T) {
+	for _, err := range []error{model.ErrTaskNotFound, model.ErrInvalidPageToken, model.ErrConnectDatabase, errors.New("boom")} {
+		want := problem.From(err)
+		got := problem.From(problem.Status(err).Err())
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v want %+v", got, want)
+		}
+	}
+}
+
+func TestGatewayWritesTheRestProblem(t *testing.T) {
+	err := fmt.Errorf("%w: page token is not for this ordering", model.ErrInvalidPageToken)
+	r := httptest.NewRequest(http.MethodGet, "/tasks?page_token=abc", nil)
+
+	rest := httptest.NewRecorder()
+	problem.Write(rest, r, err)
+
+	gateway := httptest.NewRecorder()
+	problem.GatewayErrorHandler(context.Background(), runtime.NewServeMux(), &runtime.JSONPb{}, gateway, r, problem.Status(err).Err())
+
+	if gateway.Code != rest.Code || gateway.Body.String() != rest.Body.String() {
+		t.Errorf("got %d %s want %d %s", gateway.Code, gateway.Body, rest.Code, rest.Body)
+	}
+	if contentType := gateway.Header().Get("Content-Type"); contentType != problem.ContentType {
+		t.Errorf("got Content-Type %q want %q", contentType, problem.ContentType)
+	}
+}
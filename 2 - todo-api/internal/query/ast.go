@@ -0,0 +1,73 @@
+// Package query parses task filters and orderings into a tree each repository translates.
+//
+//	filter   = or
+//	or       = and { "OR" and }
+//	and      = unary { "AND" unary }
+//	unary    = "NOT" unary | "(" or ")" | field operator value
+//	operator = "=" | "!=" | "<" | "<=" | ">" | ">=" | "~"
+//	value    = string | number | "true" | "false" | priority | "null"
+//	order by = field [ "asc" | "desc" ] { "," field [ "asc" | "desc" ] }
+//
+// "~" is a case-insensitive contains, times are quoted RFC 3339, and null
+// only compares with = and != on due_at and completed_at.
+package query
+
+type Operator string
+
+const (
+	Eq       Operator = "="
+	Ne       Operator = "!="
+	Lt       Operator = "<"
+	Le       Operator = "<="
+	Gt       Operator = ">"
+	Ge       Operator = ">="
+	Contains Operator = "~"
+)
+
+type Expr interface {
+	expr()
+}
+
+type Comparison struct {
+	Field string
+	Op    Operator
+	Value any
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+func (Comparison) expr() {}
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+type Query struct {
+	Filter  Expr
+	OrderBy []Order
+}
+
+func AndAlso(left, right Expr) Expr {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	default:
+		return And{Left: left, Right: right}
+	}
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"gochallenges/internal/model"
+	"math"
+	"time"
+)
+
+type FieldType int
+
+const (
+	TypeInt FieldType = iota
+	TypeString
+	TypeBool
+	TypeTime
+	TypePriority
+)
+
+type Field struct {
+	Name     string
+	Type     FieldType
+	Nullable bool
+	Value    func(task model.Task) any
+}
+
+// Fields lists what can be filtered and sorted on; names double as column names.
+var Fields = map[string]Field{
+	"id":           {Name: "id", Type: TypeInt, Value: func(task model.Task) any { return task.ID }},
+	"parent_id":    {Name: "parent_id", Type: TypeInt, Nullable: true, Value: func(task model.Task) any { return intValue(task.ParentID) }},
+	"project_id":   {Name: "project_id", Type: TypeInt, Nullable: true, Value: func(task model.Task) any { return intValue(task.ProjectID) }},
+	"name":         {Name: "name", Type: TypeString, Value: func(task model.Task) any { return task.Name }},
+	"completed":    {Name: "completed", Type: TypeBool, Value: func(task model.Task) any { return task.Completed }},
+	"description":  {Name: "description", Type: TypeString, Value: func(task model.Task) any { return task.Description }},
+	"priority":     {Name: "priority", Type: TypePriority, Value: func(task model.Task) any { return task.Priority }},
+	"due_at":       {Name: "due_at", Type: TypeTime, Nullable: true, Value: func(task model.Task) any { return timeValue(task.DueAt) }},
+	"created_at":   {Name: "created_at", Type: TypeTime, Value: func(task model.Task) any { return task.CreatedAt }},
+	"updated_at":   {Name: "updated_at", Type: TypeTime, Value: func(task model.Task) any { return task.UpdatedAt }},
+	"completed_at": {Name: "completed_at", Type: TypeTime, Nullable: true, Value: func(task model.Task) any { return timeValue(task.CompletedAt) }},
+}
+
+func (f Field) Coerce(value any) (any, bool) {
+	if value == nil {
+		return nil, f.Nullable
+	}
+
+	switch f.Type {
+	case TypeInt:
+		v, ok := value.(float64)
+		if !ok || v != math.Trunc(v) {
+			return nil, false
+		}
+		return int(v), true
+	case TypeString:
+		v, ok := value.(string)
+		return v, ok
+	case TypeBool:
+		v, ok := value.(bool)
+		return v, ok
+	case TypeTime:
+		v, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		return parseTime(v)
+	case TypePriority:
+		v, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		priority, err := model.ParsePriority(v)
+		return priority, err == nil
+	}
+	return nil, false
+}
+
+func (f Field) allows(op Operator) bool {
+	switch f.Type {
+	case TypeString:
+		return true
+	case TypeBool:
+		return op == Eq || op == Ne
+	default:
+		return op != Contains
+	}
+}
+
+func parseTime(value string) (any, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return nil, false
+}
+
+func intValue(i *int) any {
+	if i == nil {
+		return nil
+	}
+	return *i
+}
+
+func timeValue(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
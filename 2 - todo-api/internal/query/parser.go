@@ -0,0 +1,341 @@
+package query
+
+import (
+	"fmt"
+	"gochallenges/internal/model"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func Parse(filter, orderBy string) (Query, error) {
+	var q Query
+	var err error
+
+	if q.Filter, err = ParseFilter(filter); err != nil {
+		return q, err
+	}
+	if q.OrderBy, err = ParseOrderBy(orderBy); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+// ParseFilter returns a nil Expr for an empty filter.
+func ParseFilter(filter string) (Expr, error) {
+	tokens, err := lex(filter, model.ErrInvalidFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens, err: model.ErrInvalidFilter}
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+
+	return expr, nil
+}
+
+func ParseOrderBy(orderBy string) ([]Order, error) {
+	tokens, err := lex(orderBy, model.ErrInvalidOrderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []Order
+	seen := map[string]bool{}
+	p := parser{tokens: tokens, err: model.ErrInvalidOrderBy}
+	for p.peek().kind != tokenEOF {
+		if len(orders) > 0 {
+			if tok := p.next(); tok.kind != tokenComma {
+				return nil, p.errorf(tok, "expected \",\" but got %q", tok.text)
+			}
+		}
+
+		tok := p.next()
+		field, ok := Fields[strings.ToLower(tok.text)]
+		if tok.kind != tokenIdent || !ok {
+			return nil, p.errorf(tok, "unknown field %q", tok.text)
+		}
+		if seen[field.Name] {
+			return nil, p.errorf(tok, "field %q is sorted twice", tok.text)
+		}
+		seen[field.Name] = true
+
+		order := Order{Field: field.Name}
+		if next := p.peek(); next.kind == tokenIdent {
+			switch strings.ToLower(next.text) {
+			case "asc":
+			case "desc":
+				order.Desc = true
+			default:
+				return nil, p.errorf(next, "expected asc or desc but got %q", next.text)
+			}
+			p.next()
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+func FormatOrderBy(orders []Order) string {
+	parts := make([]string, 0, len(orders))
+	for _, order := range orders {
+		direction := "asc"
+		if order.Desc {
+			direction = "desc"
+		}
+		parts = append(parts, order.Field+" "+direction)
+	}
+	return strings.Join(parts, ", ")
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	err    error
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokenIdent && strings.EqualFold(tok.text, word) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", p.err, fmt.Sprintf(format, args...), tok.pos+1)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("NOT") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenRParen {
+			return nil, p.errorf(tok, "expected \")\" but got %q", tok.text)
+		}
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	tok := p.next()
+	field, ok := Fields[strings.ToLower(tok.text)]
+	if tok.kind != tokenIdent || !ok {
+		return nil, p.errorf(tok, "unknown field %q", tok.text)
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokenOperator {
+		return nil, p.errorf(opTok, "expected an operator but got %q", opTok.text)
+	}
+	op := Operator(opTok.text)
+	if !field.allows(op) {
+		return nil, p.errorf(opTok, "operator %q is not supported on %s", opTok.text, field.Name)
+	}
+
+	valueTok := p.next()
+	value, ok := parseValue(field, valueTok)
+	if !ok {
+		return nil, p.errorf(valueTok, "invalid value %q for %s", valueTok.text, field.Name)
+	}
+	if value == nil && op != Eq && op != Ne {
+		return nil, p.errorf(opTok, "only = and != can compare with null")
+	}
+
+	return Comparison{Field: field.Name, Op: op, Value: value}, nil
+}
+
+func parseValue(field Field, tok token) (any, bool) {
+	if tok.kind == tokenIdent && strings.EqualFold(tok.text, "null") {
+		return nil, field.Nullable
+	}
+
+	switch field.Type {
+	case TypeInt:
+		if tok.kind != tokenNumber {
+			return nil, false
+		}
+		value, err := strconv.Atoi(tok.text)
+		return value, err == nil
+	case TypeString:
+		return tok.text, tok.kind == tokenString
+	case TypeBool:
+		if tok.kind != tokenIdent {
+			return nil, false
+		}
+		value, err := strconv.ParseBool(tok.text)
+		return value, err == nil
+	case TypeTime:
+		if tok.kind != tokenString {
+			return nil, false
+		}
+		return parseTime(tok.text)
+	case TypePriority:
+		if tok.kind != tokenIdent && tok.kind != tokenString {
+			return nil, false
+		}
+		priority, err := model.ParsePriority(tok.text)
+		return priority, err == nil && tok.text != ""
+	}
+	return nil, false
+}
+
+func lex(input string, kind error) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", start})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", start})
+			i++
+
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", start})
+			i++
+
+		case r == '"':
+			var text strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", kind, start+1)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, text.String(), start})
+
+		case strings.ContainsRune("=!<>~", r):
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != '=' && r != '~' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("%w: unexpected \"!\" at position %d", kind, start+1)
+			}
+			tokens = append(tokens, token{tokenOperator, op, start})
+			i += len(op)
+
+		case unicode.IsDigit(r) || r == '-':
+			for i++; i < len(runes) && unicode.IsDigit(runes[i]); i++ {
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), start})
+
+		case unicode.IsLetter(r) || r == '_':
+			for i++; i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_'); i++ {
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), start})
+
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at position %d", kind, r, start+1)
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "end of input", len(runes)}), nil
+}
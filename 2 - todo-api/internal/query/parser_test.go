@@ -0,0 +1,163 @@
+package query_test
+
+import (
+	"errors"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	cases := []struct {
+		caseName      string
+		filter        string
+		expectedError error
+		expectedExpr  query.Expr
+	}{
+		{
+			caseName: "empty filter",
+			filter:   "  ",
+		},
+		{
+			caseName:     "single comparison",
+			filter:       "completed=false",
+			expectedExpr: query.Comparison{Field: "completed", Op: query.Eq, Value: false},
+		},
+		{
+			caseName: "and binds tighter than or",
+			filter:   `completed = false AND name ~ "deploy" or id >= 10`,
+			expectedExpr: query.Or{
+				Left: query.And{
+					Left:  query.Comparison{Field: "completed", Op: query.Eq, Value: false},
+					Right: query.Comparison{Field: "name", Op: query.Contains, Value: "deploy"},
+				},
+				Right: query.Comparison{Field: "id", Op: query.Ge, Value: 10},
+			},
+		},
+		{
+			caseName: "parentheses and not",
+			filter:   `NOT (id < 3 OR name != "a \"quoted\" name")`,
+			expectedExpr: query.Not{Expr: query.Or{
+				Left:  query.Comparison{Field: "id", Op: query.Lt, Value: 3},
+				Right: query.Comparison{Field: "name", Op: query.Ne, Value: `a "quoted" name`},
+			}},
+		},
+		{
+			caseName: "null, time and priority values",
+			filter:   `due_at != null AND due_at < "2030-01-02" AND priority >= medium`,
+			expectedExpr: query.And{
+				Left: query.And{
+					Left:  query.Comparison{Field: "due_at", Op: query.Ne, Value: nil},
+					Right: query.Comparison{Field: "due_at", Op: query.Lt, Value: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)},
+				},
+				Right: query.Comparison{Field: "priority", Op: query.Ge, Value: model.PriorityMedium},
+			},
+		},
+		{
+			caseName:      "null on a field that is never null",
+			filter:        "created_at = null",
+			expectedError: model.ErrInvalidFilter,
+		},
+		{
+			caseName:      "null with an ordering operator",
+			filter:        "due_at > null",
+			expectedError: model.ErrInvalidFilter,
+		},
+		{
+			caseName:      "unknown priority",
+			filter:        "priority = urgent",
+			expectedError: model.ErrInvalidFilter,
+		},
+		{
+			caseName:      "unknown field",
+			filter:        "color = 1",
+			expectedError: model.ErrInvalidFilter,
+		},
+		{
+			caseName:      "value of the wrong type",
+			filter:        `id = "1"`,
+			expectedError: model.ErrInvalidFilter,
+		},
+		{
+			caseName:      "operator not supported by the field",
+			filter:        "completed > true",
+			expectedError: model.ErrInvalidFilter,
+		},
+		{
+			caseName:      "unterminated string",
+			filter:        `name = "deploy`,
+			expectedError: model.ErrInvalidFilter,
+		},
+		{
+			caseName:      "unbalanced parentheses",
+			filter:        "(id = 1",
+			expectedError: model.ErrInvalidFilter,
+		},
+		{
+			caseName:      "trailing tokens",
+			filter:        "id = 1 id = 2",
+			expectedError: model.ErrInvalidFilter,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			expr, err := query.ParseFilter(testCase.filter)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("got error %v want %v", err, testCase.expectedError)
+			}
+			if !reflect.DeepEqual(expr, testCase.expectedExpr) {
+				t.Errorf("got %#v want %#v", expr, testCase.expectedExpr)
+			}
+		})
+	}
+}
+
+func TestParseOrderBy(t *testing.T) {
+	cases := []struct {
+		caseName       string
+		orderBy        string
+		expectedError  error
+		expectedOrders []query.Order
+	}{
+		{
+			caseName: "empty order by",
+		},
+		{
+			caseName:       "default direction is ascending",
+			orderBy:        "name desc, ID",
+			expectedOrders: []query.Order{{Field: "name", Desc: true}, {Field: "id"}},
+		},
+		{
+			caseName:      "unknown field",
+			orderBy:       "color",
+			expectedError: model.ErrInvalidOrderBy,
+		},
+		{
+			caseName:      "unknown direction",
+			orderBy:       "name up",
+			expectedError: model.ErrInvalidOrderBy,
+		},
+		{
+			caseName:      "field sorted twice",
+			orderBy:       "name, name desc",
+			expectedError: model.ErrInvalidOrderBy,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			orders, err := query.ParseOrderBy(testCase.orderBy)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("got error %v want %v", err, testCase.expectedError)
+			}
+			if !reflect.DeepEqual(orders, testCase.expectedOrders) {
+				t.Errorf("got %#v want %#v", orders, testCase.expectedOrders)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type auditRecordRow struct {
+	ID         int
+	TaskID     int
+	OwnerID    int
+	ActorID    int
+	Operation  model.AuditOperation
+	BeforeTask sql.NullString
+	AfterTask  sql.NullString
+	CreatedAt  time.Time
+}
+
+func (auditRecordRow) TableName() string {
+	return "task_audit"
+}
+
+type AuditOrm struct {
+	db *gorm.DB
+}
+
+func (r *AuditOrm) CreateAuditRecord(ctx context.Context, record model.AuditRecord) error {
+	record = normalizeAuditRecord(record)
+	before, err := auditTask(record.Before)
+	if err != nil {
+		return model.ErrInsertingRow
+	}
+	after, err := auditTask(record.After)
+	if err != nil {
+		return model.ErrInsertingRow
+	}
+
+	row := auditRecordRow{
+		TaskID:     record.TaskID,
+		OwnerID:    record.OwnerID,
+		ActorID:    actorOf(ctx),
+		Operation:  record.Operation,
+		BeforeTask: before,
+		AfterTask:  after,
+		CreatedAt:  record.CreatedAt,
+	}
+	if err := r.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return model.ErrInsertingRow
+	}
+
+	return nil
+}
+
+func (r *AuditOrm) FindAuditRecords(ctx context.Context, filter model.AuditFilter, page model.PageRequest) (model.AuditPage, error) {
+	limit, after, err := parsePage(page, auditOrder)
+	if err != nil {
+		return model.AuditPage{}, err
+	}
+
+	db := r.db.WithContext(ctx).Clauses(ormOrderBy(auditOrder)).Limit(limit + 1)
+	if match := query.AndAlso(auditMatch(ctx, filter), after); match != nil {
+		db = db.Clauses(ormWhereClause(match))
+	}
+
+	var rows []auditRecordRow
+	if err := db.Find(&rows).Error; err != nil {
+		return model.AuditPage{}, model.ErrExecuteQuery
+	}
+
+	records := make([]model.AuditRecord, len(rows))
+	for i, row := range rows {
+		records[i] = model.AuditRecord{
+			ID:        row.ID,
+			TaskID:    row.TaskID,
+			OwnerID:   row.OwnerID,
+			ActorID:   row.ActorID,
+			Operation: row.Operation,
+			CreatedAt: row.CreatedAt,
+		}
+		if records[i].Before, err = parseAuditTask(row.BeforeTask); err != nil {
+			return model.AuditPage{}, model.ErrScanningRows
+		}
+		if records[i].After, err = parseAuditTask(row.AfterTask); err != nil {
+			return model.AuditPage{}, model.ErrScanningRows
+		}
+		records[i] = normalizeAuditRecord(records[i])
+	}
+
+	return newAuditPage(records, limit), nil
+}
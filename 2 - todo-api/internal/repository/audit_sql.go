@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+)
+
+const auditColumns = "id, task_id, owner_id, actor_id, operation, before_task, after_task, created_at"
+
+type AuditSql struct {
+	sqlConn
+}
+
+func (r *AuditSql) CreateAuditRecord(ctx context.Context, record model.AuditRecord) error {
+	record = normalizeAuditRecord(record)
+	before, err := auditTask(record.Before)
+	if err != nil {
+		return model.ErrInsertingRow
+	}
+	after, err := auditTask(record.After)
+	if err != nil {
+		return model.ErrInsertingRow
+	}
+
+	insert := "INSERT INTO task_audit (task_id, owner_id, actor_id, operation, before_task, after_task, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	if _, err := r.conn().ExecContext(ctx, insert, record.TaskID, record.OwnerID, actorOf(ctx), record.Operation, before, after, record.CreatedAt); err != nil {
+		return model.ErrInsertingRow
+	}
+
+	return nil
+}
+
+func (r *AuditSql) FindAuditRecords(ctx context.Context, filter model.AuditFilter, page model.PageRequest) (model.AuditPage, error) {
+	limit, after, err := parsePage(page, auditOrder)
+	if err != nil {
+		return model.AuditPage{}, err
+	}
+
+	where, args := sqlWhere(query.AndAlso(auditMatch(ctx, filter), after))
+	statement := "SELECT " + auditColumns + " FROM task_audit WHERE " + where + " ORDER BY " + sqlOrderBy(auditOrder) + " LIMIT ?"
+
+	rows, err := r.conn().QueryContext(ctx, statement, append(args, limit+1)...)
+	if err != nil {
+		return model.AuditPage{}, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	records := []model.AuditRecord{}
+	for rows.Next() {
+		var record model.AuditRecord
+		var before, after sql.NullString
+		if err := rows.Scan(&record.ID, &record.TaskID, &record.OwnerID, &record.ActorID, &record.Operation, &before, &after, &record.CreatedAt); err != nil {
+			return model.AuditPage{}, model.ErrScanningRows
+		}
+		if record.Before, err = parseAuditTask(before); err != nil {
+			return model.AuditPage{}, model.ErrScanningRows
+		}
+		if record.After, err = parseAuditTask(after); err != nil {
+			return model.AuditPage{}, model.ErrScanningRows
+		}
+		records = append(records, normalizeAuditRecord(record))
+	}
+	if err := rows.Err(); err != nil {
+		return model.AuditPage{}, model.ErrScanningRows
+	}
+
+	return newAuditPage(records, limit), nil
+}
@@ -0,0 +1,164 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"gochallenges/internal/migration"
+	"gochallenges/internal/repository"
+	"gochallenges/internal/repository/repositorytest"
+	"os"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Set TEST_MYSQL_DSN to also run the suites against MySQL, which is emptied before every case.
+const mysqlDsnEnv = "TEST_MYSQL_DSN"
+
+func TestConformance(t *testing.T) {
+	backends := []struct {
+		name          string
+		newRepository repositorytest.Factory
+	}{
+		{
+			name: "sql on sqlite",
+			newRepository: func(t *testing.T) repository.Store {
+				return repository.NewStore(newSqliteRepository(t))
+			},
+		},
+		{
+			name: "orm on sqlite",
+			newRepository: func(t *testing.T) repository.Store {
+				db := newSqliteRepository(t).(*repository.TaskSqlite).DB
+				repo, err := repository.NewTaskOrmWithDialector(sqlite.Dialector{Conn: db})
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected when opening the orm", err)
+				}
+				return repository.NewStore(repo)
+			},
+		},
+		{
+			name: "events on sqlite",
+			newRepository: func(t *testing.T) repository.Store {
+				db := newSqliteRepository(t).(*repository.TaskSqlite).DB
+				// Snapshots are taken often, so the cases go through them.
+				repo, err := repository.NewTaskEventsWithDB(db, 2)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected when opening the event log", err)
+				}
+				return repository.NewStore(repo)
+			},
+		},
+		{
+			name: "sql on mysql",
+			newRepository: func(t *testing.T) repository.Store {
+				return repository.NewStore(repository.NewTaskSqlWithDB(openMysql(t)))
+			},
+		},
+		{
+			name: "orm on mysql",
+			newRepository: func(t *testing.T) repository.Store {
+				db := openMysql(t)
+				repo, err := repository.NewTaskOrmWithDialector(mysql.New(mysql.Config{Conn: db}))
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected when opening the orm", err)
+				}
+				return repository.NewStore(repo)
+			},
+		},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repositorytest.Run(t, backend.newRepository)
+		})
+	}
+}
+
+func TestUserConformance(t *testing.T) {
+	backends := []struct {
+		name          string
+		newRepository repositorytest.UserFactory
+	}{
+		{
+			name: "sql on sqlite",
+			newRepository: func(t *testing.T) repository.User {
+				return repository.NewUserSql(newSqliteDB(t))
+			},
+		},
+		{
+			name: "orm on sqlite",
+			newRepository: func(t *testing.T) repository.User {
+				db, err := gorm.Open(sqlite.Dialector{Conn: newSqliteDB(t)}, &gorm.Config{})
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected when opening the orm", err)
+				}
+				return repository.NewUserOrm(db)
+			},
+		},
+		{
+			name: "sql on mysql",
+			newRepository: func(t *testing.T) repository.User {
+				return repository.NewUserSql(openMysql(t))
+			},
+		},
+		{
+			name: "orm on mysql",
+			newRepository: func(t *testing.T) repository.User {
+				db, err := gorm.Open(mysql.New(mysql.Config{Conn: openMysql(t)}), &gorm.Config{})
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected when opening the orm", err)
+				}
+				return repository.NewUserOrm(db)
+			},
+		},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repositorytest.RunUser(t, backend.newRepository)
+		})
+	}
+}
+
+// newSqliteDB is a migrated in-memory database, closed when t finishes.
+func newSqliteDB(t *testing.T) *sql.DB {
+	repo := newSqliteRepository(t)
+	t.Cleanup(repo.Close)
+	return repo.(*repository.TaskSqlite).DB
+}
+
+func newSqliteRepository(t *testing.T) repository.Task {
+	repo, err := repository.NewTaskSqlite(repository.SqliteInMemory)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening sqlite", err)
+	}
+	return repo
+}
+
+func openMysql(t *testing.T) *sql.DB {
+	dsn := os.Getenv(mysqlDsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", mysqlDsnEnv)
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening mysql", err)
+	}
+	migrator, err := migration.New(db, migration.MySQL)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when loading migrations", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("an error '%s' was not expected when migrating mysql", err)
+	}
+	for _, statement := range []string{"DELETE FROM task", "DELETE FROM task_audit", "DELETE FROM webhook_delivery", "DELETE FROM webhook", "DELETE FROM idempotency_key", "DELETE FROM project", "DELETE FROM users WHERE id <> 1"} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("an error '%s' was not expected when emptying the tables", err)
+		}
+	}
+	return db
+}
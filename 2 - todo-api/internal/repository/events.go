@@ -0,0 +1,742 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DefaultSnapshotEvery = 1000
+
+// The events of the log; an update is logged as the events it is made of, or as a TaskEdited.
+const (
+	taskCreated   = "TaskCreated"
+	taskMoved     = "TaskMoved"
+	taskFiled     = "TaskFiled"
+	taskRenamed   = "TaskRenamed"
+	taskCompleted = "TaskCompleted"
+	taskReopened  = "TaskReopened"
+	taskEdited    = "TaskEdited"
+	taskDeleted   = "TaskDeleted"
+	taskRestored  = "TaskRestored"
+	taskPurged    = "TaskPurged"
+)
+
+// TaskEvents keeps tasks as a log, read through a projection held in memory.
+type TaskEvents struct {
+	// store holds the log, on the connection the other repositories share.
+	store         *TaskSql
+	projection    *taskProjection
+	snapshotEvery int
+	tx            *eventTx
+}
+
+type taskEvent struct {
+	Seq        int64
+	TaskID     int
+	OwnerID    int
+	Type       string
+	Version    int
+	Data       json.RawMessage
+	OccurredAt time.Time
+}
+
+type taskMovedData struct {
+	ParentID *int `json:"parent_id"`
+}
+
+type taskFiledData struct {
+	ProjectID *int `json:"project_id"`
+}
+
+type taskRenamedData struct {
+	Name string `json:"name"`
+}
+
+type taskCompletedData struct {
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+type taskEditedData struct {
+	Description string         `json:"description"`
+	DueAt       *time.Time     `json:"due_at"`
+	Priority    model.Priority `json:"priority"`
+}
+
+type taskState struct {
+	Seq    int64              `json:"seq"`
+	At     time.Time          `json:"at"`
+	LastID int                `json:"last_id"`
+	Tasks  map[int]model.Task `json:"tasks"`
+}
+
+// taskView is the tasks as a reader sees them.
+type taskView interface {
+	// get returns the task with id, when expr picks it.
+	get(id int, expr query.Expr) (model.Task, bool)
+	each(fn func(task model.Task))
+}
+
+// taskProjection is the state shared by the repository and its transactions.
+type taskProjection struct {
+	// writer lets one transaction at a time log events.
+	writer sync.Mutex
+	mu     sync.RWMutex
+	state  taskState
+	// sinceSnapshot counts the events applied since the last snapshot.
+	sinceSnapshot int
+}
+
+// eventTx is what a transaction changed, which only it sees until it commits.
+type eventTx struct {
+	parent *eventTx
+	state  *taskState
+	seq    int64
+	at     time.Time
+	lastID int
+	tasks  map[int]*model.Task
+	events int
+}
+
+func NewTaskEvents(path string) (Task, error) {
+	repo, err := NewTaskSqlite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := NewTaskEventsWithDB(repo.(*TaskSqlite).DB, DefaultSnapshotEvery)
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// NewTaskEventsWithDB takes no snapshots when snapshotEvery is 0.
+func NewTaskEventsWithDB(db *sql.DB, snapshotEvery int) (Task, error) {
+	r := &TaskEvents{store: &TaskSql{sqlConn{DB: db}}, projection: &taskProjection{}, snapshotEvery: snapshotEvery}
+
+	state, err := r.replay(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	r.projection.state = state
+	return r, nil
+}
+
+func (r *TaskEvents) Create(ctx context.Context, task model.Task) (created model.Task, err error) {
+	err = r.write(ctx, func(tx *TaskEvents) error {
+		created, err = tx.create(ctx, task)
+		return err
+	})
+	return created, err
+}
+
+func (r *TaskEvents) create(ctx context.Context, task model.Task) (model.Task, error) {
+	task = normalizeTask(task)
+	if task.ID > 0 {
+		if _, exists := r.tx.get(task.ID, nil); exists {
+			return task, model.ErrTaskAlreadyExists
+		}
+	} else {
+		task.ID = r.tx.lastID + 1
+	}
+	task.OwnerID = ownerOf(ctx)
+	task.CreatedAt = r.now()
+	task.UpdatedAt = task.CreatedAt
+	task.DeletedAt = nil
+	task.Version = 1
+
+	err := r.append(ctx, taskEvent{TaskID: task.ID, OwnerID: task.OwnerID, Type: taskCreated, Version: task.Version}, task)
+	if err != nil {
+		return task, err
+	}
+	created, _ := r.tx.get(task.ID, nil)
+	return created, nil
+}
+
+// CreateMany logs every task in one transaction.
+func (r *TaskEvents) CreateMany(ctx context.Context, tasks []model.Task) ([]model.Task, error) {
+	return createEach(ctx, r, tasks)
+}
+
+func (r *TaskEvents) FindByID(ctx context.Context, id int) (task model.Task, err error) {
+	err = r.view(ctx, func(tasks taskView) error {
+		var ok bool
+		if task, ok = tasks.get(id, taskMatch(ctx, id, 0)); !ok {
+			return model.ErrTaskNotFound
+		}
+		return nil
+	})
+	return task, err
+}
+
+func (r *TaskEvents) FindByStatus(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+	return r.Find(ctx, query.Query{Filter: inProject(projectId, query.Comparison{Field: "completed", Op: query.Eq, Value: completed})}, page)
+}
+
+func (r *TaskEvents) FindAll(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+	return r.Find(ctx, query.Query{Filter: inProject(projectId, nil)}, page)
+}
+
+func (r *TaskEvents) Find(ctx context.Context, q query.Query, page model.PageRequest) (model.TaskPage, error) {
+	return r.find(ctx, query.AndAlso(q.Filter, trashed(false)), pageOrder(q.OrderBy), page)
+}
+
+func (r *TaskEvents) FindTrash(ctx context.Context, page model.PageRequest) (model.TaskPage, error) {
+	return r.find(ctx, trashed(true), trashOrder, page)
+}
+
+func (r *TaskEvents) find(ctx context.Context, filter query.Expr, orders []query.Order, page model.PageRequest) (model.TaskPage, error) {
+	limit, after, err := parsePage(page, orders)
+	if err != nil {
+		return model.TaskPage{}, err
+	}
+
+	filter = ownerFilter(ctx, query.AndAlso(filter, after))
+	tasks := []model.Task{}
+	err = r.view(ctx, func(view taskView) error {
+		view.each(func(task model.Task) {
+			if memoryMatch(task, filter) {
+				tasks = append(tasks, task)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return model.TaskPage{}, err
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return memoryLess(tasks[i], tasks[j], orders) })
+	if len(tasks) > limit+1 {
+		tasks = tasks[:limit+1]
+	}
+	return newTaskPage(tasks, limit, orders), nil
+}
+
+func (r *TaskEvents) FindSubtree(ctx context.Context, id int, inTrash bool) ([]model.Task, error) {
+	filter := ownerFilter(ctx, trashed(inTrash))
+	tasks := []model.Task{}
+	err := r.view(ctx, func(view taskView) error {
+		children := map[int][]model.Task{}
+		view.each(func(task model.Task) {
+			if task.ParentID != nil && memoryMatch(task, filter) {
+				children[*task.ParentID] = append(children[*task.ParentID], task)
+			}
+		})
+
+		seen := map[int]bool{id: true}
+		for next := []int{id}; len(next) > 0; {
+			parent := next[0]
+			next = next[1:]
+			for _, child := range children[parent] {
+				if !seen[child.ID] {
+					seen[child.ID] = true
+					tasks = append(tasks, child)
+					next = append(next, child.ID)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (r *TaskEvents) Update(ctx context.Context, task model.Task) (updated model.Task, err error) {
+	err = r.write(ctx, func(tx *TaskEvents) error {
+		updated, err = tx.update(ctx, task)
+		return err
+	})
+	return updated, err
+}
+
+func (r *TaskEvents) update(ctx context.Context, task model.Task) (model.Task, error) {
+	task = normalizeTask(task)
+	stored, ok := r.tx.get(task.ID, taskMatch(ctx, task.ID, 0))
+	if !ok {
+		return model.Task{}, model.ErrTaskNotFound
+	}
+	if task.Version != 0 && task.Version != stored.Version {
+		return stored, model.ErrTaskVersionMismatch
+	}
+
+	event := taskEvent{TaskID: stored.ID, OwnerID: stored.OwnerID, Version: stored.Version + 1}
+	logged := 0
+	logEvent := func(eventType string, data any) error {
+		event.Type = eventType
+		logged++
+		return r.append(ctx, event, data)
+	}
+
+	if !sameId(task.ParentID, stored.ParentID) {
+		if err := logEvent(taskMoved, taskMovedData{ParentID: task.ParentID}); err != nil {
+			return stored, err
+		}
+	}
+	if !sameId(task.ProjectID, stored.ProjectID) {
+		if err := logEvent(taskFiled, taskFiledData{ProjectID: task.ProjectID}); err != nil {
+			return stored, err
+		}
+	}
+	if task.Name != stored.Name {
+		if err := logEvent(taskRenamed, taskRenamedData{Name: task.Name}); err != nil {
+			return stored, err
+		}
+	}
+	switch {
+	case task.Completed && (!stored.Completed || !sameTime(task.CompletedAt, stored.CompletedAt)):
+		if err := logEvent(taskCompleted, taskCompletedData{CompletedAt: task.CompletedAt}); err != nil {
+			return stored, err
+		}
+	case !task.Completed && stored.Completed:
+		if err := logEvent(taskReopened, struct{}{}); err != nil {
+			return stored, err
+		}
+	}
+	edited := task.Description != stored.Description || !sameTime(task.DueAt, stored.DueAt) || task.Priority != stored.Priority
+	if edited || logged == 0 {
+		if err := logEvent(taskEdited, taskEditedData{Description: task.Description, DueAt: task.DueAt, Priority: task.Priority}); err != nil {
+			return stored, err
+		}
+	}
+
+	updated, _ := r.tx.get(stored.ID, nil)
+	return updated, nil
+}
+
+func (r *TaskEvents) Delete(ctx context.Context, id int, version int) error {
+	return r.write(ctx, func(tx *TaskEvents) error {
+		stored, ok := tx.tx.get(id, taskMatch(ctx, id, 0))
+		if !ok {
+			return nil
+		}
+		if version > 0 && version != stored.Version {
+			return model.ErrTaskVersionMismatch
+		}
+		return tx.append(ctx, taskEvent{TaskID: id, OwnerID: stored.OwnerID, Type: taskDeleted, Version: stored.Version + 1}, struct{}{})
+	})
+}
+
+func (r *TaskEvents) Restore(ctx context.Context, id int) (restored model.Task, err error) {
+	err = r.write(ctx, func(tx *TaskEvents) error {
+		stored, ok := tx.tx.get(id, ownerFilter(ctx, trashed(true)))
+		if !ok {
+			return model.ErrTaskNotFound
+		}
+		if err := tx.append(ctx, taskEvent{TaskID: id, OwnerID: stored.OwnerID, Type: taskRestored, Version: stored.Version + 1}, struct{}{}); err != nil {
+			return err
+		}
+		restored, _ = tx.tx.get(id, nil)
+		return nil
+	})
+	return restored, err
+}
+
+func (r *TaskEvents) Purge(ctx context.Context, before time.Time) (purged int, err error) {
+	err = r.write(ctx, func(tx *TaskEvents) error {
+		filter := ownerFilter(ctx, query.Comparison{Field: "deleted_at", Op: query.Lt, Value: normalizeTime(before)})
+		var trashed []model.Task
+		tx.tx.each(func(task model.Task) {
+			if memoryMatch(task, filter) {
+				trashed = append(trashed, task)
+			}
+		})
+		sort.Slice(trashed, func(i, j int) bool { return trashed[i].ID < trashed[j].ID })
+
+		for _, task := range trashed {
+			if err := tx.append(ctx, taskEvent{TaskID: task.ID, OwnerID: task.OwnerID, Type: taskPurged, Version: task.Version}, struct{}{}); err != nil {
+				return err
+			}
+		}
+		purged = len(trashed)
+		return nil
+	})
+	return purged, err
+}
+
+func (r *TaskEvents) FindProjectStats(ctx context.Context, id int, now time.Time) (model.ProjectStats, error) {
+	stats := model.ProjectStats{ProjectID: id}
+	filter := ownerFilter(ctx, inProject(id, trashed(false)))
+	now = normalizeTime(now)
+	err := r.view(ctx, func(view taskView) error {
+		view.each(func(task model.Task) {
+			if !memoryMatch(task, filter) {
+				return
+			}
+			stats.Total++
+			if task.Completed {
+				stats.Completed++
+			} else if task.DueAt != nil && task.DueAt.Before(now) {
+				stats.Overdue++
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return model.ProjectStats{}, err
+	}
+
+	return countProjectStats(stats), nil
+}
+
+// Transaction applies the events fn logs to the projection once they commit.
+func (r *TaskEvents) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	p := r.projection
+	if r.tx == nil {
+		p.writer.Lock()
+		defer p.writer.Unlock()
+	}
+
+	var done *eventTx
+	err := r.store.transaction(ctx, func(c sqlConn) error {
+		tx := &TaskEvents{store: &TaskSql{c}, projection: p, snapshotEvery: r.snapshotEvery, tx: r.begin()}
+		if err := fn(sqlStore(tx, c)); err != nil {
+			return err
+		}
+		done = tx.tx
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if r.tx != nil {
+		r.tx.merge(done)
+		return nil
+	}
+
+	p.commit(done)
+	if r.snapshotEvery > 0 && p.sinceSnapshot >= r.snapshotEvery {
+		r.snapshot(ctx)
+	}
+	return nil
+}
+
+// StateAt replays the log up to at without touching the projection.
+func (r *TaskEvents) StateAt(ctx context.Context, at time.Time) ([]model.Task, error) {
+	state, err := r.replay(ctx, &at)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := ownerFilter(ctx, nil)
+	tasks := []model.Task{}
+	for _, task := range state.Tasks {
+		if memoryMatch(task, filter) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (r *TaskEvents) Close() {
+	r.store.Close()
+}
+
+// write runs fn in a transaction of its own, or in a savepoint of r.
+func (r *TaskEvents) write(ctx context.Context, fn func(tx *TaskEvents) error) error {
+	return r.Transaction(ctx, func(tx Store) error {
+		return fn(tx.Tasks.(*TaskEvents))
+	})
+}
+
+// begin starts what a transaction inside r changes.
+func (r *TaskEvents) begin() *eventTx {
+	if r.tx != nil {
+		return &eventTx{parent: r.tx, state: r.tx.state, seq: r.tx.seq, at: r.tx.at, lastID: r.tx.lastID, tasks: map[int]*model.Task{}}
+	}
+	state := &r.projection.state
+	return &eventTx{state: state, seq: state.Seq, at: state.At, lastID: state.LastID, tasks: map[int]*model.Task{}}
+}
+
+// view runs fn on what r's transaction sees, or on the projection held still.
+func (r *TaskEvents) view(ctx context.Context, fn func(tasks taskView) error) error {
+	if ctx.Err() != nil {
+		return model.ErrExecuteQuery
+	}
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	r.projection.mu.RLock()
+	defer r.projection.mu.RUnlock()
+	return fn(&r.projection.state)
+}
+
+// now never goes back before the last event, so the log stays in time order.
+func (r *TaskEvents) now() time.Time {
+	now := model.Now()
+	if at := r.tx.at; now.Before(at) {
+		return at
+	}
+	return now
+}
+
+// append logs event and applies it to what the transaction sees.
+func (r *TaskEvents) append(ctx context.Context, event taskEvent, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return model.ErrExecuteQuery
+	}
+	event.Data = encoded
+	event.OccurredAt = r.now()
+
+	statement, err := r.store.conn().PrepareContext(ctx, "INSERT INTO task_event (task_id, owner_id, type, version, data, occurred_at) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return model.ErrPreparingStatemant
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, event.TaskID, event.OwnerID, event.Type, event.Version, string(event.Data), event.OccurredAt)
+	if err != nil {
+		return model.ErrExecuteQuery
+	}
+	if event.Seq, err = result.LastInsertId(); err != nil {
+		return model.ErrExecuteQuery
+	}
+
+	return r.tx.apply(event)
+}
+
+// snapshot stores the projection; a failed one is taken again after the next commit.
+func (r *TaskEvents) snapshot(ctx context.Context) {
+	state := r.projection.state
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+
+	_, err = r.store.exec(ctx, "INSERT INTO task_snapshot (seq, state, taken_at) VALUES (?, ?, ?)", state.Seq, string(encoded), state.At)
+	if err == nil {
+		r.projection.sinceSnapshot = 0
+	}
+}
+
+// replay leaves out what happened after at, unless it is nil.
+func (r *TaskEvents) replay(ctx context.Context, at *time.Time) (taskState, error) {
+	state := taskState{Tasks: map[int]model.Task{}}
+
+	var snapshotFilter query.Expr
+	if at != nil {
+		snapshotFilter = query.Comparison{Field: "taken_at", Op: query.Le, Value: normalizeTime(*at)}
+	}
+	where, args := sqlWhere(snapshotFilter)
+	rows, err := r.store.conn().QueryContext(ctx, "SELECT state FROM task_snapshot WHERE "+where+" ORDER BY seq DESC LIMIT 1", args...)
+	if err != nil {
+		return state, model.ErrExecuteQuery
+	}
+	if rows.Next() {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			rows.Close()
+			return state, model.ErrScanningRows
+		}
+		if err := json.Unmarshal([]byte(encoded), &state); err != nil {
+			rows.Close()
+			return state, fmt.Errorf("%w: snapshot: %v", model.ErrScanningRows, err)
+		}
+	}
+	rows.Close()
+
+	var eventFilter query.Expr = query.Comparison{Field: "seq", Op: query.Gt, Value: state.Seq}
+	if at != nil {
+		eventFilter = query.AndAlso(eventFilter, query.Comparison{Field: "occurred_at", Op: query.Le, Value: normalizeTime(*at)})
+	}
+	where, args = sqlWhere(eventFilter)
+	rows, err = r.store.conn().QueryContext(ctx, "SELECT seq, task_id, owner_id, type, version, data, occurred_at FROM task_event WHERE "+where+" ORDER BY seq", args...)
+	if err != nil {
+		return state, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event taskEvent
+		var data string
+		if err := rows.Scan(&event.Seq, &event.TaskID, &event.OwnerID, &event.Type, &event.Version, &data, &event.OccurredAt); err != nil {
+			return state, model.ErrScanningRows
+		}
+		event.Data = json.RawMessage(data)
+		event.OccurredAt = normalizeTime(event.OccurredAt)
+		if err := state.apply(event); err != nil {
+			return state, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return state, model.ErrScanningRows
+	}
+
+	return state, nil
+}
+
+func (s *taskState) get(id int, expr query.Expr) (model.Task, bool) {
+	task, ok := s.Tasks[id]
+	if !ok || !memoryMatch(task, expr) {
+		return model.Task{}, false
+	}
+	return task, true
+}
+
+func (s *taskState) each(fn func(task model.Task)) {
+	for _, task := range s.Tasks {
+		fn(task)
+	}
+}
+
+func (s *taskState) apply(event taskEvent) error {
+	task, exists, err := applyEvent(s.Tasks[event.TaskID], event)
+	if err != nil {
+		return err
+	}
+	if exists {
+		s.Tasks[event.TaskID] = task
+		if event.TaskID > s.LastID {
+			s.LastID = event.TaskID
+		}
+	} else {
+		delete(s.Tasks, event.TaskID)
+	}
+	s.Seq, s.At = event.Seq, event.OccurredAt
+	return nil
+}
+
+func (tx *eventTx) get(id int, expr query.Expr) (model.Task, bool) {
+	for t := tx; t != nil; t = t.parent {
+		if task, changed := t.tasks[id]; changed {
+			if task == nil || !memoryMatch(*task, expr) {
+				return model.Task{}, false
+			}
+			return *task, true
+		}
+	}
+	return tx.state.get(id, expr)
+}
+
+func (tx *eventTx) each(fn func(task model.Task)) {
+	seen := map[int]bool{}
+	for t := tx; t != nil; t = t.parent {
+		for id, task := range t.tasks {
+			if !seen[id] && task != nil {
+				fn(*task)
+			}
+			seen[id] = true
+		}
+	}
+	for id, task := range tx.state.Tasks {
+		if !seen[id] {
+			fn(task)
+		}
+	}
+}
+
+func (tx *eventTx) apply(event taskEvent) error {
+	task, _ := tx.get(event.TaskID, nil)
+	task, exists, err := applyEvent(task, event)
+	if err != nil {
+		return err
+	}
+	tx.tasks[event.TaskID] = nil
+	if exists {
+		tx.tasks[event.TaskID] = &task
+		if event.TaskID > tx.lastID {
+			tx.lastID = event.TaskID
+		}
+	}
+	tx.seq, tx.at = event.Seq, event.OccurredAt
+	tx.events++
+	return nil
+}
+
+// merge keeps the changes of a savepoint that was released.
+func (tx *eventTx) merge(nested *eventTx) {
+	for id, task := range nested.tasks {
+		tx.tasks[id] = task
+	}
+	tx.seq, tx.at, tx.lastID = nested.seq, nested.at, nested.lastID
+	tx.events += nested.events
+}
+
+// commit applies the changes of tx once its sql.Tx committed.
+func (p *taskProjection) commit(tx *eventTx) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, task := range tx.tasks {
+		if task == nil {
+			delete(p.state.Tasks, id)
+		} else {
+			p.state.Tasks[id] = *task
+		}
+	}
+	p.state.Seq, p.state.At, p.state.LastID = tx.seq, tx.at, tx.lastID
+	p.sinceSnapshot += tx.events
+}
+
+// applyEvent also returns whether the task still exists.
+func applyEvent(task model.Task, event taskEvent) (model.Task, bool, error) {
+	var err error
+	switch event.Type {
+	case taskCreated:
+		err = json.Unmarshal(event.Data, &task)
+	case taskMoved:
+		var data taskMovedData
+		err = json.Unmarshal(event.Data, &data)
+		task.ParentID = data.ParentID
+	case taskFiled:
+		var data taskFiledData
+		err = json.Unmarshal(event.Data, &data)
+		task.ProjectID = data.ProjectID
+	case taskRenamed:
+		var data taskRenamedData
+		err = json.Unmarshal(event.Data, &data)
+		task.Name = data.Name
+	case taskCompleted:
+		var data taskCompletedData
+		err = json.Unmarshal(event.Data, &data)
+		task.Completed, task.CompletedAt = true, data.CompletedAt
+	case taskReopened:
+		task.Completed, task.CompletedAt = false, nil
+	case taskEdited:
+		var data taskEditedData
+		err = json.Unmarshal(event.Data, &data)
+		task.Description, task.DueAt, task.Priority = data.Description, data.DueAt, data.Priority
+	case taskDeleted:
+		deletedAt := event.OccurredAt
+		task.DeletedAt = &deletedAt
+	case taskRestored:
+		task.DeletedAt = nil
+	case taskPurged:
+		return task, false, nil
+	default:
+		err = fmt.Errorf("unknown event %q", event.Type)
+	}
+	if err != nil {
+		return task, false, fmt.Errorf("%w: event %d: %v", model.ErrScanningRows, event.Seq, err)
+	}
+
+	task.Version, task.UpdatedAt = event.Version, event.OccurredAt
+	return normalizeTask(task), true, nil
+}
+
+func sameId(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"strings"
+	"time"
+)
+
+// truth is SQL's three-valued logic, so the projection filters like the other backends.
+type truth int
+
+const (
+	isFalse truth = iota
+	isTrue
+	isUnknown
+)
+
+func memoryMatch(task model.Task, expr query.Expr) bool {
+	return expr == nil || memoryWhere(task, expr) == isTrue
+}
+
+func memoryWhere(task model.Task, expr query.Expr) truth {
+	switch e := expr.(type) {
+	case query.Comparison:
+		return memoryCompare(taskField(task, e.Field), e.Op, e.Value)
+	case query.And:
+		left, right := memoryWhere(task, e.Left), memoryWhere(task, e.Right)
+		switch {
+		case left == isFalse || right == isFalse:
+			return isFalse
+		case left == isUnknown || right == isUnknown:
+			return isUnknown
+		}
+		return isTrue
+	case query.Or:
+		left, right := memoryWhere(task, e.Left), memoryWhere(task, e.Right)
+		switch {
+		case left == isTrue || right == isTrue:
+			return isTrue
+		case left == isUnknown || right == isUnknown:
+			return isUnknown
+		}
+		return isFalse
+	case query.Not:
+		switch memoryWhere(task, e.Expr) {
+		case isTrue:
+			return isFalse
+		case isFalse:
+			return isTrue
+		}
+		return isUnknown
+	}
+	panic("unknown filter expression")
+}
+
+func memoryCompare(field any, op query.Operator, value any) truth {
+	if value == nil {
+		if (field == nil) == (op != query.Ne) {
+			return isTrue
+		}
+		return isFalse
+	}
+	if field == nil {
+		return isUnknown
+	}
+
+	if op == query.Contains {
+		s, _ := field.(string)
+		pattern, _ := value.(string)
+		return truthOf(strings.Contains(strings.ToLower(s), strings.ToLower(pattern)))
+	}
+
+	c := compareValues(field, value)
+	switch op {
+	case query.Eq:
+		return truthOf(c == 0)
+	case query.Ne:
+		return truthOf(c != 0)
+	case query.Lt:
+		return truthOf(c < 0)
+	case query.Le:
+		return truthOf(c <= 0)
+	case query.Gt:
+		return truthOf(c > 0)
+	case query.Ge:
+		return truthOf(c >= 0)
+	}
+	panic("unknown filter operator")
+}
+
+func truthOf(b bool) truth {
+	if b {
+		return isTrue
+	}
+	return isFalse
+}
+
+// memoryLess sorts like ORDER BY, with NULLs first.
+func memoryLess(a, b model.Task, orders []query.Order) bool {
+	for _, order := range orders {
+		c := compareValues(taskField(a, order.Field), taskField(b, order.Field))
+		if c == 0 {
+			continue
+		}
+		if order.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+func compareValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch a := a.(type) {
+	case int:
+		return compareInts(a, b.(int))
+	case model.Priority:
+		return compareInts(int(a), int(b.(model.Priority)))
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		return compareInts(boolInt(a), boolInt(b.(bool)))
+	case time.Time:
+		switch b := b.(time.Time); {
+		case a.Before(b):
+			return -1
+		case a.After(b):
+			return 1
+		}
+		return 0
+	}
+	panic("unknown field type")
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func taskField(task model.Task, field string) any {
+	switch field {
+	case "owner_id":
+		return task.OwnerID
+	case "version":
+		return task.Version
+	case "deleted_at":
+		if task.DeletedAt == nil {
+			return nil
+		}
+		return *task.DeletedAt
+	}
+	return query.Fields[field].Value(task)
+}
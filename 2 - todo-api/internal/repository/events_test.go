@@ -0,0 +1,178 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/pkg"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventsRebuildFromSnapshotAndLog(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	repo := newEventsRepository(t, db, 2)
+
+	first, _ := repo.Create(ctx, model.Task{Name: "first"})
+	second, _ := repo.Create(ctx, model.Task{Name: "second"})
+	second.Name, second.Completed = "second, done", true
+	if _, err := repo.Update(ctx, second); err != nil {
+		t.Fatalf("Error was not expected while updating the task, got %s", err)
+	}
+	if err := repo.Delete(ctx, first.ID, 0); err != nil {
+		t.Fatalf("Error was not expected while deleting the task, got %s", err)
+	}
+	want := findEverything(t, repo)
+
+	var snapshots int
+	db.QueryRow("SELECT COUNT(*) FROM task_snapshot").Scan(&snapshots)
+	if snapshots != 2 {
+		t.Fatalf("expected a snapshot every 2 events, got %d snapshots", snapshots)
+	}
+	// The events the snapshots hold are no longer needed to rebuild.
+	if _, err := db.Exec("DELETE FROM task_event WHERE seq <= (SELECT MAX(seq) FROM task_snapshot)"); err != nil {
+		t.Fatalf("Error was not expected while trimming the log, got %s", err)
+	}
+
+	rebuilt := newEventsRepository(t, db, 2)
+	if got := findEverything(t, rebuilt); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v want %+v", got, want)
+	}
+	third, err := rebuilt.Create(ctx, model.Task{Name: "third"})
+	if err != nil || third.ID != second.ID+1 {
+		t.Errorf("expected the next id after a rebuild, got %+v %v", third, err)
+	}
+}
+
+func TestEventsStateAt(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	repo := newEventsRepository(t, db, 0)
+	alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+
+	created, _ := repo.Create(ctx, model.Task{Name: "task"})
+	renamed := created
+	renamed.Name = "renamed"
+	if _, err := repo.Update(ctx, renamed); err != nil {
+		t.Fatalf("Error was not expected while updating the task, got %s", err)
+	}
+	repo.Delete(ctx, created.ID, 0)
+
+	// The task was created 3h ago, renamed 2h ago and deleted 1h ago.
+	now := model.Now()
+	for seq, hoursAgo := range map[int]int{1: 3, 2: 2, 3: 1} {
+		if _, err := db.Exec("UPDATE task_event SET occurred_at = ? WHERE seq = ?", now.Add(-time.Duration(hoursAgo)*time.Hour), seq); err != nil {
+			t.Fatalf("Error was not expected while moving the log back, got %s", err)
+		}
+	}
+
+	cases := []struct {
+		caseName      string
+		ctx           context.Context
+		at            time.Time
+		expectedNames []string
+		expectTrashed bool
+	}{
+		{caseName: "before the task existed", ctx: ctx, at: now.Add(-4 * time.Hour)},
+		{caseName: "once it was created", ctx: ctx, at: now.Add(-150 * time.Minute), expectedNames: []string{"task"}},
+		{caseName: "once it was renamed", ctx: ctx, at: now.Add(-90 * time.Minute), expectedNames: []string{"renamed"}},
+		{caseName: "once it was deleted", ctx: ctx, at: now, expectedNames: []string{"renamed"}, expectTrashed: true},
+		{caseName: "another user's view", ctx: alice, at: now},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			tasks, err := repo.(*repository.TaskEvents).StateAt(testCase.ctx, testCase.at)
+			if err != nil {
+				t.Fatalf("Error was not expected while rebuilding the state, got %s", err)
+			}
+			names := []string{}
+			for _, task := range tasks {
+				names = append(names, task.Name)
+				if trashed := task.DeletedAt != nil; trashed != testCase.expectTrashed {
+					t.Errorf("got deleted_at %v, expected it to be set %v", task.DeletedAt, testCase.expectTrashed)
+				}
+			}
+			if testCase.expectedNames == nil {
+				testCase.expectedNames = []string{}
+			}
+			if !reflect.DeepEqual(names, testCase.expectedNames) {
+				t.Errorf("got %v want %v", names, testCase.expectedNames)
+			}
+		})
+	}
+
+	if found, err := repo.FindByID(ctx, created.ID); err == nil {
+		t.Errorf("expected rebuilding a past state to leave the current one alone, got %+v", found)
+	}
+}
+
+func TestEventsTransactionIsOnlySeenByItselfUntilItCommits(t *testing.T) {
+	ctx := context.Background()
+	repo := newEventsRepository(t, newSqliteDB(t), 0)
+	existing, _ := repo.Create(ctx, model.Task{Name: "existing"})
+
+	var created model.Task
+	err := repo.Transaction(ctx, func(tx repository.Store) error {
+		var err error
+		if created, err = tx.Tasks.Create(ctx, model.Task{Name: "created"}); err != nil {
+			return err
+		}
+		if got := findEverything(t, tx.Tasks); len(got) != 2 {
+			t.Errorf("expected the transaction to see its own task, got %+v", got)
+		}
+
+		read := make(chan []model.Task, 1)
+		go func() { read <- findEverything(t, repo) }()
+		select {
+		case got := <-read:
+			if !reflect.DeepEqual(got, []model.Task{existing}) {
+				t.Errorf("expected the task to be left out until the commit, got %+v", got)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("expected reads not to wait for the transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error was not expected while committing, got %s", err)
+	}
+
+	if found, err := repo.FindByID(ctx, created.ID); err != nil || !reflect.DeepEqual(found, created) {
+		t.Errorf("expected the committed task, got %+v %v", found, err)
+	}
+}
+
+func TestEventsOpenOnSqliteOnly(t *testing.T) {
+	_, _, err := repository.Open(repository.DbEvents, pkg.DbConfig{Driver: "mysql", File: repository.SqliteInMemory})
+	if !errors.Is(err, model.ErrInvalidDbImplementation) {
+		t.Errorf("got %v want %v", err, model.ErrInvalidDbImplementation)
+	}
+}
+
+func newEventsRepository(t *testing.T, db *sql.DB, snapshotEvery int) repository.Task {
+	repo, err := repository.NewTaskEventsWithDB(db, snapshotEvery)
+	if err != nil {
+		t.Fatalf("Error was not expected while opening the event log, got %s", err)
+	}
+	return repo
+}
+
+// findEverything returns the tasks and the trash.
+func findEverything(t *testing.T, repo repository.Task) []model.Task {
+	t.Helper()
+
+	tasks, err := repo.FindAll(context.Background(), 0, model.PageRequest{})
+	if err != nil {
+		t.Fatalf("Error was not expected while finding the tasks, got %s", err)
+	}
+	trash, err := repo.FindTrash(context.Background(), model.PageRequest{})
+	if err != nil {
+		t.Fatalf("Error was not expected while finding the trash, got %s", err)
+	}
+	return append(tasks.Tasks, trash.Tasks...)
+}
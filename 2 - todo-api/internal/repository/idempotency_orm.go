@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gochallenges/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type idempotencyKeyRow struct {
+	OwnerID        int
+	IdempotencyKey string
+	Fingerprint    string
+	Response       string
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
+
+func (idempotencyKeyRow) TableName() string {
+	return "idempotency_key"
+}
+
+type IdempotencyOrm struct {
+	db *gorm.DB
+}
+
+func (r *IdempotencyOrm) FindIdempotencyKey(ctx context.Context, key string) (model.IdempotencyKey, error) {
+	idempotencyKey := model.IdempotencyKey{OwnerID: ownerOf(ctx), Key: key}
+
+	var row idempotencyKeyRow
+	err := r.db.WithContext(ctx).Where("owner_id = ? AND idempotency_key = ? AND expires_at > ?", idempotencyKey.OwnerID, key, model.Now()).First(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return idempotencyKey, model.ErrIdempotencyKeyNotFound
+		}
+		return idempotencyKey, model.ErrExecuteQuery
+	}
+	if err := json.Unmarshal([]byte(row.Response), &idempotencyKey.Task); err != nil {
+		return idempotencyKey, model.ErrScanningRows
+	}
+
+	idempotencyKey.Fingerprint = row.Fingerprint
+	idempotencyKey.CreatedAt = row.CreatedAt
+	idempotencyKey.ExpiresAt = row.ExpiresAt
+	return normalizeIdempotencyKey(idempotencyKey), nil
+}
+
+func (r *IdempotencyOrm) CreateIdempotencyKey(ctx context.Context, key model.IdempotencyKey) error {
+	key = normalizeIdempotencyKey(key)
+	key.OwnerID = ownerOf(ctx)
+	response, err := json.Marshal(key.Task)
+	if err != nil {
+		return model.ErrInsertingRow
+	}
+
+	db := r.db.WithContext(ctx)
+	if err := db.Where("owner_id = ? AND expires_at <= ?", key.OwnerID, model.Now()).Delete(&idempotencyKeyRow{}).Error; err != nil {
+		return model.ErrExecuteQuery
+	}
+
+	row := idempotencyKeyRow{
+		OwnerID:        key.OwnerID,
+		IdempotencyKey: key.Key,
+		Fingerprint:    key.Fingerprint,
+		Response:       string(response),
+		CreatedAt:      key.CreatedAt,
+		ExpiresAt:      key.ExpiresAt,
+	}
+	if err := db.Create(&row).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return model.ErrIdempotencyKeyExists
+		}
+		return model.ErrInsertingRow
+	}
+
+	return nil
+}
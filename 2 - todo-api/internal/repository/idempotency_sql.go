@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"gochallenges/internal/model"
+)
+
+type IdempotencySql struct {
+	sqlConn
+}
+
+func (r *IdempotencySql) FindIdempotencyKey(ctx context.Context, key string) (model.IdempotencyKey, error) {
+	idempotencyKey := model.IdempotencyKey{OwnerID: ownerOf(ctx), Key: key}
+
+	rows, err := r.conn().QueryContext(ctx, "SELECT fingerprint, response, created_at, expires_at FROM idempotency_key WHERE owner_id = ? AND idempotency_key = ? AND expires_at > ?", idempotencyKey.OwnerID, key, model.Now())
+	if err != nil {
+		return idempotencyKey, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return idempotencyKey, model.ErrScanningRows
+		}
+		return idempotencyKey, model.ErrIdempotencyKeyNotFound
+	}
+	var response []byte
+	if err := rows.Scan(&idempotencyKey.Fingerprint, &response, &idempotencyKey.CreatedAt, &idempotencyKey.ExpiresAt); err != nil {
+		return idempotencyKey, model.ErrScanningRows
+	}
+	if err := json.Unmarshal(response, &idempotencyKey.Task); err != nil {
+		return idempotencyKey, model.ErrScanningRows
+	}
+
+	return normalizeIdempotencyKey(idempotencyKey), nil
+}
+
+func (r *IdempotencySql) CreateIdempotencyKey(ctx context.Context, key model.IdempotencyKey) error {
+	key = normalizeIdempotencyKey(key)
+	key.OwnerID = ownerOf(ctx)
+	response, err := json.Marshal(key.Task)
+	if err != nil {
+		return model.ErrInsertingRow
+	}
+
+	if _, err := r.conn().ExecContext(ctx, "DELETE FROM idempotency_key WHERE owner_id = ? AND expires_at <= ?", key.OwnerID, model.Now()); err != nil {
+		return model.ErrExecuteQuery
+	}
+
+	insert := "INSERT INTO idempotency_key (owner_id, idempotency_key, fingerprint, response, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)"
+	if _, err := r.conn().ExecContext(ctx, insert, key.OwnerID, key.Key, key.Fingerprint, string(response), key.CreatedAt, key.ExpiresAt); err != nil {
+		if isDuplicateKeyError(err) {
+			return model.ErrIdempotencyKeyExists
+		}
+		return model.ErrInsertingRow
+	}
+
+	return nil
+}
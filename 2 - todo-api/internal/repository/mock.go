@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"context"
 	"gochallenges/internal/model"
+	"gochallenges/internal/query"
 	"reflect"
+	"time"
 
 	"github.com/golang/mock/gomock"
 )
@@ -26,82 +29,620 @@ func (m *TaskMock) EXPECT() *TaskMockMockRecorder {
 	return m.recorder
 }
 
-func (m *TaskMock) Create(task model.Task) (model.Task, error) {
+func (m *TaskMock) Create(ctx context.Context, task model.Task) (model.Task, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", task)
+	ret := m.ctrl.Call(m, "Create", ctx, task)
 	ret0, _ := ret[0].(model.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (mr *TaskMockMockRecorder) Create(task interface{}) *gomock.Call {
+func (mr *TaskMockMockRecorder) Create(ctx, task interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*TaskMock)(nil).Create), task)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*TaskMock)(nil).Create), ctx, task)
 }
 
-func (m *TaskMock) FindAll() ([]model.Task, error) {
+func (m *TaskMock) FindAll(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindAll")
+	ret := m.ctrl.Call(m, "FindAll", ctx, projectId, page)
+	ret0, _ := ret[0].(model.TaskPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *TaskMockMockRecorder) FindAll(ctx, projectId, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*TaskMock)(nil).FindAll), ctx, projectId, page)
+}
+
+func (m *TaskMock) FindByID(ctx context.Context, id int) (model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByID", ctx, id)
+	ret0, _ := ret[0].(model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *TaskMockMockRecorder) FindByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByID", reflect.TypeOf((*TaskMock)(nil).FindByID), ctx, id)
+}
+
+func (m *TaskMock) FindSubtree(ctx context.Context, id int, inTrash bool) ([]model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSubtree", ctx, id, inTrash)
 	ret0, _ := ret[0].([]model.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (mr *TaskMockMockRecorder) FindAll() *gomock.Call {
+func (mr *TaskMockMockRecorder) FindSubtree(ctx, id, inTrash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*TaskMock)(nil).FindAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSubtree", reflect.TypeOf((*TaskMock)(nil).FindSubtree), ctx, id, inTrash)
 }
 
-func (m *TaskMock) FindByID(id int) (model.Task, error) {
+func (m *TaskMock) FindByStatus(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindByID", id)
+	ret := m.ctrl.Call(m, "FindByStatus", ctx, projectId, completed, page)
+	ret0, _ := ret[0].(model.TaskPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *TaskMockMockRecorder) FindByStatus(ctx, projectId, completed, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByStatus", reflect.TypeOf((*TaskMock)(nil).FindByStatus), ctx, projectId, completed, page)
+}
+
+func (m *TaskMock) Find(ctx context.Context, q query.Query, page model.PageRequest) (model.TaskPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", ctx, q, page)
+	ret0, _ := ret[0].(model.TaskPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *TaskMockMockRecorder) Find(ctx, q, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*TaskMock)(nil).Find), ctx, q, page)
+}
+
+func (m *TaskMock) Update(ctx context.Context, task model.Task) (model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, task)
 	ret0, _ := ret[0].(model.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (mr *TaskMockMockRecorder) FindByID(id interface{}) *gomock.Call {
+func (mr *TaskMockMockRecorder) Update(ctx, task interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByID", reflect.TypeOf((*TaskMock)(nil).FindByID), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*TaskMock)(nil).Update), ctx, task)
 }
 
-func (m *TaskMock) FindByStatus(completed bool) ([]model.Task, error) {
+func (m *TaskMock) CreateMany(ctx context.Context, tasks []model.Task) ([]model.Task, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindByStatus", completed)
+	ret := m.ctrl.Call(m, "CreateMany", ctx, tasks)
 	ret0, _ := ret[0].([]model.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (mr *TaskMockMockRecorder) FindByStatus(completed interface{}) *gomock.Call {
+func (mr *TaskMockMockRecorder) CreateMany(ctx, tasks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMany", reflect.TypeOf((*TaskMock)(nil).CreateMany), ctx, tasks)
+}
+
+func (m *TaskMock) Delete(ctx context.Context, id int, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *TaskMockMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByStatus", reflect.TypeOf((*TaskMock)(nil).FindByStatus), completed)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*TaskMock)(nil).Delete), ctx, id, version)
 }
 
-func (m *TaskMock) Update(task model.Task) (model.Task, error) {
+func (m *TaskMock) FindTrash(ctx context.Context, page model.PageRequest) (model.TaskPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", task)
+	ret := m.ctrl.Call(m, "FindTrash", ctx, page)
+	ret0, _ := ret[0].(model.TaskPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *TaskMockMockRecorder) FindTrash(ctx, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTrash", reflect.TypeOf((*TaskMock)(nil).FindTrash), ctx, page)
+}
+
+func (m *TaskMock) Restore(ctx context.Context, id int) (model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
 	ret0, _ := ret[0].(model.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (mr *TaskMockMockRecorder) Update(task interface{}) *gomock.Call {
+func (mr *TaskMockMockRecorder) Restore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*TaskMock)(nil).Restore), ctx, id)
+}
+
+func (m *TaskMock) Purge(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *TaskMockMockRecorder) Purge(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*TaskMock)(nil).Purge), ctx, before)
+}
+
+func (m *TaskMock) FindProjectStats(ctx context.Context, id int, now time.Time) (model.ProjectStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindProjectStats", ctx, id, now)
+	ret0, _ := ret[0].(model.ProjectStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *TaskMockMockRecorder) FindProjectStats(ctx, id, now interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*TaskMock)(nil).Update), task)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindProjectStats", reflect.TypeOf((*TaskMock)(nil).FindProjectStats), ctx, id, now)
 }
 
-func (m *TaskMock) Delete(id int) error {
+func (m *TaskMock) Transaction(ctx context.Context, fn func(tx Store) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Transaction", ctx, fn)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (mr *TaskMockMockRecorder) Delete(id int) *gomock.Call {
+func (mr *TaskMockMockRecorder) Transaction(ctx, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*TaskMock)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*TaskMock)(nil).Transaction), ctx, fn)
 }
 
 func (m *TaskMock) Close() {
 }
+
+type StoreMock struct {
+	Tasks       *TaskMock
+	Idempotency *IdempotencyMock
+	Audit       *AuditMock
+	Webhooks    *WebhooksMock
+	Projects    *ProjectsMock
+}
+
+func NewStoreMock(ctrl *gomock.Controller) StoreMock {
+	return StoreMock{
+		Tasks:       NewTaskMock(ctrl),
+		Idempotency: NewIdempotencyMock(ctrl),
+		Audit:       NewAuditMock(ctrl),
+		Webhooks:    NewWebhooksMock(ctrl),
+		Projects:    NewProjectsMock(ctrl),
+	}
+}
+
+func (m StoreMock) Store() Store {
+	return Store{Tasks: m.Tasks, Idempotency: m.Idempotency, Audit: m.Audit, Webhooks: m.Webhooks, Projects: m.Projects}
+}
+
+type IdempotencyMock struct {
+	ctrl     *gomock.Controller
+	recorder *IdempotencyMockMockRecorder
+}
+
+type IdempotencyMockMockRecorder struct {
+	mock *IdempotencyMock
+}
+
+func NewIdempotencyMock(ctrl *gomock.Controller) *IdempotencyMock {
+	mock := &IdempotencyMock{ctrl: ctrl}
+	mock.recorder = &IdempotencyMockMockRecorder{mock}
+	return mock
+}
+
+func (m *IdempotencyMock) EXPECT() *IdempotencyMockMockRecorder {
+	return m.recorder
+}
+
+func (m *IdempotencyMock) FindIdempotencyKey(ctx context.Context, key string) (model.IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindIdempotencyKey", ctx, key)
+	ret0, _ := ret[0].(model.IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *IdempotencyMockMockRecorder) FindIdempotencyKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIdempotencyKey", reflect.TypeOf((*IdempotencyMock)(nil).FindIdempotencyKey), ctx, key)
+}
+
+func (m *IdempotencyMock) CreateIdempotencyKey(ctx context.Context, key model.IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdempotencyKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *IdempotencyMockMockRecorder) CreateIdempotencyKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdempotencyKey", reflect.TypeOf((*IdempotencyMock)(nil).CreateIdempotencyKey), ctx, key)
+}
+
+type AuditMock struct {
+	ctrl     *gomock.Controller
+	recorder *AuditMockMockRecorder
+}
+
+type AuditMockMockRecorder struct {
+	mock *AuditMock
+}
+
+func NewAuditMock(ctrl *gomock.Controller) *AuditMock {
+	mock := &AuditMock{ctrl: ctrl}
+	mock.recorder = &AuditMockMockRecorder{mock}
+	return mock
+}
+
+func (m *AuditMock) EXPECT() *AuditMockMockRecorder {
+	return m.recorder
+}
+
+func (m *AuditMock) CreateAuditRecord(ctx context.Context, record model.AuditRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditRecord", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *AuditMockMockRecorder) CreateAuditRecord(ctx, record interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditRecord", reflect.TypeOf((*AuditMock)(nil).CreateAuditRecord), ctx, record)
+}
+
+func (m *AuditMock) FindAuditRecords(ctx context.Context, filter model.AuditFilter, page model.PageRequest) (model.AuditPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAuditRecords", ctx, filter, page)
+	ret0, _ := ret[0].(model.AuditPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *AuditMockMockRecorder) FindAuditRecords(ctx, filter, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAuditRecords", reflect.TypeOf((*AuditMock)(nil).FindAuditRecords), ctx, filter, page)
+}
+
+type WebhooksMock struct {
+	ctrl     *gomock.Controller
+	recorder *WebhooksMockMockRecorder
+}
+
+type WebhooksMockMockRecorder struct {
+	mock *WebhooksMock
+}
+
+func NewWebhooksMock(ctrl *gomock.Controller) *WebhooksMock {
+	mock := &WebhooksMock{ctrl: ctrl}
+	mock.recorder = &WebhooksMockMockRecorder{mock}
+	return mock
+}
+
+func (m *WebhooksMock) EXPECT() *WebhooksMockMockRecorder {
+	return m.recorder
+}
+
+func (m *WebhooksMock) CreateWebhook(ctx context.Context, webhook model.Webhook) (model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", ctx, webhook)
+	ret0, _ := ret[0].(model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *WebhooksMockMockRecorder) CreateWebhook(ctx, webhook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*WebhooksMock)(nil).CreateWebhook), ctx, webhook)
+}
+
+func (m *WebhooksMock) FindWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindWebhooks", ctx)
+	ret0, _ := ret[0].([]model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *WebhooksMockMockRecorder) FindWebhooks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindWebhooks", reflect.TypeOf((*WebhooksMock)(nil).FindWebhooks), ctx)
+}
+
+func (m *WebhooksMock) DeleteWebhook(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *WebhooksMockMockRecorder) DeleteWebhook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*WebhooksMock)(nil).DeleteWebhook), ctx, id)
+}
+
+func (m *WebhooksMock) CreateDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeliveries", ctx, deliveries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *WebhooksMockMockRecorder) CreateDeliveries(ctx, deliveries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeliveries", reflect.TypeOf((*WebhooksMock)(nil).CreateDeliveries), ctx, deliveries)
+}
+
+func (m *WebhooksMock) FindDeliveries(ctx context.Context, filter model.DeliveryFilter, limit int) ([]model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeliveries", ctx, filter, limit)
+	ret0, _ := ret[0].([]model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *WebhooksMockMockRecorder) FindDeliveries(ctx, filter, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeliveries", reflect.TypeOf((*WebhooksMock)(nil).FindDeliveries), ctx, filter, limit)
+}
+
+func (m *WebhooksMock) ClaimDelivery(ctx context.Context, id int, attempts int, next time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDelivery", ctx, id, attempts, next)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *WebhooksMockMockRecorder) ClaimDelivery(ctx, id, attempts, next interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDelivery", reflect.TypeOf((*WebhooksMock)(nil).ClaimDelivery), ctx, id, attempts, next)
+}
+
+func (m *WebhooksMock) UpdateDelivery(ctx context.Context, delivery model.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *WebhooksMockMockRecorder) UpdateDelivery(ctx, delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*WebhooksMock)(nil).UpdateDelivery), ctx, delivery)
+}
+
+type ProjectsMock struct {
+	ctrl     *gomock.Controller
+	recorder *ProjectsMockMockRecorder
+}
+
+type ProjectsMockMockRecorder struct {
+	mock *ProjectsMock
+}
+
+func NewProjectsMock(ctrl *gomock.Controller) *ProjectsMock {
+	mock := &ProjectsMock{ctrl: ctrl}
+	mock.recorder = &ProjectsMockMockRecorder{mock}
+	return mock
+}
+
+func (m *ProjectsMock) EXPECT() *ProjectsMockMockRecorder {
+	return m.recorder
+}
+
+func (m *ProjectsMock) CreateProject(ctx context.Context, project model.Project) (model.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProject", ctx, project)
+	ret0, _ := ret[0].(model.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *ProjectsMockMockRecorder) CreateProject(ctx, project interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProject", reflect.TypeOf((*ProjectsMock)(nil).CreateProject), ctx, project)
+}
+
+func (m *ProjectsMock) FindProjects(ctx context.Context, archived bool) ([]model.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindProjects", ctx, archived)
+	ret0, _ := ret[0].([]model.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *ProjectsMockMockRecorder) FindProjects(ctx, archived interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindProjects", reflect.TypeOf((*ProjectsMock)(nil).FindProjects), ctx, archived)
+}
+
+func (m *ProjectsMock) FindProject(ctx context.Context, id int) (model.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindProject", ctx, id)
+	ret0, _ := ret[0].(model.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *ProjectsMockMockRecorder) FindProject(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindProject", reflect.TypeOf((*ProjectsMock)(nil).FindProject), ctx, id)
+}
+
+func (m *ProjectsMock) UpdateProject(ctx context.Context, project model.Project) (model.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProject", ctx, project)
+	ret0, _ := ret[0].(model.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *ProjectsMockMockRecorder) UpdateProject(ctx, project interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProject", reflect.TypeOf((*ProjectsMock)(nil).UpdateProject), ctx, project)
+}
+
+func (m *ProjectsMock) DeleteProject(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProject", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *ProjectsMockMockRecorder) DeleteProject(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProject", reflect.TypeOf((*ProjectsMock)(nil).DeleteProject), ctx, id)
+}
+
+type UserMock struct {
+	ctrl     *gomock.Controller
+	recorder *UserMockMockRecorder
+}
+
+type UserMockMockRecorder struct {
+	mock *UserMock
+}
+
+func NewUserMock(ctrl *gomock.Controller) *UserMock {
+	mock := &UserMock{ctrl: ctrl}
+	mock.recorder = &UserMockMockRecorder{mock}
+	return mock
+}
+
+func (m *UserMock) EXPECT() *UserMockMockRecorder {
+	return m.recorder
+}
+
+func (m *UserMock) Create(ctx context.Context, user model.User) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, user)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *UserMockMockRecorder) Create(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*UserMock)(nil).Create), ctx, user)
+}
+
+func (m *UserMock) FindById(ctx context.Context, id int) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *UserMockMockRecorder) FindById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*UserMock)(nil).FindById), ctx, id)
+}
+
+func (m *UserMock) FindByName(ctx context.Context, name string) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByName", ctx, name)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *UserMockMockRecorder) FindByName(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByName", reflect.TypeOf((*UserMock)(nil).FindByName), ctx, name)
+}
+
+func (m *UserMock) FindByToken(ctx context.Context, hash string) (model.User, model.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByToken", ctx, hash)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(model.Token)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (mr *UserMockMockRecorder) FindByToken(ctx, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByToken", reflect.TypeOf((*UserMock)(nil).FindByToken), ctx, hash)
+}
+
+func (m *UserMock) CreateToken(ctx context.Context, token model.Token) (model.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, token)
+	ret0, _ := ret[0].(model.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *UserMockMockRecorder) CreateToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*UserMock)(nil).CreateToken), ctx, token)
+}
+
+func (m *UserMock) TouchToken(ctx context.Context, tokenId int, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchToken", ctx, tokenId, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *UserMockMockRecorder) TouchToken(ctx, tokenId, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchToken", reflect.TypeOf((*UserMock)(nil).TouchToken), ctx, tokenId, usedAt)
+}
+
+func (m *UserMock) FindToken(ctx context.Context, userId int, tokenId int) (model.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindToken", ctx, userId, tokenId)
+	ret0, _ := ret[0].(model.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *UserMockMockRecorder) FindToken(ctx, userId, tokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindToken", reflect.TypeOf((*UserMock)(nil).FindToken), ctx, userId, tokenId)
+}
+
+func (m *UserMock) FindTokens(ctx context.Context, userId int) ([]model.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTokens", ctx, userId)
+	ret0, _ := ret[0].([]model.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *UserMockMockRecorder) FindTokens(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTokens", reflect.TypeOf((*UserMock)(nil).FindTokens), ctx, userId)
+}
+
+func (m *UserMock) DeleteToken(ctx context.Context, userId int, tokenId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteToken", ctx, userId, tokenId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *UserMockMockRecorder) DeleteToken(ctx, userId, tokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteToken", reflect.TypeOf((*UserMock)(nil).DeleteToken), ctx, userId, tokenId)
+}
@@ -1,7 +1,12 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"gochallenges/internal/migration"
 	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -12,71 +17,228 @@ type TaskOrm struct {
 }
 
 func NewTaskOrm(connStr string) (Task, error) {
-	db, err := gorm.Open(mysql.Open(connStr), &gorm.Config{})
+	return NewTaskOrmWithDialector(mysql.Open(connStr))
+}
+
+func NewTaskOrmWithDialector(dialector gorm.Dialector) (Task, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{NowFunc: model.Now})
 	if err != nil {
-		panic(model.ErrConnectDatabase)
+		return nil, model.ErrConnectDatabase
 	}
 
-	db.AutoMigrate(&TaskOrm{})
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, model.ErrConnectDatabase
+	}
+	dialect, err := migration.DialectFor(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	if err = migrate(sqlDB, dialect); err != nil {
+		return nil, err
+	}
 
 	return &TaskOrm{db}, nil
 }
 
-func (r *TaskOrm) Create(task model.Task) (model.Task, error) {
-	newTask := model.Task{ID: task.ID, Name: task.Name, Completed: task.Completed}
-	if err := r.db.Create(&newTask).Error; err != nil {
-		return newTask, model.ErrInsertingRow
+func (r *TaskOrm) Create(ctx context.Context, task model.Task) (model.Task, error) {
+	newTask := normalizeTask(task)
+	newTask.CreatedAt = model.Now()
+	newTask.UpdatedAt = newTask.CreatedAt
+	newTask.Version = 1
+	newTask.OwnerID = ownerOf(ctx)
+	newTask.DeletedAt = nil
+	if err := r.db.WithContext(ctx).Create(&newTask).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return task, model.ErrTaskAlreadyExists
+		}
+		return task, model.ErrInsertingRow
 	}
 
 	return newTask, nil
 }
 
-func (r *TaskOrm) FindByID(id int) (model.Task, error) {
+// CreateMany inserts row by row, so each task gets the id of its own insert.
+func (r *TaskOrm) CreateMany(ctx context.Context, tasks []model.Task) ([]model.Task, error) {
+	return createEach(ctx, r, tasks)
+}
+
+func (r *TaskOrm) FindByID(ctx context.Context, id int) (model.Task, error) {
 	var task model.Task
-	if err := r.db.First(&task, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Clauses(ormWhereClause(taskMatch(ctx, id, 0))).First(&task).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return task, model.ErrTaskNotFound
+		}
 		return task, model.ErrExecuteQuery
 	}
 
-	return task, nil
+	return normalizeTask(task), nil
 }
 
-func (r *TaskOrm) FindByStatus(completed bool) ([]model.Task, error) {
-	var tasks []model.Task
-	if err := r.db.Where("Completed = ?", completed).Find(&tasks).Error; err != nil {
-		return nil, model.ErrExecuteQuery
+func (r *TaskOrm) FindByStatus(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+	return r.Find(ctx, query.Query{Filter: inProject(projectId, query.Comparison{Field: "completed", Op: query.Eq, Value: completed})}, page)
+}
+
+func (r *TaskOrm) FindAll(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+	return r.Find(ctx, query.Query{Filter: inProject(projectId, nil)}, page)
+}
+
+func (r *TaskOrm) Find(ctx context.Context, q query.Query, page model.PageRequest) (model.TaskPage, error) {
+	return r.find(ctx, query.AndAlso(q.Filter, trashed(false)), pageOrder(q.OrderBy), page)
+}
+
+func (r *TaskOrm) FindTrash(ctx context.Context, page model.PageRequest) (model.TaskPage, error) {
+	return r.find(ctx, trashed(true), trashOrder, page)
+}
+
+func (r *TaskOrm) find(ctx context.Context, filter query.Expr, orders []query.Order, page model.PageRequest) (model.TaskPage, error) {
+	limit, after, err := parsePage(page, orders)
+	if err != nil {
+		return model.TaskPage{}, err
 	}
 
-	return tasks, nil
+	db := r.db.WithContext(ctx).Clauses(ormOrderBy(orders), ormWhereClause(ownerFilter(ctx, query.AndAlso(filter, after)))).Limit(limit + 1)
+
+	tasks := []model.Task{}
+	if err := db.Find(&tasks).Error; err != nil {
+		return model.TaskPage{}, model.ErrExecuteQuery
+	}
+	for i := range tasks {
+		tasks[i] = normalizeTask(tasks[i])
+	}
+
+	return newTaskPage(tasks, limit, orders), nil
 }
 
-func (r *TaskOrm) FindAll() ([]model.Task, error) {
-	var tasks []model.Task
-	if err := r.db.Find(&tasks).Error; err != nil {
+func (r *TaskOrm) FindSubtree(ctx context.Context, id int, inTrash bool) ([]model.Task, error) {
+	statement, args := subtreeQuery(ctx, id, inTrash)
+
+	tasks := []model.Task{}
+	if err := r.db.WithContext(ctx).Where("id IN ("+statement+")", args...).Order("id").Find(&tasks).Error; err != nil {
 		return nil, model.ErrExecuteQuery
 	}
+	for i := range tasks {
+		tasks[i] = normalizeTask(tasks[i])
+	}
 
 	return tasks, nil
 }
 
-func (r *TaskOrm) Update(task model.Task) (model.Task, error) {
-	var taskOrm model.Task
-	if err := r.db.First(&taskOrm, task.ID).Error; err != nil {
-		return taskOrm, model.ErrExecuteQuery
+func (r *TaskOrm) Update(ctx context.Context, task model.Task) (model.Task, error) {
+	task = normalizeTask(task)
+
+	db := r.db.WithContext(ctx).Model(&model.Task{}).Clauses(ormWhereClause(taskMatch(ctx, task.ID, task.Version)))
+	result := db.Updates(map[string]any{
+		"parent_id":    task.ParentID,
+		"project_id":   task.ProjectID,
+		"name":         task.Name,
+		"completed":    task.Completed,
+		"description":  task.Description,
+		"due_at":       task.DueAt,
+		"priority":     task.Priority,
+		"updated_at":   model.Now(),
+		"completed_at": task.CompletedAt,
+		"version":      gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return model.Task{}, model.ErrExecuteQuery
 	}
 
-	taskOrm.Name = task.Name
-	taskOrm.Completed = task.Completed
-	r.db.Save(&taskOrm)
+	updatedTask, err := r.FindByID(ctx, task.ID)
+	if err != nil {
+		return updatedTask, err
+	}
+	if result.RowsAffected == 0 {
+		return updatedTask, model.ErrTaskVersionMismatch
+	}
 
-	return taskOrm, nil
+	return updatedTask, nil
 }
 
-func (r *TaskOrm) Delete(id int) error {
-	if err := r.db.Delete(&model.Task{}, id).Error; err != nil {
+func (r *TaskOrm) Delete(ctx context.Context, id int, version int) error {
+	now := model.Now()
+	result := r.db.WithContext(ctx).Model(&model.Task{}).Clauses(ormWhereClause(taskMatch(ctx, id, version))).Updates(map[string]any{
+		"deleted_at": now,
+		"updated_at": now,
+		"version":    gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
 		return model.ErrExecuteQuery
 	}
+
+	if result.RowsAffected == 0 && version > 0 {
+		if _, err := r.FindByID(ctx, id); err == nil {
+			return model.ErrTaskVersionMismatch
+		} else if !errors.Is(err, model.ErrTaskNotFound) {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (r *TaskOrm) Restore(ctx context.Context, id int) (model.Task, error) {
+	match := ownerFilter(ctx, query.AndAlso(query.Comparison{Field: "id", Op: query.Eq, Value: id}, trashed(true)))
+	result := r.db.WithContext(ctx).Model(&model.Task{}).Clauses(ormWhereClause(match)).Updates(map[string]any{
+		"deleted_at": nil,
+		"updated_at": model.Now(),
+		"version":    gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return model.Task{}, model.ErrExecuteQuery
+	}
+	if result.RowsAffected == 0 {
+		return model.Task{}, model.ErrTaskNotFound
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+func (r *TaskOrm) Purge(ctx context.Context, before time.Time) (int, error) {
+	match := ownerFilter(ctx, query.Comparison{Field: "deleted_at", Op: query.Lt, Value: normalizeTime(before)})
+	result := r.db.WithContext(ctx).Clauses(ormWhereClause(match)).Delete(&model.Task{})
+	if result.Error != nil {
+		return 0, model.ErrExecuteQuery
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+func (r *TaskOrm) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return ormTransaction(ctx, r.db, func(tx *gorm.DB) error {
+		return fn(ormStore(&TaskOrm{tx}, tx))
+	})
+}
+
+// ormTransaction returns the error of fn as it is; nested, it is a savepoint.
+func ormTransaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	var fnErr error
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fnErr = fn(tx)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return model.ErrExecuteQuery
+	}
+
+	return nil
+}
+
+func ormStore(tasks Task, db *gorm.DB) Store {
+	return Store{
+		Tasks:       tasks,
+		Idempotency: &IdempotencyOrm{db},
+		Audit:       &AuditOrm{db},
+		Webhooks:    &WebhooksOrm{db},
+		Projects:    &ProjectsOrm{db},
+	}
+}
+
 func (r *TaskOrm) Close() {
+	if db, err := r.db.DB(); err == nil {
+		db.Close()
+	}
 }
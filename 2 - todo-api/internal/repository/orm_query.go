@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"gochallenges/internal/query"
+
+	"gorm.io/gorm/clause"
+)
+
+func ormWhereClause(expr query.Expr) clause.Where {
+	return clause.Where{Exprs: []clause.Expression{ormWhere(expr)}}
+}
+
+func ormWhere(expr query.Expr) clause.Expression {
+	switch e := expr.(type) {
+	case query.Comparison:
+		column := clause.Column{Name: e.Field}
+		switch e.Op {
+		case query.Eq:
+			return clause.Eq{Column: column, Value: e.Value}
+		case query.Ne:
+			return clause.Neq{Column: column, Value: e.Value}
+		case query.Lt:
+			return clause.Lt{Column: column, Value: e.Value}
+		case query.Le:
+			return clause.Lte{Column: column, Value: e.Value}
+		case query.Gt:
+			return clause.Gt{Column: column, Value: e.Value}
+		case query.Ge:
+			return clause.Gte{Column: column, Value: e.Value}
+		case query.Contains:
+			return clause.Expr{SQL: "? LIKE ? ESCAPE '" + likeEscape + "'", Vars: []any{column, containsPattern(e.Value)}}
+		}
+	case query.And:
+		return clause.And(ormWhere(e.Left), ormWhere(e.Right))
+	case query.Or:
+		return clause.Or(ormWhere(e.Left), ormWhere(e.Right))
+	case query.Not:
+		return clause.Not(ormWhere(e.Expr))
+	}
+	panic("unknown filter expression")
+}
+
+func ormOrderBy(orders []query.Order) clause.OrderBy {
+	var orderBy clause.OrderBy
+	for _, order := range orders {
+		orderBy.Columns = append(orderBy.Columns, clause.OrderByColumn{Column: clause.Column{Name: order.Field}, Desc: order.Desc})
+	}
+	return orderBy
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+)
+
+// pageCursor holds the sort values of the last task returned and their ordering.
+type pageCursor struct {
+	OrderBy string `json:"order_by"`
+	Values  []any  `json:"values"`
+}
+
+// pageOrder breaks ties on id, so every task has one position to resume after.
+func pageOrder(orders []query.Order) []query.Order {
+	for i, order := range orders {
+		if order.Field == "id" {
+			return orders[:i+1]
+		}
+	}
+	return append(append([]query.Order{}, orders...), query.Order{Field: "id"})
+}
+
+func parsePage(page model.PageRequest, orders []query.Order) (int, query.Expr, error) {
+	limit := page.Size
+	switch {
+	case limit < 0:
+		return 0, nil, model.ErrInvalidPageSize
+	case limit == 0:
+		limit = model.DefaultPageSize
+	case limit > model.MaxPageSize:
+		limit = model.MaxPageSize
+	}
+
+	if page.Token == "" {
+		return limit, nil, nil
+	}
+
+	var cursor pageCursor
+	raw, err := base64.RawURLEncoding.DecodeString(page.Token)
+	if err != nil {
+		return 0, nil, model.ErrInvalidPageToken
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return 0, nil, model.ErrInvalidPageToken
+	}
+	if cursor.OrderBy != query.FormatOrderBy(orders) || len(cursor.Values) != len(orders) {
+		return 0, nil, model.ErrInvalidPageToken
+	}
+
+	values := make([]any, len(orders))
+	for i, order := range orders {
+		value, ok := query.Fields[order.Field].Coerce(cursor.Values[i])
+		if !ok {
+			return 0, nil, model.ErrInvalidPageToken
+		}
+		values[i] = value
+	}
+
+	return limit, afterCursor(orders, values), nil
+}
+
+// afterCursor builds (a > x) OR (a = x AND b > y) OR ..., with NULLs sorting first.
+func afterCursor(orders []query.Order, values []any) query.Expr {
+	var after query.Expr
+	for i := len(orders) - 1; i >= 0; i-- {
+		field, value := orders[i].Field, values[i]
+		isNull := query.Comparison{Field: field, Op: query.Eq, Value: nil}
+		tie := query.Comparison{Field: field, Op: query.Eq, Value: value}
+
+		var expr query.Expr
+		switch {
+		case value == nil && orders[i].Desc:
+			expr = query.And{Left: isNull, Right: after}
+		case value == nil:
+			expr = query.Or{Left: query.Not{Expr: isNull}, Right: query.And{Left: isNull, Right: after}}
+		default:
+			op := query.Gt
+			if orders[i].Desc {
+				op = query.Lt
+			}
+			expr = query.Comparison{Field: field, Op: op, Value: value}
+			if orders[i].Desc && query.Fields[field].Nullable {
+				expr = query.Or{Left: expr, Right: isNull}
+			}
+			if after != nil {
+				expr = query.Or{Left: expr, Right: query.And{Left: tie, Right: after}}
+			}
+		}
+		after = expr
+	}
+	return after
+}
+
+// newTaskPage takes up to limit+1 tasks; the extra one only signals another page.
+func newTaskPage(tasks []model.Task, limit int, orders []query.Order) model.TaskPage {
+	if len(tasks) <= limit {
+		return model.TaskPage{Tasks: tasks}
+	}
+
+	tasks = tasks[:limit]
+	last := tasks[limit-1]
+
+	cursor := pageCursor{OrderBy: query.FormatOrderBy(orders)}
+	for _, order := range orders {
+		cursor.Values = append(cursor.Values, query.Fields[order.Field].Value(last))
+	}
+	raw, _ := json.Marshal(cursor)
+
+	return model.TaskPage{
+		Tasks:         tasks,
+		NextPageToken: base64.RawURLEncoding.EncodeToString(raw),
+	}
+}
+
+func newAuditPage(records []model.AuditRecord, limit int) model.AuditPage {
+	if len(records) <= limit {
+		return model.AuditPage{Records: records}
+	}
+
+	records = records[:limit]
+	cursor := pageCursor{OrderBy: query.FormatOrderBy(auditOrder), Values: []any{records[limit-1].ID}}
+	raw, _ := json.Marshal(cursor)
+
+	return model.AuditPage{
+		Records:       records,
+		NextPageToken: base64.RawURLEncoding.EncodeToString(raw),
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ProjectsOrm struct {
+	db *gorm.DB
+}
+
+func (r *ProjectsOrm) CreateProject(ctx context.Context, project model.Project) (model.Project, error) {
+	project = normalizeProject(project)
+	project.OwnerID = ownerOf(ctx)
+
+	if err := r.db.WithContext(ctx).Create(&project).Error; err != nil {
+		return project, model.ErrInsertingRow
+	}
+
+	return project, nil
+}
+
+func (r *ProjectsOrm) FindProjects(ctx context.Context, archived bool) ([]model.Project, error) {
+	return r.findProjects(ctx, ownerFilter(ctx, archivedProjects(archived)))
+}
+
+func (r *ProjectsOrm) FindProject(ctx context.Context, id int) (model.Project, error) {
+	projects, err := r.findProjects(ctx, projectMatch(ctx, id))
+	if err != nil {
+		return model.Project{}, err
+	}
+	if len(projects) == 0 {
+		return model.Project{}, model.ErrProjectNotFound
+	}
+	return projects[0], nil
+}
+
+func (r *ProjectsOrm) findProjects(ctx context.Context, filter query.Expr) ([]model.Project, error) {
+	var projects []model.Project
+	if err := r.db.WithContext(ctx).Clauses(ormWhereClause(filter)).Order("id").Find(&projects).Error; err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+
+	for i := range projects {
+		projects[i] = normalizeProject(projects[i])
+	}
+	return projects, nil
+}
+
+func (r *ProjectsOrm) UpdateProject(ctx context.Context, project model.Project) (model.Project, error) {
+	project = normalizeProject(project)
+	result := r.db.WithContext(ctx).Model(&model.Project{}).Clauses(ormWhereClause(projectMatch(ctx, project.ID))).Updates(map[string]any{
+		"name":        project.Name,
+		"description": project.Description,
+		"updated_at":  project.UpdatedAt,
+		"archived_at": project.ArchivedAt,
+	})
+	if result.Error != nil {
+		return project, model.ErrExecuteQuery
+	}
+	if result.RowsAffected == 0 {
+		return project, model.ErrProjectNotFound
+	}
+
+	return r.FindProject(ctx, project.ID)
+}
+
+func (r *ProjectsOrm) DeleteProject(ctx context.Context, id int) error {
+	result := r.db.WithContext(ctx).Clauses(ormWhereClause(projectMatch(ctx, id))).Delete(&model.Project{})
+	if result.Error != nil {
+		return model.ErrExecuteQuery
+	}
+	if result.RowsAffected == 0 {
+		return model.ErrProjectNotFound
+	}
+
+	return nil
+}
+
+func (r *TaskOrm) FindProjectStats(ctx context.Context, id int, now time.Time) (model.ProjectStats, error) {
+	var counts struct {
+		Total     int
+		Completed int
+		Overdue   int
+	}
+	err := r.db.WithContext(ctx).Model(&model.Task{}).
+		Select("COUNT(*) AS total, "+
+			"COALESCE(SUM(CASE WHEN completed THEN 1 ELSE 0 END), 0) AS completed, "+
+			"COALESCE(SUM(CASE WHEN NOT completed AND due_at < ? THEN 1 ELSE 0 END), 0) AS overdue", normalizeTime(now)).
+		Clauses(ormWhereClause(ownerFilter(ctx, inProject(id, trashed(false))))).
+		Scan(&counts).Error
+	if err != nil {
+		return model.ProjectStats{}, model.ErrExecuteQuery
+	}
+
+	return countProjectStats(model.ProjectStats{ProjectID: id, Total: counts.Total, Completed: counts.Completed, Overdue: counts.Overdue}), nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"time"
+)
+
+const projectColumns = "id, owner_id, name, description, created_at, updated_at, archived_at"
+
+type ProjectsSql struct {
+	sqlConn
+}
+
+func (r *ProjectsSql) CreateProject(ctx context.Context, project model.Project) (model.Project, error) {
+	project = normalizeProject(project)
+	project.OwnerID = ownerOf(ctx)
+
+	insert := "INSERT INTO project (owner_id, name, description, created_at, updated_at, archived_at) VALUES (?, ?, ?, ?, ?, ?)"
+	result, err := r.conn().ExecContext(ctx, insert, project.OwnerID, project.Name, project.Description, project.CreatedAt, project.UpdatedAt, project.ArchivedAt)
+	if err != nil {
+		return project, model.ErrInsertingRow
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return project, model.ErrExecuteQuery
+	}
+
+	project.ID = int(id)
+	return project, nil
+}
+
+func (r *ProjectsSql) FindProjects(ctx context.Context, archived bool) ([]model.Project, error) {
+	return r.findProjects(ctx, ownerFilter(ctx, archivedProjects(archived)))
+}
+
+func (r *ProjectsSql) FindProject(ctx context.Context, id int) (model.Project, error) {
+	projects, err := r.findProjects(ctx, projectMatch(ctx, id))
+	if err != nil {
+		return model.Project{}, err
+	}
+	if len(projects) == 0 {
+		return model.Project{}, model.ErrProjectNotFound
+	}
+	return projects[0], nil
+}
+
+func (r *ProjectsSql) findProjects(ctx context.Context, filter query.Expr) ([]model.Project, error) {
+	where, args := sqlWhere(filter)
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+projectColumns+" FROM project WHERE "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	projects := []model.Project{}
+	for rows.Next() {
+		var project model.Project
+		var archivedAt sql.NullTime
+		if err := rows.Scan(&project.ID, &project.OwnerID, &project.Name, &project.Description, &project.CreatedAt, &project.UpdatedAt, &archivedAt); err != nil {
+			return nil, model.ErrScanningRows
+		}
+		if archivedAt.Valid {
+			project.ArchivedAt = &archivedAt.Time
+		}
+		projects = append(projects, normalizeProject(project))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, model.ErrScanningRows
+	}
+
+	return projects, nil
+}
+
+func (r *ProjectsSql) UpdateProject(ctx context.Context, project model.Project) (model.Project, error) {
+	project = normalizeProject(project)
+	where, args := sqlWhere(projectMatch(ctx, project.ID))
+	update := "UPDATE project SET name = ?, description = ?, updated_at = ?, archived_at = ? WHERE " + where
+	affected, err := r.exec(ctx, update, append([]any{project.Name, project.Description, project.UpdatedAt, project.ArchivedAt}, args...)...)
+	if err != nil {
+		return project, err
+	}
+	if affected == 0 {
+		return project, model.ErrProjectNotFound
+	}
+
+	return r.FindProject(ctx, project.ID)
+}
+
+func (r *ProjectsSql) DeleteProject(ctx context.Context, id int) error {
+	where, args := sqlWhere(projectMatch(ctx, id))
+	affected, err := r.exec(ctx, "DELETE FROM project WHERE "+where, args...)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.ErrProjectNotFound
+	}
+
+	return nil
+}
+
+func (r *TaskSql) FindProjectStats(ctx context.Context, id int, now time.Time) (model.ProjectStats, error) {
+	stats := model.ProjectStats{ProjectID: id}
+	where, args := sqlWhere(ownerFilter(ctx, inProject(id, trashed(false))))
+	statement := "SELECT COUNT(*), " +
+		"COALESCE(SUM(CASE WHEN completed THEN 1 ELSE 0 END), 0), " +
+		"COALESCE(SUM(CASE WHEN NOT completed AND due_at < ? THEN 1 ELSE 0 END), 0) " +
+		"FROM task WHERE " + where
+
+	rows, err := r.conn().QueryContext(ctx, statement, append([]any{normalizeTime(now)}, args...)...)
+	if err != nil {
+		return stats, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return stats, model.ErrScanningRows
+	}
+	if err := rows.Scan(&stats.Total, &stats.Completed, &stats.Overdue); err != nil {
+		return stats, model.ErrScanningRows
+	}
+
+	return countProjectStats(stats), nil
+}
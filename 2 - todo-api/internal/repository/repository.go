@@ -1,19 +1,440 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"gochallenges/internal/migration"
 	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"gochallenges/pkg"
+	"strings"
+	"time"
 )
 
 const (
-	DbVanilla, DbOrm = "vanilla", "orm"
+	DbVanilla, DbOrm, DbSqlite, DbEvents = "vanilla", "orm", "sqlite", "events"
 )
 
+const mysqlDriver = "mysql"
+
 type Task interface {
-	Create(task model.Task) (model.Task, error)
-	FindByID(id int) (model.Task, error)
-	FindByStatus(completed bool) ([]model.Task, error)
-	FindAll() ([]model.Task, error)
-	Update(task model.Task) (model.Task, error)
-	Delete(id int) error
+	Create(ctx context.Context, task model.Task) (model.Task, error)
+	// CreateMany stores every task or none, and returns them in the same order.
+	CreateMany(ctx context.Context, tasks []model.Task) ([]model.Task, error)
+	FindByID(ctx context.Context, id int) (model.Task, error)
+	FindByStatus(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error)
+	FindAll(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error)
+	Find(ctx context.Context, q query.Query, page model.PageRequest) (model.TaskPage, error)
+	// FindSubtree only follows the tasks in the trash, or those out of it.
+	FindSubtree(ctx context.Context, id int, inTrash bool) ([]model.Task, error)
+	Update(ctx context.Context, task model.Task) (model.Task, error)
+	Delete(ctx context.Context, id int, version int) error
+	FindTrash(ctx context.Context, page model.PageRequest) (model.TaskPage, error)
+	Restore(ctx context.Context, id int) (model.Task, error)
+	Purge(ctx context.Context, before time.Time) (int, error)
+	FindProjectStats(ctx context.Context, id int, now time.Time) (model.ProjectStats, error)
+	// Transaction runs fn with a store sharing one transaction; nested, it is a savepoint.
+	Transaction(ctx context.Context, fn func(tx Store) error) error
 	Close()
 }
+
+type Idempotency interface {
+	FindIdempotencyKey(ctx context.Context, key string) (model.IdempotencyKey, error)
+	// CreateIdempotencyKey drops the user's expired keys first.
+	CreateIdempotencyKey(ctx context.Context, key model.IdempotencyKey) error
+}
+
+type Audit interface {
+	// CreateAuditRecord appends record, made by the user in ctx.
+	CreateAuditRecord(ctx context.Context, record model.AuditRecord) error
+	// FindAuditRecords pages through the records visible to ctx, or all with AllOwners.
+	FindAuditRecords(ctx context.Context, filter model.AuditFilter, page model.PageRequest) (model.AuditPage, error)
+}
+
+type Webhooks interface {
+	CreateWebhook(ctx context.Context, webhook model.Webhook) (model.Webhook, error)
+	FindWebhooks(ctx context.Context) ([]model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	CreateDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error
+	FindDeliveries(ctx context.Context, filter model.DeliveryFilter, limit int) ([]model.WebhookDelivery, error)
+	// ClaimDelivery counts an attempt and returns false when another dispatcher got it first.
+	ClaimDelivery(ctx context.Context, id int, attempts int, next time.Time) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery model.WebhookDelivery) error
+}
+
+type Projects interface {
+	CreateProject(ctx context.Context, project model.Project) (model.Project, error)
+	FindProjects(ctx context.Context, archived bool) ([]model.Project, error)
+	FindProject(ctx context.Context, id int) (model.Project, error)
+	UpdateProject(ctx context.Context, project model.Project) (model.Project, error)
+	// DeleteProject leaves the tasks of the project as they are.
+	DeleteProject(ctx context.Context, id int) error
+}
+
+type Store struct {
+	Tasks       Task
+	Idempotency Idempotency
+	Audit       Audit
+	Webhooks    Webhooks
+	Projects    Projects
+}
+
+func NewStore(tasks Task) Store {
+	switch r := tasks.(type) {
+	case *TaskSql:
+		return sqlStore(r, r.sqlConn)
+	case *TaskSqlite:
+		return sqlStore(r, r.sqlConn)
+	case *TaskOrm:
+		return ormStore(r, r.db)
+	case *TaskEvents:
+		return sqlStore(r, r.store.sqlConn)
+	}
+	return Store{Tasks: tasks}
+}
+
+type User interface {
+	Create(ctx context.Context, user model.User) (model.User, error)
+	FindById(ctx context.Context, id int) (model.User, error)
+	FindByName(ctx context.Context, name string) (model.User, error)
+	FindByToken(ctx context.Context, hash string) (model.User, model.Token, error)
+	CreateToken(ctx context.Context, token model.Token) (model.Token, error)
+	TouchToken(ctx context.Context, tokenId int, usedAt time.Time) error
+	FindToken(ctx context.Context, userId int, tokenId int) (model.Token, error)
+	FindTokens(ctx context.Context, userId int) ([]model.Token, error)
+	DeleteToken(ctx context.Context, userId int, tokenId int) error
+}
+
+// Open returns repositories sharing one pool; closing the task repository closes it.
+func Open(dbImpl string, dbConfig pkg.DbConfig) (Store, User, error) {
+	var tasks Task
+	var err error
+	switch dbImpl {
+	case DbVanilla:
+		tasks, err = NewTaskSql(dbConfig.Driver, pkg.GetMysqlDbConnection(dbConfig))
+	case DbOrm:
+		tasks, err = NewTaskOrm(pkg.GetMysqlDbConnection(dbConfig))
+	case DbSqlite:
+		tasks, err = NewTaskSqlite(pkg.GetSqliteDbConnection(dbConfig))
+	case DbEvents:
+		// The log is kept in sqlite, whatever DB_DRIVER the sql backend uses.
+		if dialect, _ := migration.DialectFor(dbConfig.Driver); dbConfig.Driver != "" && dialect != migration.SQLite {
+			err = fmt.Errorf("%w: %s runs on sqlite only, not %s", model.ErrInvalidDbImplementation, DbEvents, dbConfig.Driver)
+			break
+		}
+		tasks, err = NewTaskEvents(pkg.GetSqliteDbConnection(dbConfig))
+	default:
+		err = model.ErrInvalidDbImplementation
+	}
+	if err != nil {
+		return Store{}, nil, err
+	}
+
+	var users User
+	switch r := tasks.(type) {
+	case *TaskSql:
+		users = NewUserSql(r.DB)
+	case *TaskSqlite:
+		users = NewUserSql(r.DB)
+	case *TaskOrm:
+		users = NewUserOrm(r.db)
+	case *TaskEvents:
+		users = NewUserSql(r.store.DB)
+	}
+	return NewStore(tasks), users, nil
+}
+
+func OpenDatabase(dbImpl string, dbConfig pkg.DbConfig) (*sql.DB, migration.Dialect, error) {
+	driverName, connStr := mysqlDriver, pkg.GetMysqlDbConnection(dbConfig)
+	switch dbImpl {
+	case DbVanilla:
+		driverName = dbConfig.Driver
+	case DbOrm:
+	case DbSqlite, DbEvents:
+		driverName, connStr = sqliteDriver, pkg.GetSqliteDbConnection(dbConfig)
+	default:
+		return nil, "", model.ErrInvalidDbImplementation
+	}
+
+	dialect, err := migration.DialectFor(driverName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	db, err := sql.Open(driverName, connStr)
+	if err != nil {
+		return nil, "", model.ErrConnectDatabase
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, "", model.ErrConnectDatabase
+	}
+
+	return db, dialect, nil
+}
+
+func migrate(db *sql.DB, dialect migration.Dialect) error {
+	migrator, err := migration.New(db, dialect)
+	if err != nil {
+		return err
+	}
+	return migrator.Up(context.Background())
+}
+
+// ownerFilter narrows expr to the user in ctx; without one every task is visible.
+func ownerFilter(ctx context.Context, expr query.Expr) query.Expr {
+	if user, ok := model.UserFromContext(ctx); ok {
+		return query.AndAlso(expr, query.Comparison{Field: "owner_id", Op: query.Eq, Value: user.ID})
+	}
+	return expr
+}
+
+func inProject(id int, expr query.Expr) query.Expr {
+	if id == 0 {
+		return expr
+	}
+	return query.AndAlso(query.Comparison{Field: "project_id", Op: query.Eq, Value: id}, expr)
+}
+
+func taskMatch(ctx context.Context, id int, version int) query.Expr {
+	var expr query.Expr = query.Comparison{Field: "id", Op: query.Eq, Value: id}
+	if version > 0 {
+		expr = query.AndAlso(expr, query.Comparison{Field: "version", Op: query.Eq, Value: version})
+	}
+	return ownerFilter(ctx, query.AndAlso(expr, trashed(false)))
+}
+
+// deleted_at is not in query.Fields, so clients cannot filter on it.
+func trashed(in bool) query.Expr {
+	if in {
+		return query.Comparison{Field: "deleted_at", Op: query.Ne, Value: nil}
+	}
+	return query.Comparison{Field: "deleted_at", Op: query.Eq, Value: nil}
+}
+
+// trashOrder sorts on updated_at, which deleting sets and page tokens can hold.
+var trashOrder = []query.Order{{Field: "updated_at", Desc: true}, {Field: "id", Desc: true}}
+
+func ownerOf(ctx context.Context) int {
+	if user, ok := model.UserFromContext(ctx); ok {
+		return user.ID
+	}
+	return model.DefaultUserID
+}
+
+// actorOf is the user in ctx who makes a change, or 0 without one.
+func actorOf(ctx context.Context) int {
+	if user, ok := model.UserFromContext(ctx); ok {
+		return user.ID
+	}
+	return 0
+}
+
+// normalizeTask keeps timestamps in UTC to the second, as every backend can store them.
+func normalizeTask(task model.Task) model.Task {
+	task.CreatedAt = normalizeTime(task.CreatedAt)
+	task.UpdatedAt = normalizeTime(task.UpdatedAt)
+	if task.DueAt != nil {
+		dueAt := normalizeTime(*task.DueAt)
+		task.DueAt = &dueAt
+	}
+	if task.CompletedAt != nil {
+		completedAt := normalizeTime(*task.CompletedAt)
+		task.CompletedAt = &completedAt
+	}
+	if task.DeletedAt != nil {
+		deletedAt := normalizeTime(*task.DeletedAt)
+		task.DeletedAt = &deletedAt
+	}
+	return task
+}
+
+func normalizeIdempotencyKey(key model.IdempotencyKey) model.IdempotencyKey {
+	key.Task = normalizeTask(key.Task)
+	key.CreatedAt = normalizeTime(key.CreatedAt)
+	key.ExpiresAt = normalizeTime(key.ExpiresAt)
+	return key
+}
+
+func normalizeAuditRecord(record model.AuditRecord) model.AuditRecord {
+	if record.Before != nil {
+		before := normalizeTask(*record.Before)
+		record.Before = &before
+	}
+	if record.After != nil {
+		after := normalizeTask(*record.After)
+		record.After = &after
+	}
+	record.CreatedAt = normalizeTime(record.CreatedAt)
+	return record
+}
+
+// auditOrder is the order of the audit log, which page tokens are read with.
+var auditOrder = []query.Order{{Field: "id"}}
+
+// auditMatch picks the records filter selects among those visible to ctx.
+func auditMatch(ctx context.Context, filter model.AuditFilter) query.Expr {
+	var expr query.Expr
+	if filter.TaskID != 0 {
+		expr = query.AndAlso(expr, query.Comparison{Field: "task_id", Op: query.Eq, Value: filter.TaskID})
+	}
+	if filter.ActorID != 0 {
+		expr = query.AndAlso(expr, query.Comparison{Field: "actor_id", Op: query.Eq, Value: filter.ActorID})
+	}
+	if filter.From != nil {
+		expr = query.AndAlso(expr, query.Comparison{Field: "created_at", Op: query.Ge, Value: normalizeTime(*filter.From)})
+	}
+	if filter.To != nil {
+		expr = query.AndAlso(expr, query.Comparison{Field: "created_at", Op: query.Lt, Value: normalizeTime(*filter.To)})
+	}
+	if filter.AllOwners {
+		return expr
+	}
+	return ownerFilter(ctx, expr)
+}
+
+func auditTask(task *model.Task) (sql.NullString, error) {
+	if task == nil {
+		return sql.NullString{}, nil
+	}
+	encoded, err := json.Marshal(task)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(encoded), Valid: true}, nil
+}
+
+func parseAuditTask(stored sql.NullString) (*model.Task, error) {
+	if !stored.Valid {
+		return nil, nil
+	}
+	var task model.Task
+	if err := json.Unmarshal([]byte(stored.String), &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func normalizeWebhook(webhook model.Webhook) model.Webhook {
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	webhook.CreatedAt = normalizeTime(webhook.CreatedAt)
+	return webhook
+}
+
+func webhookEvents(events []string) string {
+	return strings.Join(events, ",")
+}
+
+func parseWebhookEvents(stored string) []string {
+	if stored == "" {
+		return []string{}
+	}
+	return strings.Split(stored, ",")
+}
+
+func normalizeDelivery(delivery model.WebhookDelivery) model.WebhookDelivery {
+	delivery.NextAttemptAt = normalizeTime(delivery.NextAttemptAt)
+	delivery.CreatedAt = normalizeTime(delivery.CreatedAt)
+	if delivery.DeliveredAt != nil {
+		deliveredAt := normalizeTime(*delivery.DeliveredAt)
+		delivery.DeliveredAt = &deliveredAt
+	}
+	return delivery
+}
+
+func deliveryMatch(ctx context.Context, filter model.DeliveryFilter) query.Expr {
+	var expr query.Expr
+	if filter.ID != 0 {
+		expr = query.AndAlso(expr, query.Comparison{Field: "id", Op: query.Eq, Value: filter.ID})
+	}
+	if filter.WebhookID != 0 {
+		expr = query.AndAlso(expr, query.Comparison{Field: "webhook_id", Op: query.Eq, Value: filter.WebhookID})
+	}
+	if filter.Status != "" {
+		expr = query.AndAlso(expr, query.Comparison{Field: "status", Op: query.Eq, Value: string(filter.Status)})
+	}
+	if filter.DueBy != nil {
+		expr = query.AndAlso(expr, query.Comparison{Field: "next_attempt_at", Op: query.Le, Value: normalizeTime(*filter.DueBy)})
+	}
+	return ownerFilter(ctx, expr)
+}
+
+// deliveryOrder lists due deliveries soonest first and the others newest first.
+func deliveryOrder(filter model.DeliveryFilter) []query.Order {
+	if filter.DueBy != nil {
+		return []query.Order{{Field: "next_attempt_at"}, {Field: "id"}}
+	}
+	return []query.Order{{Field: "id", Desc: true}}
+}
+
+func normalizeProject(project model.Project) model.Project {
+	project.CreatedAt = normalizeTime(project.CreatedAt)
+	project.UpdatedAt = normalizeTime(project.UpdatedAt)
+	if project.ArchivedAt != nil {
+		archivedAt := normalizeTime(*project.ArchivedAt)
+		project.ArchivedAt = &archivedAt
+	}
+	return project
+}
+
+func projectMatch(ctx context.Context, id int) query.Expr {
+	return ownerFilter(ctx, query.Comparison{Field: "id", Op: query.Eq, Value: id})
+}
+
+func archivedProjects(archived bool) query.Expr {
+	if archived {
+		return query.Comparison{Field: "archived_at", Op: query.Ne, Value: nil}
+	}
+	return query.Comparison{Field: "archived_at", Op: query.Eq, Value: nil}
+}
+
+func countProjectStats(stats model.ProjectStats) model.ProjectStats {
+	stats.Open = stats.Total - stats.Completed
+	if stats.Total > 0 {
+		stats.CompletionRate = float64(stats.Completed) / float64(stats.Total)
+	}
+	return stats
+}
+
+func normalizeToken(token model.Token) model.Token {
+	token.CreatedAt = normalizeTime(token.CreatedAt)
+	if token.ExpiresAt != nil {
+		expiresAt := normalizeTime(*token.ExpiresAt)
+		token.ExpiresAt = &expiresAt
+	}
+	if token.LastUsedAt != nil {
+		lastUsedAt := normalizeTime(*token.LastUsedAt)
+		token.LastUsedAt = &lastUsedAt
+	}
+	return token
+}
+
+func normalizeTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC().Truncate(time.Second)
+}
+
+// createEach inserts row by row, as ids counted on from the first insert may have gaps.
+func createEach(ctx context.Context, r Task, tasks []model.Task) ([]model.Task, error) {
+	created := make([]model.Task, len(tasks))
+	err := r.Transaction(ctx, func(tx Store) error {
+		for i, task := range tasks {
+			var err error
+			if created[i], err = tx.Tasks.Create(ctx, task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
@@ -0,0 +1,1257 @@
+// Package repositorytest holds the behaviour every repository backend must share.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"gochallenges/internal/repository"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Factory returns a store with empty repositories, closed when the case finishes.
+type Factory func(t *testing.T) repository.Store
+
+type conformanceCase struct {
+	caseName string
+	run      func(ctx context.Context, t *testing.T, repo repository.Task)
+}
+
+type storeCase struct {
+	caseName string
+	run      func(ctx context.Context, t *testing.T, store repository.Store)
+}
+
+var conformanceCases = []conformanceCase{
+	{
+		caseName: "find by id of a missing task returns ErrTaskNotFound",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			_, err := repo.FindByID(ctx, 42)
+			assertError(t, err, model.ErrTaskNotFound)
+		},
+	},
+	{
+		caseName: "create without id assigns one",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{Name: "task", Completed: true})
+			if created.ID == 0 {
+				t.Fatalf("expected an id to be assigned, got %+v", created)
+			}
+
+			found, err := repo.FindByID(ctx, created.ID)
+			assertError(t, err, nil)
+			assertTask(t, found, created)
+		},
+	},
+	{
+		caseName: "create stores details and timestamps",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			dueAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+2", 2*60*60))
+			task := model.Task{
+				Name:        "task",
+				Description: "with details",
+				DueAt:       &dueAt,
+				Priority:    model.PriorityMedium,
+			}
+			before := model.Now()
+
+			created := mustCreate(ctx, t, repo, task)
+			if created.CreatedAt.Before(before) || !created.UpdatedAt.Equal(created.CreatedAt) {
+				t.Errorf("expected created_at and updated_at to be set on create, got %+v", created)
+			}
+			if created.DueAt == nil || !created.DueAt.Equal(dueAt) || created.DueAt.Location() != time.UTC {
+				t.Errorf("expected due_at to be stored in utc, got %v", created.DueAt)
+			}
+
+			found, err := repo.FindByID(ctx, created.ID)
+			assertError(t, err, nil)
+			assertTask(t, found, created)
+		},
+	},
+	{
+		caseName: "create with id keeps it",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{ID: 7, Name: "task"})
+			if created.ID != 7 {
+				t.Fatalf("expected id 7, got %+v", created)
+			}
+
+			found, err := repo.FindByID(ctx, 7)
+			assertError(t, err, nil)
+			assertTask(t, found, created)
+		},
+	},
+	{
+		caseName: "create with a duplicate id returns ErrTaskAlreadyExists",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{ID: 7, Name: "task"})
+
+			_, err := repo.Create(ctx, model.Task{ID: 7, Name: "other task"})
+			assertError(t, err, model.ErrTaskAlreadyExists)
+
+			found, err := repo.FindByID(ctx, 7)
+			assertError(t, err, nil)
+			assertTask(t, found, created)
+		},
+	},
+	{
+		caseName: "create many stores every task",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			dueAt := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+
+			created, err := repo.CreateMany(alice, []model.Task{
+				{Name: "first", DueAt: &dueAt, Priority: model.PriorityHigh},
+				{ID: 10, Name: "second", Completed: true, CompletedAt: &dueAt},
+				{Name: "third"},
+			})
+			assertError(t, err, nil)
+			if len(created) != 3 || created[0].Name != "first" || created[1].ID != 10 || created[2].Name != "third" {
+				t.Fatalf("expected the tasks back in order, got %+v", created)
+			}
+			for _, task := range created {
+				found, err := repo.FindByID(alice, task.ID)
+				assertError(t, err, nil)
+				assertTask(t, found, task)
+			}
+
+			page, err := repo.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Tasks) != 3 {
+				t.Fatalf("expected 3 tasks, got %+v", page.Tasks)
+			}
+			names := map[string]model.Task{}
+			for _, task := range page.Tasks {
+				if task.OwnerID != 2 || task.Version != 1 || task.CreatedAt.IsZero() {
+					t.Errorf("expected alice's task at version 1 with a creation time, got %+v", task)
+				}
+				names[task.Name] = task
+			}
+			if second := names["second"]; second.ID != 10 || second.CompletedAt == nil || !second.CompletedAt.Equal(dueAt) {
+				t.Errorf("expected the second task to keep its id and completion, got %+v", second)
+			}
+			if first := names["first"]; first.DueAt == nil || !first.DueAt.Equal(dueAt) || first.Priority != model.PriorityHigh {
+				t.Errorf("expected the first task to keep its details, got %+v", first)
+			}
+		},
+	},
+	{
+		caseName: "create many stores nothing when a task fails",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			existing := mustCreate(ctx, t, repo, model.Task{Name: "existing"})
+
+			created, err := repo.CreateMany(ctx, []model.Task{{Name: "new"}, {ID: existing.ID, Name: "duplicate"}})
+			assertError(t, err, model.ErrTaskAlreadyExists)
+			if created != nil {
+				t.Errorf("expected no tasks back, got %+v", created)
+			}
+
+			page, err := repo.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{existing})
+		},
+	},
+	{
+		caseName: "find all on an empty table returns an empty slice",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			page, err := repo.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{})
+		},
+	},
+	{
+		caseName: "find by status without matches returns an empty slice",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			mustCreate(ctx, t, repo, model.Task{Name: "open task"})
+
+			page, err := repo.FindByStatus(ctx, 0, true, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{})
+		},
+	},
+	{
+		caseName: "find by status only returns matching tasks in id order",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			first := mustCreate(ctx, t, repo, model.Task{Name: "first", Completed: true})
+			mustCreate(ctx, t, repo, model.Task{Name: "second"})
+			third := mustCreate(ctx, t, repo, model.Task{Name: "third", Completed: true})
+
+			page, err := repo.FindByStatus(ctx, 0, true, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{first, third})
+		},
+	},
+	{
+		caseName: "find all returns every task in id order",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			first := mustCreate(ctx, t, repo, model.Task{Name: "first"})
+			second := mustCreate(ctx, t, repo, model.Task{Name: "second", Completed: true})
+
+			page, err := repo.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{first, second})
+		},
+	},
+	{
+		caseName: "find all walks every page with the page token",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			var created []model.Task
+			for i := 0; i < 5; i++ {
+				created = append(created, mustCreate(ctx, t, repo, model.Task{Name: "task"}))
+			}
+
+			page, err := repo.FindAll(ctx, 0, model.PageRequest{Size: 2})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, created[0:2])
+
+			page, err = repo.FindAll(ctx, 0, model.PageRequest{Size: 2, Token: page.NextPageToken})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, created[2:4])
+
+			page, err = repo.FindAll(ctx, 0, model.PageRequest{Size: 2, Token: page.NextPageToken})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, created[4:])
+			if page.NextPageToken != "" {
+				t.Errorf("expected no token after the last page, got %q", page.NextPageToken)
+			}
+		},
+	},
+	{
+		caseName: "find by status pages over matching tasks only",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			var completed []model.Task
+			for i := 0; i < 3; i++ {
+				completed = append(completed, mustCreate(ctx, t, repo, model.Task{Name: "done", Completed: true}))
+				mustCreate(ctx, t, repo, model.Task{Name: "open"})
+			}
+
+			page, err := repo.FindByStatus(ctx, 0, true, model.PageRequest{Size: 2})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, completed[0:2])
+
+			page, err = repo.FindByStatus(ctx, 0, true, model.PageRequest{Size: 2, Token: page.NextPageToken})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, completed[2:])
+			if page.NextPageToken != "" {
+				t.Errorf("expected no token after the last page, got %q", page.NextPageToken)
+			}
+		},
+	},
+	{
+		caseName: "page size is capped at the maximum",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			for i := 0; i < model.MaxPageSize+1; i++ {
+				mustCreate(ctx, t, repo, model.Task{Name: "task"})
+			}
+
+			page, err := repo.FindAll(ctx, 0, model.PageRequest{Size: model.MaxPageSize + 1})
+			assertError(t, err, nil)
+			if len(page.Tasks) != model.MaxPageSize || page.NextPageToken == "" {
+				t.Errorf("expected %d tasks and a next page, got %d tasks and token %q", model.MaxPageSize, len(page.Tasks), page.NextPageToken)
+			}
+		},
+	},
+	{
+		caseName: "invalid page requests are rejected",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			_, err := repo.FindAll(ctx, 0, model.PageRequest{Size: -1})
+			assertError(t, err, model.ErrInvalidPageSize)
+
+			_, err = repo.FindAll(ctx, 0, model.PageRequest{Token: "not a token"})
+			assertError(t, err, model.ErrInvalidPageToken)
+
+			_, err = repo.FindByStatus(ctx, 0, true, model.PageRequest{Token: "e30"})
+			assertError(t, err, model.ErrInvalidPageToken)
+		},
+	},
+	{
+		caseName: "find applies the filter",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			deploy := mustCreate(ctx, t, repo, model.Task{Name: "Deploy api"})
+			mustCreate(ctx, t, repo, model.Task{Name: "deploy worker", Completed: true})
+			mustCreate(ctx, t, repo, model.Task{Name: "write docs"})
+			release := mustCreate(ctx, t, repo, model.Task{Name: "release", Completed: true})
+
+			page, err := repo.Find(ctx, mustParse(t, `completed = false AND name ~ "DEPLOY"`, ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{deploy})
+
+			page, err = repo.Find(ctx, mustParse(t, `NOT (name ~ "deploy" OR name = "write docs")`, ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{release})
+		},
+	},
+	{
+		caseName: "find matches like wildcards literally",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			literal := mustCreate(ctx, t, repo, model.Task{Name: "grow 50% faster"})
+			mustCreate(ctx, t, repo, model.Task{Name: "grow 500 faster"})
+			mustCreate(ctx, t, repo, model.Task{Name: "grow!faster"})
+
+			page, err := repo.Find(ctx, mustParse(t, `name ~ "50%" OR name ~ "w 5_0" OR name ~ "w!!f"`, ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{literal})
+		},
+	},
+	{
+		caseName: "find sorts and pages over ties",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			a1 := mustCreate(ctx, t, repo, model.Task{Name: "a"})
+			b1 := mustCreate(ctx, t, repo, model.Task{Name: "b"})
+			a2 := mustCreate(ctx, t, repo, model.Task{Name: "a"})
+			b2 := mustCreate(ctx, t, repo, model.Task{Name: "b", Completed: true})
+			c := mustCreate(ctx, t, repo, model.Task{Name: "c"})
+			q := mustParse(t, "", "name desc")
+
+			var got []model.Task
+			page := model.TaskPage{}
+			for i := 0; i == 0 || page.NextPageToken != ""; i++ {
+				var err error
+				page, err = repo.Find(ctx, q, model.PageRequest{Size: 2, Token: page.NextPageToken})
+				assertError(t, err, nil)
+				got = append(got, page.Tasks...)
+			}
+			assertTasks(t, got, []model.Task{c, b1, b2, a1, a2})
+
+			page, err := repo.Find(ctx, mustParse(t, "id > 1", "completed desc, name, id desc"), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{b2, a2, b1, c})
+		},
+	},
+	{
+		caseName: "find filters and pages over nullable and enum fields",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			early := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+			late := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+			noDue := mustCreate(ctx, t, repo, model.Task{Name: "no due date", Priority: model.PriorityHigh})
+			lateTask := mustCreate(ctx, t, repo, model.Task{Name: "late", DueAt: &late, Priority: model.PriorityLow})
+			earlyTask := mustCreate(ctx, t, repo, model.Task{Name: "early", DueAt: &early, Priority: model.PriorityHigh})
+			noDue2 := mustCreate(ctx, t, repo, model.Task{Name: "no due date either"})
+
+			page, err := repo.Find(ctx, mustParse(t, `priority = high AND due_at != null`, ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{earlyTask})
+
+			page, err = repo.Find(ctx, mustParse(t, `due_at < "2030-03-01" OR priority > medium`, ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{noDue, earlyTask})
+
+			for _, testCase := range []struct {
+				orderBy string
+				want    []model.Task
+			}{
+				{"due_at", []model.Task{noDue, noDue2, earlyTask, lateTask}},
+				{"due_at desc", []model.Task{lateTask, earlyTask, noDue, noDue2}},
+				{"priority desc, due_at desc", []model.Task{earlyTask, noDue, lateTask, noDue2}},
+			} {
+				var got []model.Task
+				page := model.TaskPage{}
+				for i := 0; i == 0 || page.NextPageToken != ""; i++ {
+					var err error
+					page, err = repo.Find(ctx, mustParse(t, "", testCase.orderBy), model.PageRequest{Size: 1, Token: page.NextPageToken})
+					assertError(t, err, nil)
+					got = append(got, page.Tasks...)
+				}
+				assertTasks(t, got, testCase.want)
+			}
+		},
+	},
+	{
+		caseName: "page token does not carry over to another ordering",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			for i := 0; i < 3; i++ {
+				mustCreate(ctx, t, repo, model.Task{Name: "task"})
+			}
+
+			page, err := repo.Find(ctx, mustParse(t, "", "name"), model.PageRequest{Size: 1})
+			assertError(t, err, nil)
+
+			_, err = repo.Find(ctx, mustParse(t, "", "name desc"), model.PageRequest{Token: page.NextPageToken})
+			assertError(t, err, model.ErrInvalidPageToken)
+		},
+	},
+	{
+		caseName: "update returns the stored task",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{Name: "task"})
+			dueAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+			modified := created
+			modified.Name = "renamed"
+			modified.Completed = true
+			modified.Description = "with details"
+			modified.DueAt = &dueAt
+			modified.Priority = model.PriorityHigh
+			modified.CompletedAt = &dueAt
+
+			updated, err := repo.Update(ctx, modified)
+			assertError(t, err, nil)
+			if updated.UpdatedAt.Before(created.UpdatedAt) {
+				t.Errorf("expected updated_at to move forward, got %v before %v", updated.UpdatedAt, created.UpdatedAt)
+			}
+			modified.UpdatedAt = updated.UpdatedAt
+			modified.Version = created.Version + 1
+			assertTask(t, updated, modified)
+
+			found, err := repo.FindByID(ctx, created.ID)
+			assertError(t, err, nil)
+			assertTask(t, found, modified)
+		},
+	},
+	{
+		caseName: "update checks and bumps the version",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{Name: "task"})
+			if created.Version != 1 {
+				t.Fatalf("expected a new task at version 1, got %d", created.Version)
+			}
+
+			first := created
+			first.Name = "first edit"
+			updated, err := repo.Update(ctx, first)
+			assertError(t, err, nil)
+			if updated.Version != 2 {
+				t.Errorf("expected version 2 after an update, got %d", updated.Version)
+			}
+
+			stale := created
+			stale.Name = "stale edit"
+			_, err = repo.Update(ctx, stale)
+			assertError(t, err, model.ErrTaskVersionMismatch)
+
+			unconditional := created
+			unconditional.Name = "unconditional edit"
+			unconditional.Version = 0
+			updated, err = repo.Update(ctx, unconditional)
+			assertError(t, err, nil)
+			if updated.Name != "unconditional edit" || updated.Version != 3 {
+				t.Errorf("expected an unconditional update to version 3, got %+v", updated)
+			}
+		},
+	},
+	{
+		caseName: "update of a missing task returns ErrTaskNotFound",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			_, err := repo.Update(ctx, model.Task{ID: 42, Name: "task"})
+			assertError(t, err, model.ErrTaskNotFound)
+
+			_, err = repo.FindByID(ctx, 42)
+			assertError(t, err, model.ErrTaskNotFound)
+		},
+	},
+	{
+		caseName: "cancelled context fails",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			mustCreate(ctx, t, repo, model.Task{Name: "task"})
+
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+
+			if _, err := repo.FindAll(cancelled, 0, model.PageRequest{}); err == nil {
+				t.Errorf("expected an error for a cancelled context")
+			}
+			if _, err := repo.Create(cancelled, model.Task{Name: "task"}); err == nil {
+				t.Errorf("expected an error for a cancelled context")
+			}
+		},
+	},
+	{
+		caseName: "delete removes the task",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{Name: "task"})
+
+			assertError(t, repo.Delete(ctx, created.ID, 0), nil)
+
+			_, err := repo.FindByID(ctx, created.ID)
+			assertError(t, err, model.ErrTaskNotFound)
+		},
+	},
+	{
+		caseName: "delete checks the version",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{Name: "task"})
+
+			assertError(t, repo.Delete(ctx, created.ID, created.Version+1), model.ErrTaskVersionMismatch)
+			assertError(t, repo.Delete(ctx, created.ID, created.Version), nil)
+			assertError(t, repo.Delete(ctx, created.ID, created.Version), nil)
+		},
+	},
+	{
+		caseName: "delete is idempotent",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{Name: "task"})
+
+			assertError(t, repo.Delete(ctx, created.ID, 0), nil)
+			assertError(t, repo.Delete(ctx, created.ID, 0), nil)
+			assertError(t, repo.Delete(ctx, 42, 0), nil)
+		},
+	},
+	{
+		caseName: "deleted tasks are only found in the trash",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			deleted := mustCreate(alice, t, repo, model.Task{Name: "deleted"})
+			kept := mustCreate(alice, t, repo, model.Task{Name: "kept"})
+
+			assertError(t, repo.Delete(alice, deleted.ID, deleted.Version), nil)
+
+			_, err := repo.FindByID(alice, deleted.ID)
+			assertError(t, err, model.ErrTaskNotFound)
+			_, err = repo.Update(alice, deleted)
+			assertError(t, err, model.ErrTaskNotFound)
+			page, err := repo.FindAll(alice, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{kept})
+			page, err = repo.FindByStatus(alice, 0, false, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{kept})
+
+			trash, err := repo.FindTrash(alice, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(trash.Tasks) != 1 || trash.Tasks[0].ID != deleted.ID || trash.Tasks[0].DeletedAt == nil || trash.Tasks[0].Version != deleted.Version+1 {
+				t.Errorf("expected the deleted task in the trash, got %+v", trash.Tasks)
+			}
+			trash, err = repo.FindTrash(bob, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, trash.Tasks, []model.Task{})
+		},
+	},
+	{
+		caseName: "trash pages from the most recently deleted task",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			var ids []int
+			for _, name := range []string{"first", "second", "third"} {
+				created := mustCreate(ctx, t, repo, model.Task{Name: name})
+				assertError(t, repo.Delete(ctx, created.ID, 0), nil)
+				ids = append([]int{created.ID}, ids...)
+			}
+
+			var got []int
+			page := model.PageRequest{Size: 2}
+			for {
+				trash, err := repo.FindTrash(ctx, page)
+				assertError(t, err, nil)
+				for _, task := range trash.Tasks {
+					got = append(got, task.ID)
+				}
+				if trash.NextPageToken == "" {
+					break
+				}
+				page.Token = trash.NextPageToken
+			}
+			if !reflect.DeepEqual(got, ids) {
+				t.Errorf("got %v want %v", got, ids)
+			}
+		},
+	},
+	{
+		caseName: "restore takes a task out of the trash",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			created := mustCreate(alice, t, repo, model.Task{Name: "task", Priority: model.PriorityLow})
+
+			_, err := repo.Restore(alice, created.ID)
+			assertError(t, err, model.ErrTaskNotFound)
+
+			assertError(t, repo.Delete(alice, created.ID, 0), nil)
+			_, err = repo.Restore(bob, created.ID)
+			assertError(t, err, model.ErrTaskNotFound)
+
+			restored, err := repo.Restore(alice, created.ID)
+			assertError(t, err, nil)
+			if restored.DeletedAt != nil || restored.Version != created.Version+2 || restored.Name != created.Name || restored.Priority != created.Priority {
+				t.Errorf("expected the task back at a new version, got %+v", restored)
+			}
+			found, err := repo.FindByID(alice, created.ID)
+			assertError(t, err, nil)
+			assertTask(t, found, restored)
+
+			_, err = repo.Restore(alice, created.ID)
+			assertError(t, err, model.ErrTaskNotFound)
+		},
+	},
+	{
+		caseName: "purge removes the tasks trashed before a time",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			trashed := mustCreate(ctx, t, repo, model.Task{Name: "trashed"})
+			kept := mustCreate(ctx, t, repo, model.Task{Name: "kept"})
+			assertError(t, repo.Delete(ctx, trashed.ID, 0), nil)
+
+			purged, err := repo.Purge(ctx, model.Now().Add(-time.Hour))
+			assertError(t, err, nil)
+			if purged != 0 {
+				t.Errorf("expected nothing trashed an hour ago, purged %d", purged)
+			}
+			purged, err = repo.Purge(alice, model.Now().Add(time.Second))
+			assertError(t, err, nil)
+			if purged != 0 {
+				t.Errorf("expected another user's trash to be left alone, purged %d", purged)
+			}
+
+			purged, err = repo.Purge(ctx, model.Now().Add(time.Second))
+			assertError(t, err, nil)
+			if purged != 1 {
+				t.Errorf("expected the trashed task to be purged, purged %d", purged)
+			}
+			trash, err := repo.FindTrash(ctx, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, trash.Tasks, []model.Task{})
+			_, err = repo.Restore(ctx, trashed.ID)
+			assertError(t, err, model.ErrTaskNotFound)
+			found, err := repo.FindByID(ctx, kept.ID)
+			assertError(t, err, nil)
+			assertTask(t, found, kept)
+		},
+	},
+	{
+		caseName: "subtasks keep their parent through creates, updates and filters",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			parent := mustCreate(ctx, t, repo, model.Task{Name: "parent"})
+			child := mustCreate(ctx, t, repo, model.Task{Name: "child", ParentID: &parent.ID})
+			if child.ParentID == nil || *child.ParentID != parent.ID {
+				t.Fatalf("expected the child under %d, got %+v", parent.ID, child)
+			}
+			found, err := repo.FindByID(ctx, child.ID)
+			assertError(t, err, nil)
+			assertTask(t, found, child)
+
+			page, err := repo.Find(ctx, mustParse(t, "parent_id = null", ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{parent})
+
+			child.ParentID = nil
+			moved, err := repo.Update(ctx, child)
+			assertError(t, err, nil)
+			if moved.ParentID != nil {
+				t.Errorf("expected the child moved to the top, got %+v", moved)
+			}
+			moved.ParentID = &parent.ID
+			moved, err = repo.Update(ctx, moved)
+			assertError(t, err, nil)
+			page, err = repo.Find(ctx, mustParse(t, fmt.Sprintf("parent_id = %d", parent.ID), ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{moved})
+
+			_, err = repo.CreateMany(ctx, []model.Task{{Name: "imported", ParentID: &parent.ID}})
+			assertError(t, err, nil)
+			page, err = repo.Find(ctx, mustParse(t, "name = \"imported\"", ""), model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Tasks) != 1 || page.Tasks[0].ParentID == nil || *page.Tasks[0].ParentID != parent.ID {
+				t.Errorf("expected the imported task under %d, got %+v", parent.ID, page.Tasks)
+			}
+		},
+	},
+	{
+		caseName: "find subtree walks down the tasks on one side of the trash",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			root := mustCreate(alice, t, repo, model.Task{Name: "root"})
+			child := mustCreate(alice, t, repo, model.Task{Name: "child", ParentID: &root.ID})
+			sibling := mustCreate(alice, t, repo, model.Task{Name: "sibling", ParentID: &root.ID})
+			grandchild := mustCreate(alice, t, repo, model.Task{Name: "grandchild", ParentID: &child.ID})
+			mustCreate(alice, t, repo, model.Task{Name: "elsewhere"})
+
+			subtree, err := repo.FindSubtree(alice, root.ID, false)
+			assertError(t, err, nil)
+			assertTasks(t, subtree, []model.Task{child, sibling, grandchild})
+			subtree, err = repo.FindSubtree(bob, root.ID, false)
+			assertError(t, err, nil)
+			assertTasks(t, subtree, []model.Task{})
+
+			assertError(t, repo.Delete(alice, child.ID, 0), nil)
+			subtree, err = repo.FindSubtree(alice, root.ID, false)
+			assertError(t, err, nil)
+			assertTasks(t, subtree, []model.Task{sibling})
+			subtree, err = repo.FindSubtree(alice, child.ID, true)
+			assertError(t, err, nil)
+			assertTasks(t, subtree, []model.Task{})
+
+			assertError(t, repo.Delete(alice, grandchild.ID, 0), nil)
+			subtree, err = repo.FindSubtree(alice, child.ID, true)
+			assertError(t, err, nil)
+			if len(subtree) != 1 || subtree[0].ID != grandchild.ID || subtree[0].DeletedAt == nil {
+				t.Errorf("expected the trashed grandchild, got %+v", subtree)
+			}
+		},
+	},
+	{
+		caseName: "create without a user in the context belongs to the default user",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			created := mustCreate(ctx, t, repo, model.Task{Name: "task"})
+			if created.OwnerID != model.DefaultUserID {
+				t.Errorf("expected owner %d, got %+v", model.DefaultUserID, created)
+			}
+		},
+	},
+	{
+		caseName: "tasks are scoped to the user in the context",
+		run: func(ctx context.Context, t *testing.T, repo repository.Task) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+
+			created := mustCreate(alice, t, repo, model.Task{Name: "alice's task"})
+			if created.OwnerID != 2 {
+				t.Fatalf("expected owner 2, got %+v", created)
+			}
+			mustCreate(bob, t, repo, model.Task{Name: "bob's task"})
+
+			_, err := repo.FindByID(bob, created.ID)
+			assertError(t, err, model.ErrTaskNotFound)
+
+			modified := created
+			modified.Name = "taken over"
+			_, err = repo.Update(bob, modified)
+			assertError(t, err, model.ErrTaskNotFound)
+
+			assertError(t, repo.Delete(bob, created.ID, 0), nil)
+			assertError(t, repo.Delete(bob, created.ID, created.Version), nil)
+
+			page, err := repo.Find(alice, mustParse(t, `name~"task"`, ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{created})
+
+			page, err = repo.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Tasks) != 2 {
+				t.Errorf("expected both tasks without a user in the context, got %+v", page.Tasks)
+			}
+		},
+	},
+}
+
+var storeCases = []storeCase{
+	{
+		caseName: "idempotency keys are found by their user until they expire",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			now := model.Now()
+			task := mustCreate(alice, t, store.Tasks, model.Task{Name: "task", Priority: model.PriorityHigh})
+			key := model.IdempotencyKey{Key: "retry-1", Fingerprint: "abc", Task: task, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+			assertError(t, store.Idempotency.CreateIdempotencyKey(alice, key), nil)
+			assertError(t, store.Idempotency.CreateIdempotencyKey(alice, key), model.ErrIdempotencyKeyExists)
+			assertError(t, store.Idempotency.CreateIdempotencyKey(bob, key), nil)
+
+			found, err := store.Idempotency.FindIdempotencyKey(alice, "retry-1")
+			assertError(t, err, nil)
+			assertTask(t, found.Task, task)
+			if found.OwnerID != 2 || found.Fingerprint != "abc" || !found.ExpiresAt.Equal(key.ExpiresAt) {
+				t.Errorf("expected alice's key, got %+v", found)
+			}
+			_, err = store.Idempotency.FindIdempotencyKey(alice, "retry-2")
+			assertError(t, err, model.ErrIdempotencyKeyNotFound)
+
+			expired := model.IdempotencyKey{Key: "retry-2", Fingerprint: "def", Task: task, CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+			assertError(t, store.Idempotency.CreateIdempotencyKey(alice, expired), nil)
+			_, err = store.Idempotency.FindIdempotencyKey(alice, "retry-2")
+			assertError(t, err, model.ErrIdempotencyKeyNotFound)
+
+			expired.Fingerprint, expired.ExpiresAt = "ghi", now.Add(time.Hour)
+			assertError(t, store.Idempotency.CreateIdempotencyKey(alice, expired), nil)
+			found, err = store.Idempotency.FindIdempotencyKey(alice, "retry-2")
+			assertError(t, err, nil)
+			if found.Fingerprint != "ghi" {
+				t.Errorf("expected an expired key to be replaced, got %+v", found)
+			}
+		},
+	},
+	{
+		caseName: "audit records are kept per owner, oldest first",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			now := model.Now()
+			task := mustCreate(alice, t, store.Tasks, model.Task{Name: "task", Priority: model.PriorityHigh})
+			renamed := task
+			renamed.Name, renamed.Version = "renamed", 2
+
+			records := []model.AuditRecord{
+				{TaskID: task.ID, OwnerID: 2, Operation: model.AuditCreate, After: &task, CreatedAt: now.Add(-time.Hour)},
+				{TaskID: task.ID, OwnerID: 2, Operation: model.AuditUpdate, Before: &task, After: &renamed, CreatedAt: now},
+				{TaskID: task.ID + 1, OwnerID: 2, Operation: model.AuditDelete, Before: &task, CreatedAt: now},
+			}
+			for _, record := range records {
+				assertError(t, store.Audit.CreateAuditRecord(alice, record), nil)
+			}
+			assertError(t, store.Audit.CreateAuditRecord(bob, model.AuditRecord{TaskID: 99, OwnerID: 3, Operation: model.AuditCreate, After: &task, CreatedAt: now}), nil)
+
+			history, err := store.Audit.FindAuditRecords(alice, model.AuditFilter{TaskID: task.ID}, model.PageRequest{Size: 1})
+			assertError(t, err, nil)
+			if len(history.Records) != 1 || history.NextPageToken == "" {
+				t.Fatalf("expected the first of two records, got %+v", history)
+			}
+			first := history.Records[0]
+			if first.ActorID != 2 || first.Operation != model.AuditCreate || first.Before != nil || first.After == nil || !first.CreatedAt.Equal(records[0].CreatedAt) {
+				t.Errorf("expected the create record, got %+v", first)
+			}
+			assertTask(t, *first.After, task)
+
+			history, err = store.Audit.FindAuditRecords(alice, model.AuditFilter{TaskID: task.ID}, model.PageRequest{Size: 1, Token: history.NextPageToken})
+			assertError(t, err, nil)
+			if len(history.Records) != 1 || history.NextPageToken != "" || history.Records[0].Operation != model.AuditUpdate {
+				t.Fatalf("expected the update record last, got %+v", history)
+			}
+			assertTask(t, *history.Records[0].Before, task)
+			assertTask(t, *history.Records[0].After, renamed)
+
+			from, to := now.Add(-time.Minute), now.Add(time.Minute)
+			page, err := store.Audit.FindAuditRecords(alice, model.AuditFilter{ActorID: 2, From: &from, To: &to}, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Records) != 2 || page.Records[0].Operation != model.AuditUpdate || page.Records[1].Operation != model.AuditDelete {
+				t.Errorf("expected the records of the last minute, got %+v", page.Records)
+			}
+			page, err = store.Audit.FindAuditRecords(alice, model.AuditFilter{ActorID: 3}, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Records) != 0 {
+				t.Errorf("expected bob's records on his own tasks only, got %+v", page.Records)
+			}
+			page, err = store.Audit.FindAuditRecords(ctx, model.AuditFilter{}, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Records) != 4 {
+				t.Errorf("expected every record without a user in the context, got %+v", page.Records)
+			}
+		},
+	},
+	{
+		caseName: "audit records name the user who acted, whose tasks they are or not",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			now := model.Now()
+			alicesTask := mustCreate(alice, t, store.Tasks, model.Task{Name: "alice's task"})
+			bobsTask := mustCreate(bob, t, store.Tasks, model.Task{Name: "bob's task"})
+
+			assertError(t, store.Audit.CreateAuditRecord(alice, model.AuditRecord{TaskID: bobsTask.ID, OwnerID: 3, Operation: model.AuditUpdate, Before: &bobsTask, After: &bobsTask, CreatedAt: now}), nil)
+			assertError(t, store.Audit.CreateAuditRecord(bob, model.AuditRecord{TaskID: alicesTask.ID, OwnerID: 2, Operation: model.AuditDelete, Before: &alicesTask, CreatedAt: now}), nil)
+			assertError(t, store.Audit.CreateAuditRecord(ctx, model.AuditRecord{TaskID: alicesTask.ID, OwnerID: 2, Operation: model.AuditRestore, After: &alicesTask, CreatedAt: now.Add(time.Second)}), nil)
+
+			page, err := store.Audit.FindAuditRecords(bob, model.AuditFilter{}, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Records) != 1 || page.Records[0].TaskID != bobsTask.ID || page.Records[0].ActorID != 2 {
+				t.Errorf("expected alice's change to bob's task, got %+v", page.Records)
+			}
+			page, err = store.Audit.FindAuditRecords(alice, model.AuditFilter{}, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Records) != 2 || page.Records[0].ActorID != 3 || page.Records[1].ActorID != 0 {
+				t.Errorf("expected bob's change and one without a user to alice's task, got %+v", page.Records)
+			}
+
+			page, err = store.Audit.FindAuditRecords(alice, model.AuditFilter{AllOwners: true}, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Records) != 3 {
+				t.Errorf("expected the records of every owner, got %+v", page.Records)
+			}
+			page, err = store.Audit.FindAuditRecords(bob, model.AuditFilter{ActorID: 3, AllOwners: true}, model.PageRequest{})
+			assertError(t, err, nil)
+			if len(page.Records) != 1 || page.Records[0].TaskID != alicesTask.ID || page.Records[0].OwnerID != 2 {
+				t.Errorf("expected bob's change to alice's task, got %+v", page.Records)
+			}
+		},
+	},
+	{
+		caseName: "projects are kept per owner and archived apart",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			now := model.Now()
+
+			created, err := store.Projects.CreateProject(alice, model.Project{Name: "home", Description: "chores", CreatedAt: now, UpdatedAt: now})
+			assertError(t, err, nil)
+			if created.ID == 0 || created.OwnerID != 2 {
+				t.Fatalf("expected an id and alice as the owner, got %+v", created)
+			}
+			other, err := store.Projects.CreateProject(bob, model.Project{Name: "work", CreatedAt: now, UpdatedAt: now})
+			assertError(t, err, nil)
+
+			found, err := store.Projects.FindProject(alice, created.ID)
+			assertError(t, err, nil)
+			if !reflect.DeepEqual(found, created) {
+				t.Errorf("got %+v want %+v", found, created)
+			}
+			_, err = store.Projects.FindProject(alice, other.ID)
+			assertError(t, err, model.ErrProjectNotFound)
+
+			created.Name = "house"
+			created.ArchivedAt = &now
+			_, err = store.Projects.UpdateProject(bob, created)
+			assertError(t, err, model.ErrProjectNotFound)
+			archived, err := store.Projects.UpdateProject(alice, created)
+			assertError(t, err, nil)
+			if archived.Name != "house" || archived.ArchivedAt == nil || !archived.ArchivedAt.Equal(now) {
+				t.Errorf("expected the project renamed and archived, got %+v", archived)
+			}
+
+			projects, err := store.Projects.FindProjects(alice, false)
+			assertError(t, err, nil)
+			if len(projects) != 0 {
+				t.Errorf("expected no active projects, got %+v", projects)
+			}
+			projects, err = store.Projects.FindProjects(alice, true)
+			assertError(t, err, nil)
+			if !reflect.DeepEqual(projects, []model.Project{archived}) {
+				t.Errorf("got %+v want the archived project %+v", projects, archived)
+			}
+			projects, err = store.Projects.FindProjects(ctx, false)
+			assertError(t, err, nil)
+			if !reflect.DeepEqual(projects, []model.Project{other}) {
+				t.Errorf("got %+v want bob's project %+v without a user in the context", projects, other)
+			}
+
+			assertError(t, store.Projects.DeleteProject(bob, created.ID), model.ErrProjectNotFound)
+			assertError(t, store.Projects.DeleteProject(alice, created.ID), nil)
+			_, err = store.Projects.FindProject(alice, created.ID)
+			assertError(t, err, model.ErrProjectNotFound)
+		},
+	},
+	{
+		caseName: "tasks are listed and counted per project",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			now := model.Now()
+			past := now.Add(-time.Hour)
+			project, err := store.Projects.CreateProject(ctx, model.Project{Name: "home", CreatedAt: now, UpdatedAt: now})
+			assertError(t, err, nil)
+
+			done := mustCreate(ctx, t, store.Tasks, model.Task{Name: "done", ProjectID: &project.ID, Completed: true, DueAt: &past})
+			late := mustCreate(ctx, t, store.Tasks, model.Task{Name: "late", ProjectID: &project.ID, DueAt: &past})
+			open := mustCreate(ctx, t, store.Tasks, model.Task{Name: "open", ProjectID: &project.ID})
+			trashed := mustCreate(ctx, t, store.Tasks, model.Task{Name: "trashed", ProjectID: &project.ID})
+			loose := mustCreate(ctx, t, store.Tasks, model.Task{Name: "loose"})
+			assertError(t, store.Tasks.Delete(ctx, trashed.ID, 0), nil)
+
+			page, err := store.Tasks.FindAll(ctx, project.ID, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{done, late, open})
+			page, err = store.Tasks.FindByStatus(ctx, project.ID, false, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{late, open})
+			page, err = store.Tasks.Find(ctx, mustParse(t, "project_id = null", ""), model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{loose})
+
+			stats, err := store.Tasks.FindProjectStats(ctx, project.ID, now)
+			assertError(t, err, nil)
+			want := model.ProjectStats{ProjectID: project.ID, Total: 3, Completed: 1, Open: 2, Overdue: 1, CompletionRate: 1.0 / 3}
+			if stats != want {
+				t.Errorf("got %+v want %+v", stats, want)
+			}
+
+			open.ProjectID = nil
+			moved, err := store.Tasks.Update(ctx, open)
+			assertError(t, err, nil)
+			if moved.ProjectID != nil {
+				t.Errorf("expected the task out of the project, got %+v", moved)
+			}
+			stats, err = store.Tasks.FindProjectStats(ctx, project.ID, now)
+			assertError(t, err, nil)
+			if stats.Total != 2 || stats.CompletionRate != 0.5 {
+				t.Errorf("expected two tasks left, got %+v", stats)
+			}
+
+			empty, err := store.Tasks.FindProjectStats(ctx, project.ID+1, now)
+			assertError(t, err, nil)
+			if empty != (model.ProjectStats{ProjectID: project.ID + 1}) {
+				t.Errorf("expected no tasks in an unknown project, got %+v", empty)
+			}
+		},
+	},
+	{
+		caseName: "webhooks are kept per owner and deleted with their deliveries",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			now := model.Now()
+
+			created, err := store.Webhooks.CreateWebhook(alice, model.Webhook{URL: "http://example.com/hook", Events: []string{"task.created", "task.deleted"}, Secret: "secret", CreatedAt: now})
+			assertError(t, err, nil)
+			if created.ID == 0 || created.OwnerID != 2 {
+				t.Fatalf("expected an id and alice as the owner, got %+v", created)
+			}
+			_, err = store.Webhooks.CreateWebhook(bob, model.Webhook{URL: "http://example.com/bob", Secret: "secret", CreatedAt: now})
+			assertError(t, err, nil)
+
+			webhooks, err := store.Webhooks.FindWebhooks(alice)
+			assertError(t, err, nil)
+			if !reflect.DeepEqual(webhooks, []model.Webhook{created}) {
+				t.Errorf("got %+v want alice's webhook %+v", webhooks, created)
+			}
+			webhooks, err = store.Webhooks.FindWebhooks(ctx)
+			assertError(t, err, nil)
+			if len(webhooks) != 2 || len(webhooks[1].Events) != 0 {
+				t.Errorf("expected every webhook without a user in the context, got %+v", webhooks)
+			}
+
+			delivery := model.WebhookDelivery{WebhookID: created.ID, OwnerID: 2, EventType: "task.created", Payload: []byte(`{"type":"task.created"}`), Status: model.DeliveryPending, NextAttemptAt: now, CreatedAt: now}
+			assertError(t, store.Webhooks.CreateDeliveries(alice, []model.WebhookDelivery{delivery, delivery}), nil)
+
+			assertError(t, store.Webhooks.DeleteWebhook(bob, created.ID), model.ErrWebhookNotFound)
+			assertError(t, store.Webhooks.DeleteWebhook(alice, created.ID), nil)
+			assertError(t, store.Webhooks.DeleteWebhook(alice, created.ID), model.ErrWebhookNotFound)
+			deliveries, err := store.Webhooks.FindDeliveries(ctx, model.DeliveryFilter{}, 10)
+			assertError(t, err, nil)
+			if len(deliveries) != 0 {
+				t.Errorf("expected the deliveries to go with their webhook, got %+v", deliveries)
+			}
+		},
+	},
+	{
+		caseName: "deliveries are due soonest first and claimed once",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			alice := model.ContextWithUser(ctx, model.User{ID: 2, Name: "alice"})
+			bob := model.ContextWithUser(ctx, model.User{ID: 3, Name: "bob"})
+			now := model.Now()
+			webhook, err := store.Webhooks.CreateWebhook(alice, model.Webhook{URL: "http://example.com/hook", Secret: "secret", CreatedAt: now})
+			assertError(t, err, nil)
+
+			delivery := func(eventType string, next time.Time) model.WebhookDelivery {
+				return model.WebhookDelivery{WebhookID: webhook.ID, OwnerID: 2, EventType: eventType, Payload: []byte(`{"type":"` + eventType + `"}`), Status: model.DeliveryPending, NextAttemptAt: next, CreatedAt: now}
+			}
+			assertError(t, store.Webhooks.CreateDeliveries(alice, []model.WebhookDelivery{
+				delivery("task.updated", now),
+				delivery("task.created", now.Add(-time.Minute)),
+				delivery("task.deleted", now.Add(time.Hour)),
+			}), nil)
+
+			due, err := store.Webhooks.FindDeliveries(ctx, model.DeliveryFilter{Status: model.DeliveryPending, DueBy: &now}, 10)
+			assertError(t, err, nil)
+			if len(due) != 2 || due[0].EventType != "task.created" || due[1].EventType != "task.updated" {
+				t.Fatalf("expected the two due deliveries, soonest first, got %+v", due)
+			}
+			if string(due[0].Payload) != `{"type":"task.created"}` || !due[0].NextAttemptAt.Equal(now.Add(-time.Minute)) || due[0].DeliveredAt != nil {
+				t.Errorf("expected the delivery as it was stored, got %+v", due[0])
+			}
+
+			next := now.Add(30 * time.Second)
+			claimed, err := store.Webhooks.ClaimDelivery(ctx, due[0].ID, 0, next)
+			assertError(t, err, nil)
+			if !claimed {
+				t.Fatalf("expected the first claim to win")
+			}
+			claimed, err = store.Webhooks.ClaimDelivery(ctx, due[0].ID, 0, next)
+			assertError(t, err, nil)
+			if claimed {
+				t.Errorf("expected the second claim at the same attempt to lose")
+			}
+
+			done := due[0]
+			done.Status, done.Attempts, done.DeliveredAt = model.DeliveryDone, 1, &now
+			assertError(t, store.Webhooks.UpdateDelivery(bob, model.WebhookDelivery{ID: due[1].ID, Status: model.DeliveryDead}), nil)
+			assertError(t, store.Webhooks.UpdateDelivery(alice, done), nil)
+
+			listed, err := store.Webhooks.FindDeliveries(alice, model.DeliveryFilter{WebhookID: webhook.ID}, 10)
+			assertError(t, err, nil)
+			if len(listed) != 3 || listed[0].EventType != "task.deleted" || listed[1].ID != done.ID {
+				t.Fatalf("expected the deliveries of the webhook, newest first, got %+v", listed)
+			}
+			if got := listed[1]; got.Status != model.DeliveryDone || got.Attempts != 1 || got.DeliveredAt == nil || !got.DeliveredAt.Equal(now) {
+				t.Errorf("expected the delivery to be done, got %+v", got)
+			}
+			if listed[2].Status != model.DeliveryPending {
+				t.Errorf("expected bob to leave alice's delivery alone, got %+v", listed[2])
+			}
+			dead, err := store.Webhooks.FindDeliveries(alice, model.DeliveryFilter{ID: done.ID, Status: model.DeliveryDone}, 1)
+			assertError(t, err, nil)
+			if len(dead) != 1 {
+				t.Errorf("expected to find the delivery by id and status, got %+v", dead)
+			}
+			hidden, err := store.Webhooks.FindDeliveries(bob, model.DeliveryFilter{ID: done.ID}, 1)
+			assertError(t, err, nil)
+			if len(hidden) != 0 {
+				t.Errorf("expected bob not to see alice's deliveries, got %+v", hidden)
+			}
+		},
+	},
+	{
+		caseName: "transaction commits when its function succeeds",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			existing := mustCreate(ctx, t, store.Tasks, model.Task{Name: "existing"})
+
+			var created model.Task
+			err := store.Tasks.Transaction(ctx, func(tx repository.Store) error {
+				created = mustCreate(ctx, t, tx.Tasks, model.Task{Name: "created"})
+				return tx.Tasks.Delete(ctx, existing.ID, existing.Version)
+			})
+			assertError(t, err, nil)
+
+			page, err := store.Tasks.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{created})
+		},
+	},
+	{
+		caseName: "transaction rolls back when its function fails",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			existing := mustCreate(ctx, t, store.Tasks, model.Task{Name: "existing"})
+
+			err := store.Tasks.Transaction(ctx, func(tx repository.Store) error {
+				mustCreate(ctx, t, tx.Tasks, model.Task{Name: "created"})
+				if err := tx.Tasks.Delete(ctx, existing.ID, existing.Version); err != nil {
+					return err
+				}
+				modified := existing
+				modified.Name = "renamed"
+				_, err := tx.Tasks.Update(ctx, modified)
+				return err
+			})
+			assertError(t, err, model.ErrTaskNotFound)
+
+			page, err := store.Tasks.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{existing})
+		},
+	},
+	{
+		caseName: "transaction rolls back the other repositories with the tasks",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			now := model.Now()
+			err := store.Tasks.Transaction(ctx, func(tx repository.Store) error {
+				created := mustCreate(ctx, t, tx.Tasks, model.Task{Name: "created"})
+				if err := tx.Idempotency.CreateIdempotencyKey(ctx, model.IdempotencyKey{Key: "retry-1", Fingerprint: "abc", Task: created, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+					return err
+				}
+				if err := tx.Audit.CreateAuditRecord(ctx, model.AuditRecord{TaskID: created.ID, Operation: model.AuditCreate, After: &created, CreatedAt: now}); err != nil {
+					return err
+				}
+				if _, err := tx.Projects.CreateProject(ctx, model.Project{Name: "home", CreatedAt: now, UpdatedAt: now}); err != nil {
+					return err
+				}
+				if _, err := tx.Webhooks.CreateWebhook(ctx, model.Webhook{URL: "http://example.com/hook", Secret: "secret", CreatedAt: now}); err != nil {
+					return err
+				}
+				return model.ErrTaskVersionMismatch
+			})
+			assertError(t, err, model.ErrTaskVersionMismatch)
+
+			_, err = store.Idempotency.FindIdempotencyKey(ctx, "retry-1")
+			assertError(t, err, model.ErrIdempotencyKeyNotFound)
+			history, err := store.Audit.FindAuditRecords(ctx, model.AuditFilter{}, model.PageRequest{})
+			assertError(t, err, nil)
+			projects, err := store.Projects.FindProjects(ctx, false)
+			assertError(t, err, nil)
+			webhooks, err := store.Webhooks.FindWebhooks(ctx)
+			assertError(t, err, nil)
+			if len(history.Records) != 0 || len(projects) != 0 || len(webhooks) != 0 {
+				t.Errorf("expected nothing to be left, got %+v, %+v and %+v", history.Records, projects, webhooks)
+			}
+		},
+	},
+	{
+		caseName: "transaction inside another one is rolled back with it",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			err := store.Tasks.Transaction(ctx, func(tx repository.Store) error {
+				if err := tx.Tasks.Transaction(ctx, func(inner repository.Store) error {
+					mustCreate(ctx, t, inner.Tasks, model.Task{Name: "created"})
+					return nil
+				}); err != nil {
+					return err
+				}
+				return model.ErrTaskVersionMismatch
+			})
+			assertError(t, err, model.ErrTaskVersionMismatch)
+
+			page, err := store.Tasks.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{})
+		},
+	},
+	{
+		caseName: "failed transaction inside another one rolls back only its own writes",
+		run: func(ctx context.Context, t *testing.T, store repository.Store) {
+			var kept, after model.Task
+			err := store.Tasks.Transaction(ctx, func(tx repository.Store) error {
+				kept = mustCreate(ctx, t, tx.Tasks, model.Task{Name: "kept"})
+				err := tx.Tasks.Transaction(ctx, func(inner repository.Store) error {
+					mustCreate(ctx, t, inner.Tasks, model.Task{Name: "dropped"})
+					renamed := kept
+					renamed.Name = "renamed"
+					if _, err := inner.Tasks.Update(ctx, renamed); err != nil {
+						return err
+					}
+					return model.ErrTaskVersionMismatch
+				})
+				assertError(t, err, model.ErrTaskVersionMismatch)
+				after = mustCreate(ctx, t, tx.Tasks, model.Task{Name: "after"})
+				return nil
+			})
+			assertError(t, err, nil)
+
+			page, err := store.Tasks.FindAll(ctx, 0, model.PageRequest{})
+			assertError(t, err, nil)
+			assertTasks(t, page.Tasks, []model.Task{kept, after})
+		},
+	},
+}
+
+func Run(t *testing.T, newRepository Factory) {
+	t.Helper()
+
+	for _, testCase := range conformanceCases {
+		testCase := testCase
+		t.Run(testCase.caseName, func(t *testing.T) {
+			store := newRepository(t)
+			defer store.Tasks.Close()
+
+			testCase.run(context.Background(), t, store.Tasks)
+		})
+	}
+	for _, testCase := range storeCases {
+		testCase := testCase
+		t.Run(testCase.caseName, func(t *testing.T) {
+			store := newRepository(t)
+			defer store.Tasks.Close()
+
+			testCase.run(context.Background(), t, store)
+		})
+	}
+}
+
+func mustCreate(ctx context.Context, t testing.TB, repo repository.Task, task model.Task) model.Task {
+	t.Helper()
+
+	created, err := repo.Create(ctx, task)
+	if err != nil {
+		t.Fatalf("Error was not expected while creating task, got %s", err)
+	}
+	return created
+}
+
+func mustParse(t testing.TB, filter, orderBy string) query.Query {
+	t.Helper()
+
+	q, err := query.Parse(filter, orderBy)
+	if err != nil {
+		t.Fatalf("Error was not expected while parsing the query, got %s", err)
+	}
+	return q
+}
+
+func assertError(t testing.TB, got, want error) {
+	t.Helper()
+	if !errors.Is(got, want) {
+		t.Fatalf("got error %v want %v", got, want)
+	}
+}
+
+func assertTask(t testing.TB, got, want model.Task) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v want %+v", got, want)
+	}
+}
+
+func assertTasks(t testing.TB, got, want []model.Task) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("got a nil slice want %+v", want)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v want %+v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package repositorytest
+
+import (
+	"context"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// UserFactory returns a user repository holding only the default user.
+type UserFactory func(t *testing.T) repository.User
+
+var userConformanceCases = []struct {
+	caseName string
+	run      func(ctx context.Context, t *testing.T, repo repository.User)
+}{
+	{
+		caseName: "the default user exists",
+		run: func(ctx context.Context, t *testing.T, repo repository.User) {
+			user, err := repo.FindByName(ctx, "default")
+			assertError(t, err, nil)
+			if user.ID != model.DefaultUserID {
+				t.Errorf("expected id %d, got %+v", model.DefaultUserID, user)
+			}
+		},
+	},
+	{
+		caseName: "create and find by id and by name",
+		run: func(ctx context.Context, t *testing.T, repo repository.User) {
+			created, err := repo.Create(ctx, model.User{Name: "alice", PasswordHash: "hash"})
+			assertError(t, err, nil)
+			if created.ID == 0 || created.CreatedAt.IsZero() {
+				t.Fatalf("expected an id and a creation time, got %+v", created)
+			}
+
+			found, err := repo.FindByName(ctx, "alice")
+			assertError(t, err, nil)
+			if found != created {
+				t.Errorf("got %+v want %+v", found, created)
+			}
+
+			found, err = repo.FindById(ctx, created.ID)
+			assertError(t, err, nil)
+			if found != created {
+				t.Errorf("got %+v want %+v", found, created)
+			}
+
+			_, err = repo.FindByName(ctx, "bob")
+			assertError(t, err, model.ErrUserNotFound)
+			_, err = repo.FindById(ctx, created.ID+1)
+			assertError(t, err, model.ErrUserNotFound)
+		},
+	},
+	{
+		caseName: "names are unique",
+		run: func(ctx context.Context, t *testing.T, repo repository.User) {
+			_, err := repo.Create(ctx, model.User{Name: "alice", PasswordHash: "hash"})
+			assertError(t, err, nil)
+
+			_, err = repo.Create(ctx, model.User{Name: "alice", PasswordHash: "other"})
+			assertError(t, err, model.ErrUserAlreadyExists)
+		},
+	},
+	{
+		caseName: "tokens find their user and are revoked by their owner only",
+		run: func(ctx context.Context, t *testing.T, repo repository.User) {
+			alice, err := repo.Create(ctx, model.User{Name: "alice", PasswordHash: "hash"})
+			assertError(t, err, nil)
+
+			token, err := repo.CreateToken(ctx, model.Token{UserID: alice.ID, Name: "laptop", Hash: "aliceHash"})
+			assertError(t, err, nil)
+
+			found, foundToken, err := repo.FindByToken(ctx, "aliceHash")
+			assertError(t, err, nil)
+			if found.ID != alice.ID || foundToken.ID != token.ID {
+				t.Errorf("expected %+v and %+v, got %+v and %+v", alice, token, found, foundToken)
+			}
+
+			foundToken, err = repo.FindToken(ctx, alice.ID, token.ID)
+			assertError(t, err, nil)
+			if !reflect.DeepEqual(foundToken, token) {
+				t.Errorf("expected %+v, got %+v", token, foundToken)
+			}
+			_, err = repo.FindToken(ctx, model.DefaultUserID, token.ID)
+			assertError(t, err, model.ErrTokenNotFound)
+
+			tokens, err := repo.FindTokens(ctx, alice.ID)
+			assertError(t, err, nil)
+			if len(tokens) != 1 || !reflect.DeepEqual(tokens[0], token) {
+				t.Errorf("expected [%+v], got %+v", token, tokens)
+			}
+
+			assertError(t, repo.DeleteToken(ctx, model.DefaultUserID, token.ID), model.ErrTokenNotFound)
+			assertError(t, repo.DeleteToken(ctx, alice.ID, token.ID), nil)
+
+			_, _, err = repo.FindByToken(ctx, "aliceHash")
+			assertError(t, err, model.ErrUserNotFound)
+			_, err = repo.FindToken(ctx, alice.ID, token.ID)
+			assertError(t, err, model.ErrTokenNotFound)
+
+			tokens, err = repo.FindTokens(ctx, alice.ID)
+			assertError(t, err, nil)
+			if tokens == nil || len(tokens) != 0 {
+				t.Errorf("expected no tokens, got %+v", tokens)
+			}
+		},
+	},
+	{
+		caseName: "tokens keep their scopes, expiry and last use",
+		run: func(ctx context.Context, t *testing.T, repo repository.User) {
+			expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+			token, err := repo.CreateToken(ctx, model.Token{UserID: model.DefaultUserID, Hash: "ciHash", Scopes: model.ScopeTasksRead, ExpiresAt: &expiresAt})
+			assertError(t, err, nil)
+
+			usedAt := time.Date(2029, 1, 2, 3, 4, 5, 0, time.UTC)
+			assertError(t, repo.TouchToken(ctx, token.ID, usedAt), nil)
+
+			_, found, err := repo.FindByToken(ctx, "ciHash")
+			assertError(t, err, nil)
+			token.LastUsedAt = &usedAt
+			if !reflect.DeepEqual(found, token) {
+				t.Errorf("got %+v want %+v", found, token)
+			}
+		},
+	},
+}
+
+func RunUser(t *testing.T, newRepository UserFactory) {
+	t.Helper()
+
+	for _, testCase := range userConformanceCases {
+		testCase := testCase
+		t.Run(testCase.caseName, func(t *testing.T) {
+			testCase.run(context.Background(), t, newRepository(t))
+		})
+	}
+}
@@ -1,14 +1,33 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"gochallenges/internal/migration"
 	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 type TaskSql struct {
-	DB *sql.DB
+	sqlConn
+}
+
+// sqlConn is the connection pool or, inside a transaction, its sql.Tx.
+type sqlConn struct {
+	DB         *sql.DB
+	tx         *sql.Tx
+	savepoints int
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 }
 
 func NewTaskSql(driverName string, connStr string) (Task, error) {
@@ -22,18 +41,48 @@ func NewTaskSql(driverName string, connStr string) (Task, error) {
 		return nil, model.ErrConnectDatabase
 	}
 
-	return &TaskSql{db}, nil
+	dialect, err := migration.DialectFor(driverName)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = migrate(db, dialect); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return NewTaskSqlWithDB(db), nil
 }
 
-func (r *TaskSql) Create(task model.Task) (model.Task, error) {
-	statement, err := r.DB.Prepare("INSERT INTO task (name, completed) VALUES (?, ?)")
+func NewTaskSqlWithDB(db *sql.DB) Task {
+	return &TaskSql{sqlConn{DB: db}}
+}
+
+func (r *TaskSql) Create(ctx context.Context, task model.Task) (model.Task, error) {
+	task = normalizeTask(task)
+	task.CreatedAt = model.Now()
+	task.UpdatedAt = task.CreatedAt
+	task.Version = 1
+	task.OwnerID = ownerOf(ctx)
+
+	insert := "INSERT INTO task (owner_id, parent_id, project_id, name, completed, description, due_at, priority, created_at, updated_at, completed_at, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	args := []any{task.OwnerID, task.ParentID, task.ProjectID, task.Name, task.Completed, task.Description, task.DueAt, task.Priority, task.CreatedAt, task.UpdatedAt, task.CompletedAt, task.Version}
+	if task.ID > 0 {
+		insert = "INSERT INTO task (id, owner_id, parent_id, project_id, name, completed, description, due_at, priority, created_at, updated_at, completed_at, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		args = append([]any{task.ID}, args...)
+	}
+
+	statement, err := r.conn().PrepareContext(ctx, insert)
 	if err != nil {
 		return task, model.ErrPreparingStatemant
 	}
 	defer statement.Close()
 
-	result, err := statement.Exec(task.Name, task.Completed)
+	result, err := statement.ExecContext(ctx, args...)
 	if err != nil {
+		if isDuplicateKeyError(err) {
+			return task, model.ErrTaskAlreadyExists
+		}
 		return task, model.ErrExecuteQuery
 	}
 
@@ -46,97 +95,297 @@ func (r *TaskSql) Create(task model.Task) (model.Task, error) {
 	return task, nil
 }
 
-func (r *TaskSql) FindByID(taskId int) (model.Task, error) {
+// CreateMany inserts row by row, so each task gets the id of its own insert.
+func (r *TaskSql) CreateMany(ctx context.Context, tasks []model.Task) ([]model.Task, error) {
+	return createEach(ctx, r, tasks)
+}
+
+func (r *TaskSql) FindByID(ctx context.Context, taskId int) (model.Task, error) {
 	var task model.Task
 
-	row, err := r.DB.Query("SELECT id, name, completed FROM task WHERE id = ?", taskId)
+	where, args := sqlWhere(taskMatch(ctx, taskId, 0))
+	row, err := r.conn().QueryContext(ctx, "SELECT "+taskColumns+" FROM task WHERE "+where, args...)
 	if err != nil {
 		return task, model.ErrExecuteQuery
 	}
 	defer row.Close()
 
-	if row.Next() {
-		if err := row.Scan(&task.ID, &task.Name, &task.Completed); err != nil {
-			return task, model.ErrScanningRows
+	if !row.Next() {
+		if err := row.Err(); err != nil {
+			return task, model.ErrExecuteQuery
 		}
+		return task, model.ErrTaskNotFound
 	}
 
-	return task, nil
+	return scanTask(row)
 }
 
-func (r *TaskSql) FindByStatus(completed bool) ([]model.Task, error) {
-	rows, err := r.DB.Query("SELECT id, name, completed FROM task WHERE completed = ?", completed)
-	if err != nil {
-		return nil, model.ErrExecuteQuery
-	}
-	defer rows.Close()
+func (r *TaskSql) FindByStatus(ctx context.Context, projectId int, completed bool, page model.PageRequest) (model.TaskPage, error) {
+	return r.Find(ctx, query.Query{Filter: inProject(projectId, query.Comparison{Field: "completed", Op: query.Eq, Value: completed})}, page)
+}
 
-	var tasks []model.Task
-	for rows.Next() {
-		var task model.Task
-		if err := rows.Scan(&task.ID, &task.Name, &task.Completed); err != nil {
-			return nil, model.ErrScanningRows
-		}
-		tasks = append(tasks, task)
-	}
+func (r *TaskSql) FindAll(ctx context.Context, projectId int, page model.PageRequest) (model.TaskPage, error) {
+	return r.Find(ctx, query.Query{Filter: inProject(projectId, nil)}, page)
+}
 
-	return tasks, nil
+func (r *TaskSql) Find(ctx context.Context, q query.Query, page model.PageRequest) (model.TaskPage, error) {
+	return r.find(ctx, query.AndAlso(q.Filter, trashed(false)), pageOrder(q.OrderBy), page)
 }
 
-func (r *TaskSql) FindAll() ([]model.Task, error) {
-	rows, err := r.DB.Query("SELECT id, name, completed FROM task")
+func (r *TaskSql) FindTrash(ctx context.Context, page model.PageRequest) (model.TaskPage, error) {
+	return r.find(ctx, trashed(true), trashOrder, page)
+}
+
+func (r *TaskSql) find(ctx context.Context, filter query.Expr, orders []query.Order, page model.PageRequest) (model.TaskPage, error) {
+	limit, after, err := parsePage(page, orders)
 	if err != nil {
-		return nil, model.ErrExecuteQuery
+		return model.TaskPage{}, err
+	}
+
+	where, args := sqlWhere(ownerFilter(ctx, query.AndAlso(filter, after)))
+	statement := "SELECT " + taskColumns + " FROM task WHERE " + where + " ORDER BY " + sqlOrderBy(orders) + " LIMIT ?"
+
+	rows, err := r.conn().QueryContext(ctx, statement, append(args, limit+1)...)
+	if err != nil {
+		return model.TaskPage{}, model.ErrExecuteQuery
 	}
 	defer rows.Close()
 
-	var tasks []model.Task
-	for rows.Next() {
-		var task model.Task
-		if err := rows.Scan(&task.ID, &task.Name, &task.Completed); err != nil {
-			return nil, model.ErrScanningRows
-		}
-		tasks = append(tasks, task)
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return model.TaskPage{}, err
 	}
 
-	return tasks, nil
+	return newTaskPage(tasks, limit, orders), nil
 }
 
-func (r *TaskSql) Update(task model.Task) (model.Task, error) {
+// Update only applies when task.Version matches the stored one, unless it is 0.
+func (r *TaskSql) Update(ctx context.Context, task model.Task) (model.Task, error) {
 	var updatedTask model.Task
+	task = normalizeTask(task)
 
-	statement, err := r.DB.Prepare("UPDATE task SET name = ?, completed = ? WHERE id = ?")
+	where, whereArgs := sqlWhere(taskMatch(ctx, task.ID, task.Version))
+	update := "UPDATE task SET parent_id = ?, project_id = ?, name = ?, completed = ?, description = ?, due_at = ?, priority = ?, updated_at = ?, completed_at = ?, version = version + 1 WHERE " + where
+	args := append([]any{task.ParentID, task.ProjectID, task.Name, task.Completed, task.Description, task.DueAt, task.Priority, model.Now(), task.CompletedAt}, whereArgs...)
+
+	statement, err := r.conn().PrepareContext(ctx, update)
 	if err != nil {
 		return updatedTask, model.ErrPreparingStatemant
 	}
 	defer statement.Close()
 
-	if _, err := statement.Exec(task.Name, task.Completed, task.ID); err != nil {
+	result, err := statement.ExecContext(ctx, args...)
+	if err != nil {
+		return updatedTask, model.ErrExecuteQuery
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
 		return updatedTask, model.ErrExecuteQuery
 	}
 
-	updatedTask, err = r.FindByID(task.ID)
+	updatedTask, err = r.FindByID(ctx, task.ID)
 	if err != nil {
 		return updatedTask, err
 	}
+	if affected == 0 {
+		return updatedTask, model.ErrTaskVersionMismatch
+	}
 
 	return updatedTask, nil
 }
 
-func (r *TaskSql) Delete(id int) error {
-	statement, err := r.DB.Prepare("DELETE FROM task WHERE id = ?")
+// Delete is a no-op for a missing or trashed task.
+func (r *TaskSql) Delete(ctx context.Context, id int, version int) error {
+	where, whereArgs := sqlWhere(taskMatch(ctx, id, version))
+	now := model.Now()
+
+	affected, err := r.exec(ctx, "UPDATE task SET deleted_at = ?, updated_at = ?, version = version + 1 WHERE "+where, append([]any{now, now}, whereArgs...)...)
 	if err != nil {
-		return model.ErrPreparingStatemant
+		return err
 	}
-	defer statement.Close()
 
-	if _, err := statement.Exec(id); err != nil {
+	if affected == 0 && version > 0 {
+		if _, err := r.FindByID(ctx, id); err == nil {
+			return model.ErrTaskVersionMismatch
+		} else if !errors.Is(err, model.ErrTaskNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *TaskSql) FindSubtree(ctx context.Context, id int, inTrash bool) ([]model.Task, error) {
+	statement, args := subtreeQuery(ctx, id, inTrash)
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+taskColumns+" FROM task WHERE id IN ("+statement+") ORDER BY id", args...)
+	if err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+func (r *TaskSql) Restore(ctx context.Context, id int) (model.Task, error) {
+	where, whereArgs := sqlWhere(ownerFilter(ctx, query.AndAlso(query.Comparison{Field: "id", Op: query.Eq, Value: id}, trashed(true))))
+
+	affected, err := r.exec(ctx, "UPDATE task SET deleted_at = NULL, updated_at = ?, version = version + 1 WHERE "+where, append([]any{model.Now()}, whereArgs...)...)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if affected == 0 {
+		return model.Task{}, model.ErrTaskNotFound
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+func (r *TaskSql) Purge(ctx context.Context, before time.Time) (int, error) {
+	where, args := sqlWhere(ownerFilter(ctx, query.Comparison{Field: "deleted_at", Op: query.Lt, Value: normalizeTime(before)}))
+
+	affected, err := r.exec(ctx, "DELETE FROM task WHERE "+where, args...)
+	return int(affected), err
+}
+
+func (c sqlConn) exec(ctx context.Context, statement string, args ...any) (int64, error) {
+	prepared, err := c.conn().PrepareContext(ctx, statement)
+	if err != nil {
+		return 0, model.ErrPreparingStatemant
+	}
+	defer prepared.Close()
+
+	result, err := prepared.ExecContext(ctx, args...)
+	if err != nil {
+		return 0, model.ErrExecuteQuery
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, model.ErrExecuteQuery
+	}
+
+	return affected, nil
+}
+
+func (r *TaskSql) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return r.transaction(ctx, func(tx sqlConn) error {
+		return fn(sqlStore(&TaskSql{tx}, tx))
+	})
+}
+
+// transaction commits when fn returns nil; nested, it is a savepoint of the outer one.
+func (c sqlConn) transaction(ctx context.Context, fn func(tx sqlConn) error) error {
+	if c.tx != nil {
+		return c.savepoint(ctx, fn)
+	}
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return model.ErrExecuteQuery
+	}
+	if err := fn(sqlConn{DB: c.DB, tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
 		return model.ErrExecuteQuery
 	}
 
 	return nil
 }
 
+func (c sqlConn) savepoint(ctx context.Context, fn func(tx sqlConn) error) error {
+	nested := c
+	nested.savepoints++
+	name := fmt.Sprintf("savepoint_%d", nested.savepoints)
+	if _, err := c.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return model.ErrExecuteQuery
+	}
+	if err := fn(nested); err != nil {
+		c.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		c.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+		return err
+	}
+	if _, err := c.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return model.ErrExecuteQuery
+	}
+
+	return nil
+}
+
+func sqlStore(tasks Task, c sqlConn) Store {
+	return Store{
+		Tasks:       tasks,
+		Idempotency: &IdempotencySql{c},
+		Audit:       &AuditSql{c},
+		Webhooks:    &WebhooksSql{c},
+		Projects:    &ProjectsSql{c},
+	}
+}
+
+// subtreeQuery stops at tasks not visible to ctx or on the wrong side of the trash.
+func subtreeQuery(ctx context.Context, id int, inTrash bool) (string, []any) {
+	where, args := sqlWhere(ownerFilter(ctx, trashed(inTrash)))
+	statement := "WITH RECURSIVE subtree (id) AS (" +
+		"SELECT id FROM task WHERE parent_id = ? AND " + where +
+		" UNION SELECT task.id FROM task JOIN subtree ON task.parent_id = subtree.id WHERE " + where +
+		") SELECT id FROM subtree"
+	return statement, append(append([]any{id}, args...), args...)
+}
+
+func (c sqlConn) conn() querier {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.DB
+}
+
 func (r *TaskSql) Close() {
 	r.DB.Close()
 }
+
+func scanTasks(rows *sql.Rows) ([]model.Task, error) {
+	tasks := []model.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, model.ErrScanningRows
+	}
+
+	return tasks, nil
+}
+
+const taskColumns = "id, owner_id, parent_id, project_id, name, completed, description, due_at, priority, created_at, updated_at, completed_at, deleted_at, version"
+
+func scanTask(rows *sql.Rows) (model.Task, error) {
+	var task model.Task
+	var parentId, projectId sql.NullInt64
+	var dueAt, completedAt, deletedAt sql.NullTime
+
+	if err := rows.Scan(&task.ID, &task.OwnerID, &parentId, &projectId, &task.Name, &task.Completed, &task.Description, &dueAt, &task.Priority, &task.CreatedAt, &task.UpdatedAt, &completedAt, &deletedAt, &task.Version); err != nil {
+		return task, model.ErrScanningRows
+	}
+	if parentId.Valid {
+		id := int(parentId.Int64)
+		task.ParentID = &id
+	}
+	if projectId.Valid {
+		id := int(projectId.Int64)
+		task.ProjectID = &id
+	}
+	if dueAt.Valid {
+		task.DueAt = &dueAt.Time
+	}
+	if completedAt.Valid {
+		task.CompletedAt = &completedAt.Time
+	}
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.Time
+	}
+
+	return normalizeTask(task), nil
+}
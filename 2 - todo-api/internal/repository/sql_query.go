@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"gochallenges/internal/query"
+	"strings"
+)
+
+// likeEscape avoids backslashes, which MySQL and SQLite write differently.
+const likeEscape = "!"
+
+var likeEscaper = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+// sqlWhere inlines field names, which come from query.Fields, never from user input.
+func sqlWhere(expr query.Expr) (string, []any) {
+	switch e := expr.(type) {
+	case nil:
+		return "1 = 1", nil
+	case query.Comparison:
+		if e.Value == nil {
+			if e.Op == query.Ne {
+				return e.Field + " IS NOT NULL", nil
+			}
+			return e.Field + " IS NULL", nil
+		}
+		if e.Op == query.Contains {
+			return e.Field + " LIKE ? ESCAPE '" + likeEscape + "'", []any{containsPattern(e.Value)}
+		}
+		return e.Field + " " + string(e.Op) + " ?", []any{e.Value}
+	case query.And:
+		return sqlJoin("AND", e.Left, e.Right)
+	case query.Or:
+		return sqlJoin("OR", e.Left, e.Right)
+	case query.Not:
+		where, args := sqlWhere(e.Expr)
+		return "NOT (" + where + ")", args
+	}
+	panic("unknown filter expression")
+}
+
+func sqlJoin(op string, left, right query.Expr) (string, []any) {
+	leftWhere, leftArgs := sqlWhere(left)
+	rightWhere, rightArgs := sqlWhere(right)
+	return "(" + leftWhere + ") " + op + " (" + rightWhere + ")", append(leftArgs, rightArgs...)
+}
+
+func sqlOrderBy(orders []query.Order) string {
+	parts := make([]string, 0, len(orders))
+	for _, order := range orders {
+		if order.Desc {
+			parts = append(parts, order.Field+" DESC")
+		} else {
+			parts = append(parts, order.Field+" ASC")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func containsPattern(value any) string {
+	s, _ := value.(string)
+	return "%" + likeEscaper.Replace(s) + "%"
+}
@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"context"
 	"database/sql"
 	"gochallenges/internal/model"
 	"gochallenges/internal/repository"
@@ -26,17 +27,17 @@ func NewMock() (*sql.DB, sqlmock.Sqlmock) {
 
 func TestCreate(t *testing.T) {
 	db, mock := NewMock()
-	repo := &repository.TaskSql{db}
+	repo := repository.NewTaskSqlWithDB(db)
 	defer func() {
 		repo.Close()
 	}()
 
-	query := "INSERT INTO task \\(name, completed\\) VALUES \\(\\?, \\?\\)"
+	query := "INSERT INTO task \\(owner_id, parent_id, project_id, name, completed, description, due_at, priority, created_at, updated_at, completed_at, version\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)"
 
 	prep := mock.ExpectPrepare(query)
-	prep.ExpectExec().WithArgs(taskMock.Name, taskMock.Completed).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithArgs(model.DefaultUserID, nil, nil, taskMock.Name, taskMock.Completed, taskMock.Description, nil, taskMock.Priority, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 1).WillReturnResult(sqlmock.NewResult(int64(taskMock.ID), 1))
 
-	task, err := repo.Create(taskMock)
+	task, err := repo.Create(context.Background(), model.Task{Name: taskMock.Name, Completed: taskMock.Completed})
 	if err != nil {
 		t.Errorf("Error was not expected while creating task, got %s", err)
 	}
@@ -47,17 +48,19 @@ func TestCreate(t *testing.T) {
 
 func TestFindByID(t *testing.T) {
 	db, mock := NewMock()
-	repo := &repository.TaskSql{db}
+	repo := repository.NewTaskSqlWithDB(db)
 	defer func() {
 		repo.Close()
 	}()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "completed"}).AddRow(taskMock.ID, taskMock.Name, taskMock.Completed)
+	now := model.Now()
+	rows := sqlmock.NewRows([]string{"id", "owner_id", "parent_id", "project_id", "name", "completed", "description", "due_at", "priority", "created_at", "updated_at", "completed_at", "deleted_at", "version"}).
+		AddRow(taskMock.ID, model.DefaultUserID, nil, nil, taskMock.Name, taskMock.Completed, taskMock.Description, nil, taskMock.Priority, now, now, nil, nil, 1)
 
-	query := "SELECT id, name, completed FROM task WHERE id = \\?"
+	query := "SELECT id, owner_id, parent_id, project_id, name, completed, description, due_at, priority, created_at, updated_at, completed_at, deleted_at, version FROM task WHERE \\(id = \\?\\) AND \\(deleted_at IS NULL\\)"
 	mock.ExpectQuery(query).WithArgs(taskMock.ID).WillReturnRows(rows)
 
-	_, err := repo.FindByID(taskMock.ID)
+	_, err := repo.FindByID(context.Background(), taskMock.ID)
 	if err != nil {
 		t.Errorf("Error was not expected while creating task, got %s", err)
 	}
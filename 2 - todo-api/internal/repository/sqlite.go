@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"database/sql"
+	"gochallenges/internal/migration"
+	"gochallenges/internal/model"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteDriver = "sqlite3"
+const SqliteInMemory = ":memory:"
+
+type TaskSqlite struct {
+	TaskSql
+}
+
+func NewTaskSqlite(path string) (Task, error) {
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, model.ErrConnectDatabase
+	}
+
+	// sqlite has a single writer and each ":memory:" connection is a new database.
+	db.SetMaxOpenConns(1)
+
+	if err = db.Ping(); err != nil {
+		return nil, model.ErrConnectDatabase
+	}
+
+	if err = migrate(db, migration.SQLite); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &TaskSqlite{TaskSql{sqlConn{DB: db}}}, nil
+}
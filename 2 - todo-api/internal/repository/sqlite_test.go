@@ -0,0 +1,80 @@
+package repository_test
+
+import (
+	"context"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"reflect"
+	"testing"
+)
+
+func TestSqliteInMemory(t *testing.T) {
+	ctx := context.Background()
+	repo, err := repository.NewTaskSqlite(repository.SqliteInMemory)
+	if err != nil {
+		t.Fatalf("Error was not expected while opening sqlite, got %s", err)
+	}
+	defer repo.Close()
+
+	created, err := repo.Create(ctx, taskMock)
+	if err != nil {
+		t.Fatalf("Error was not expected while creating task, got %s", err)
+	}
+	if created.ID != taskMock.ID {
+		t.Errorf("Expected task ID to be %d, got %d", taskMock.ID, created.ID)
+	}
+
+	created.Completed = true
+	updated, err := repo.Update(ctx, created)
+	if err != nil {
+		t.Fatalf("Error was not expected while updating task, got %s", err)
+	}
+
+	completed, err := repo.FindByStatus(ctx, 0, true, model.PageRequest{})
+	if err != nil {
+		t.Fatalf("Error was not expected while finding tasks, got %s", err)
+	}
+	if !reflect.DeepEqual(completed.Tasks, []model.Task{updated}) {
+		t.Errorf("Expected %v, got %v", []model.Task{updated}, completed.Tasks)
+	}
+
+	if err := repo.Delete(ctx, updated.ID, updated.Version); err != nil {
+		t.Fatalf("Error was not expected while deleting task, got %s", err)
+	}
+
+	page, err := repo.FindAll(ctx, 0, model.PageRequest{})
+	if err != nil {
+		t.Fatalf("Error was not expected while finding tasks, got %s", err)
+	}
+	if len(page.Tasks) != 0 {
+		t.Errorf("Expected no tasks, got %v", page.Tasks)
+	}
+}
+
+func TestSqliteFileSchemaIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/todoapi.db"
+
+	repo, err := repository.NewTaskSqlite(path)
+	if err != nil {
+		t.Fatalf("Error was not expected while opening sqlite, got %s", err)
+	}
+	if _, err := repo.Create(ctx, taskMock); err != nil {
+		t.Fatalf("Error was not expected while creating task, got %s", err)
+	}
+	repo.Close()
+
+	repo, err = repository.NewTaskSqlite(path)
+	if err != nil {
+		t.Fatalf("Error was not expected while reopening sqlite, got %s", err)
+	}
+	defer repo.Close()
+
+	page, err := repo.FindAll(ctx, 0, model.PageRequest{})
+	if err != nil {
+		t.Fatalf("Error was not expected while finding tasks, got %s", err)
+	}
+	if len(page.Tasks) != 1 {
+		t.Errorf("Expected the task to survive a reopen, got %v", page.Tasks)
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gochallenges/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserOrm struct {
+	db *gorm.DB
+}
+
+func NewUserOrm(db *gorm.DB) User {
+	return &UserOrm{db}
+}
+
+func (r *UserOrm) Create(ctx context.Context, user model.User) (model.User, error) {
+	newUser := user
+	newUser.CreatedAt = model.Now()
+	if err := r.db.WithContext(ctx).Create(&newUser).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return user, model.ErrUserAlreadyExists
+		}
+		return user, model.ErrInsertingRow
+	}
+
+	return newUser, nil
+}
+
+func (r *UserOrm) FindById(ctx context.Context, id int) (model.User, error) {
+	return r.findUser(r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+func (r *UserOrm) FindByName(ctx context.Context, name string) (model.User, error) {
+	return r.findUser(r.db.WithContext(ctx).Where("name = ?", name))
+}
+
+func (r *UserOrm) FindByToken(ctx context.Context, hash string) (model.User, model.Token, error) {
+	token, err := r.findToken(r.db.WithContext(ctx).Where("hash = ?", hash))
+	if err == model.ErrTokenNotFound {
+		return model.User{}, token, model.ErrUserNotFound
+	}
+	if err != nil {
+		return model.User{}, token, err
+	}
+
+	user, err := r.FindById(ctx, token.UserID)
+	return user, token, err
+}
+
+func (r *UserOrm) CreateToken(ctx context.Context, token model.Token) (model.Token, error) {
+	newToken := token
+	newToken.CreatedAt = model.Now()
+	newToken = normalizeToken(newToken)
+	if err := r.db.WithContext(ctx).Create(&newToken).Error; err != nil {
+		return token, model.ErrInsertingRow
+	}
+
+	return newToken, nil
+}
+
+// FindToken returns ErrTokenNotFound for another user's token as well.
+func (r *UserOrm) FindToken(ctx context.Context, userId int, tokenId int) (model.Token, error) {
+	return r.findToken(r.db.WithContext(ctx).Where("id = ? AND user_id = ?", tokenId, userId))
+}
+
+func (r *UserOrm) FindTokens(ctx context.Context, userId int) ([]model.Token, error) {
+	tokens := []model.Token{}
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("id").Find(&tokens).Error; err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+	for i := range tokens {
+		tokens[i] = normalizeToken(tokens[i])
+	}
+
+	return tokens, nil
+}
+
+func (r *UserOrm) TouchToken(ctx context.Context, tokenId int, usedAt time.Time) error {
+	if err := r.db.WithContext(ctx).Model(&model.Token{}).Where("id = ?", tokenId).Update("last_used_at", normalizeTime(usedAt)).Error; err != nil {
+		return model.ErrExecuteQuery
+	}
+	return nil
+}
+
+func (r *UserOrm) DeleteToken(ctx context.Context, userId int, tokenId int) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", tokenId, userId).Delete(&model.Token{})
+	if result.Error != nil {
+		return model.ErrExecuteQuery
+	}
+	if result.RowsAffected == 0 {
+		return model.ErrTokenNotFound
+	}
+
+	return nil
+}
+
+func (r *UserOrm) findUser(db *gorm.DB) (model.User, error) {
+	var user model.User
+	if err := db.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, model.ErrUserNotFound
+		}
+		return user, model.ErrExecuteQuery
+	}
+
+	user.CreatedAt = normalizeTime(user.CreatedAt)
+	return user, nil
+}
+
+func (r *UserOrm) findToken(db *gorm.DB) (model.Token, error) {
+	var token model.Token
+	if err := db.First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return token, model.ErrTokenNotFound
+		}
+		return token, model.ErrExecuteQuery
+	}
+
+	return normalizeToken(token), nil
+}
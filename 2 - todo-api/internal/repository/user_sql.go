@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gochallenges/internal/model"
+	"time"
+)
+
+type UserSql struct {
+	DB *sql.DB
+}
+
+func NewUserSql(db *sql.DB) User {
+	return &UserSql{DB: db}
+}
+
+func (r *UserSql) Create(ctx context.Context, user model.User) (model.User, error) {
+	user.CreatedAt = model.Now()
+
+	result, err := r.DB.ExecContext(ctx, "INSERT INTO users (name, password_hash, created_at) VALUES (?, ?, ?)", user.Name, user.PasswordHash, user.CreatedAt)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return user, model.ErrUserAlreadyExists
+		}
+		return user, model.ErrExecuteQuery
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return user, model.ErrExecuteQuery
+	}
+
+	user.ID = int(newId)
+	return user, nil
+}
+
+func (r *UserSql) FindById(ctx context.Context, id int) (model.User, error) {
+	return r.findUser(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id)
+}
+
+func (r *UserSql) FindByName(ctx context.Context, name string) (model.User, error) {
+	return r.findUser(ctx, "SELECT "+userColumns+" FROM users WHERE name = ?", name)
+}
+
+func (r *UserSql) FindByToken(ctx context.Context, hash string) (model.User, model.Token, error) {
+	token, err := r.findToken(ctx, "SELECT "+tokenColumns+" FROM user_token WHERE hash = ?", hash)
+	if err == model.ErrTokenNotFound {
+		return model.User{}, token, model.ErrUserNotFound
+	}
+	if err != nil {
+		return model.User{}, token, err
+	}
+
+	user, err := r.FindById(ctx, token.UserID)
+	return user, token, err
+}
+
+func (r *UserSql) CreateToken(ctx context.Context, token model.Token) (model.Token, error) {
+	token.CreatedAt = model.Now()
+	token = normalizeToken(token)
+
+	result, err := r.DB.ExecContext(ctx, "INSERT INTO user_token (user_id, name, hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)", token.UserID, token.Name, token.Hash, token.Scopes, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return token, model.ErrInsertingRow
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return token, model.ErrExecuteQuery
+	}
+
+	token.ID = int(newId)
+	return token, nil
+}
+
+// FindToken returns ErrTokenNotFound for another user's token as well.
+func (r *UserSql) FindToken(ctx context.Context, userId int, tokenId int) (model.Token, error) {
+	return r.findToken(ctx, "SELECT "+tokenColumns+" FROM user_token WHERE id = ? AND user_id = ?", tokenId, userId)
+}
+
+func (r *UserSql) FindTokens(ctx context.Context, userId int) ([]model.Token, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+tokenColumns+" FROM user_token WHERE user_id = ? ORDER BY id", userId)
+	if err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	tokens := []model.Token{}
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, model.ErrScanningRows
+	}
+
+	return tokens, nil
+}
+
+func (r *UserSql) TouchToken(ctx context.Context, tokenId int, usedAt time.Time) error {
+	if _, err := r.DB.ExecContext(ctx, "UPDATE user_token SET last_used_at = ? WHERE id = ?", normalizeTime(usedAt), tokenId); err != nil {
+		return model.ErrExecuteQuery
+	}
+	return nil
+}
+
+func (r *UserSql) DeleteToken(ctx context.Context, userId int, tokenId int) error {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM user_token WHERE id = ? AND user_id = ?", tokenId, userId)
+	if err != nil {
+		return model.ErrExecuteQuery
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return model.ErrExecuteQuery
+	}
+	if affected == 0 {
+		return model.ErrTokenNotFound
+	}
+
+	return nil
+}
+
+const userColumns = "id, name, password_hash, created_at"
+const tokenColumns = "id, user_id, name, hash, scopes, expires_at, last_used_at, created_at"
+
+func (r *UserSql) findUser(ctx context.Context, query string, args ...any) (model.User, error) {
+	var user model.User
+
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.Name, &user.PasswordHash, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return user, model.ErrUserNotFound
+	}
+	if err != nil {
+		return user, model.ErrExecuteQuery
+	}
+
+	user.CreatedAt = normalizeTime(user.CreatedAt)
+	return user, nil
+}
+
+func (r *UserSql) findToken(ctx context.Context, query string, args ...any) (model.Token, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return model.Token{}, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return model.Token{}, model.ErrScanningRows
+		}
+		return model.Token{}, model.ErrTokenNotFound
+	}
+	return scanToken(rows)
+}
+
+func scanToken(rows *sql.Rows) (model.Token, error) {
+	var token model.Token
+	var expiresAt, lastUsedAt sql.NullTime
+
+	if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.Hash, &token.Scopes, &expiresAt, &lastUsedAt, &token.CreatedAt); err != nil {
+		return token, model.ErrScanningRows
+	}
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return normalizeToken(token), nil
+}
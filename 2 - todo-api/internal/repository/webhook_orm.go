@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type webhookRow struct {
+	ID        int
+	OwnerID   int
+	URL       string
+	Events    string
+	Secret    string
+	CreatedAt time.Time
+}
+
+func (webhookRow) TableName() string {
+	return "webhook"
+}
+
+type webhookDeliveryRow struct {
+	ID            int
+	WebhookID     int
+	OwnerID       int
+	EventType     string
+	Payload       string
+	Status        model.DeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+}
+
+func (webhookDeliveryRow) TableName() string {
+	return "webhook_delivery"
+}
+
+type WebhooksOrm struct {
+	db *gorm.DB
+}
+
+func (r *WebhooksOrm) CreateWebhook(ctx context.Context, webhook model.Webhook) (model.Webhook, error) {
+	webhook = normalizeWebhook(webhook)
+	webhook.OwnerID = ownerOf(ctx)
+
+	row := webhookRow{
+		OwnerID:   webhook.OwnerID,
+		URL:       webhook.URL,
+		Events:    webhookEvents(webhook.Events),
+		Secret:    webhook.Secret,
+		CreatedAt: webhook.CreatedAt,
+	}
+	if err := r.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return webhook, model.ErrInsertingRow
+	}
+
+	webhook.ID = row.ID
+	return webhook, nil
+}
+
+func (r *WebhooksOrm) FindWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	db := r.db.WithContext(ctx).Order("id")
+	if match := ownerFilter(ctx, nil); match != nil {
+		db = db.Clauses(ormWhereClause(match))
+	}
+
+	var rows []webhookRow
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+
+	webhooks := make([]model.Webhook, len(rows))
+	for i, row := range rows {
+		webhooks[i] = normalizeWebhook(model.Webhook{
+			ID:        row.ID,
+			OwnerID:   row.OwnerID,
+			URL:       row.URL,
+			Events:    parseWebhookEvents(row.Events),
+			Secret:    row.Secret,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhooksOrm) DeleteWebhook(ctx context.Context, id int) error {
+	return ormTransaction(ctx, r.db, func(tx *gorm.DB) error {
+		conn := tx.WithContext(ctx)
+		match := ownerFilter(ctx, query.Comparison{Field: "id", Op: query.Eq, Value: id})
+		result := conn.Clauses(ormWhereClause(match)).Delete(&webhookRow{})
+		if result.Error != nil {
+			return model.ErrExecuteQuery
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrWebhookNotFound
+		}
+		if err := conn.Where("webhook_id = ?", id).Delete(&webhookDeliveryRow{}).Error; err != nil {
+			return model.ErrExecuteQuery
+		}
+		return nil
+	})
+}
+
+func (r *WebhooksOrm) CreateDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	rows := make([]webhookDeliveryRow, len(deliveries))
+	for i, delivery := range deliveries {
+		delivery = normalizeDelivery(delivery)
+		rows[i] = webhookDeliveryRow{
+			WebhookID:     delivery.WebhookID,
+			OwnerID:       delivery.OwnerID,
+			EventType:     delivery.EventType,
+			Payload:       string(delivery.Payload),
+			Status:        delivery.Status,
+			Attempts:      delivery.Attempts,
+			NextAttemptAt: delivery.NextAttemptAt,
+			LastError:     delivery.LastError,
+			CreatedAt:     delivery.CreatedAt,
+			DeliveredAt:   delivery.DeliveredAt,
+		}
+	}
+	if err := r.db.WithContext(ctx).Create(&rows).Error; err != nil {
+		return model.ErrInsertingRow
+	}
+
+	return nil
+}
+
+func (r *WebhooksOrm) FindDeliveries(ctx context.Context, filter model.DeliveryFilter, limit int) ([]model.WebhookDelivery, error) {
+	db := r.db.WithContext(ctx).Clauses(ormOrderBy(deliveryOrder(filter))).Limit(limit)
+	if match := deliveryMatch(ctx, filter); match != nil {
+		db = db.Clauses(ormWhereClause(match))
+	}
+
+	var rows []webhookDeliveryRow
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+
+	deliveries := make([]model.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = normalizeDelivery(model.WebhookDelivery{
+			ID:            row.ID,
+			WebhookID:     row.WebhookID,
+			OwnerID:       row.OwnerID,
+			EventType:     row.EventType,
+			Payload:       []byte(row.Payload),
+			Status:        row.Status,
+			Attempts:      row.Attempts,
+			NextAttemptAt: row.NextAttemptAt,
+			LastError:     row.LastError,
+			CreatedAt:     row.CreatedAt,
+			DeliveredAt:   row.DeliveredAt,
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhooksOrm) ClaimDelivery(ctx context.Context, id int, attempts int, next time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&webhookDeliveryRow{}).
+		Where("id = ? AND status = ? AND attempts = ?", id, model.DeliveryPending, attempts).
+		Updates(map[string]any{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": normalizeTime(next),
+		})
+	if result.Error != nil {
+		return false, model.ErrExecuteQuery
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *WebhooksOrm) UpdateDelivery(ctx context.Context, delivery model.WebhookDelivery) error {
+	delivery = normalizeDelivery(delivery)
+	match := ownerFilter(ctx, query.Comparison{Field: "id", Op: query.Eq, Value: delivery.ID})
+	result := r.db.WithContext(ctx).Model(&webhookDeliveryRow{}).Clauses(ormWhereClause(match)).Updates(map[string]any{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"last_error":      delivery.LastError,
+		"delivered_at":    delivery.DeliveredAt,
+	})
+	if result.Error != nil {
+		return model.ErrExecuteQuery
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gochallenges/internal/model"
+	"gochallenges/internal/query"
+	"time"
+)
+
+const webhookColumns = "id, owner_id, url, events, secret, created_at"
+const deliveryColumns = "id, webhook_id, owner_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at"
+
+type WebhooksSql struct {
+	sqlConn
+}
+
+func (r *WebhooksSql) CreateWebhook(ctx context.Context, webhook model.Webhook) (model.Webhook, error) {
+	webhook = normalizeWebhook(webhook)
+	webhook.OwnerID = ownerOf(ctx)
+
+	insert := "INSERT INTO webhook (owner_id, url, events, secret, created_at) VALUES (?, ?, ?, ?, ?)"
+	result, err := r.conn().ExecContext(ctx, insert, webhook.OwnerID, webhook.URL, webhookEvents(webhook.Events), webhook.Secret, webhook.CreatedAt)
+	if err != nil {
+		return webhook, model.ErrInsertingRow
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return webhook, model.ErrExecuteQuery
+	}
+
+	webhook.ID = int(id)
+	return webhook, nil
+}
+
+func (r *WebhooksSql) FindWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	where, args := sqlWhere(ownerFilter(ctx, nil))
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		var webhook model.Webhook
+		var events string
+		if err := rows.Scan(&webhook.ID, &webhook.OwnerID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt); err != nil {
+			return nil, model.ErrScanningRows
+		}
+		webhook.Events = parseWebhookEvents(events)
+		webhooks = append(webhooks, normalizeWebhook(webhook))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, model.ErrScanningRows
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhooksSql) DeleteWebhook(ctx context.Context, id int) error {
+	return r.transaction(ctx, func(conn sqlConn) error {
+		where, args := sqlWhere(ownerFilter(ctx, query.Comparison{Field: "id", Op: query.Eq, Value: id}))
+		affected, err := conn.exec(ctx, "DELETE FROM webhook WHERE "+where, args...)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return model.ErrWebhookNotFound
+		}
+		_, err = conn.exec(ctx, "DELETE FROM webhook_delivery WHERE webhook_id = ?", id)
+		return err
+	})
+}
+
+func (r *WebhooksSql) CreateDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	statement, err := r.conn().PrepareContext(ctx, "INSERT INTO webhook_delivery (webhook_id, owner_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return model.ErrPreparingStatemant
+	}
+	defer statement.Close()
+
+	for _, delivery := range deliveries {
+		delivery = normalizeDelivery(delivery)
+		_, err := statement.ExecContext(ctx, delivery.WebhookID, delivery.OwnerID, delivery.EventType, string(delivery.Payload), string(delivery.Status), delivery.Attempts, delivery.NextAttemptAt, delivery.LastError, delivery.CreatedAt, delivery.DeliveredAt)
+		if err != nil {
+			return model.ErrInsertingRow
+		}
+	}
+
+	return nil
+}
+
+func (r *WebhooksSql) FindDeliveries(ctx context.Context, filter model.DeliveryFilter, limit int) ([]model.WebhookDelivery, error) {
+	where, args := sqlWhere(deliveryMatch(ctx, filter))
+	statement := "SELECT " + deliveryColumns + " FROM webhook_delivery WHERE " + where + " ORDER BY " + sqlOrderBy(deliveryOrder(filter)) + " LIMIT ?"
+
+	rows, err := r.conn().QueryContext(ctx, statement, append(args, limit)...)
+	if err != nil {
+		return nil, model.ErrExecuteQuery
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		var delivery model.WebhookDelivery
+		var payload string
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.OwnerID, &delivery.EventType, &payload, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError, &delivery.CreatedAt, &deliveredAt); err != nil {
+			return nil, model.ErrScanningRows
+		}
+		delivery.Payload = []byte(payload)
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, normalizeDelivery(delivery))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, model.ErrScanningRows
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhooksSql) ClaimDelivery(ctx context.Context, id int, attempts int, next time.Time) (bool, error) {
+	update := "UPDATE webhook_delivery SET attempts = attempts + 1, next_attempt_at = ? WHERE id = ? AND status = ? AND attempts = ?"
+	affected, err := r.exec(ctx, update, normalizeTime(next), id, string(model.DeliveryPending), attempts)
+	return affected == 1, err
+}
+
+func (r *WebhooksSql) UpdateDelivery(ctx context.Context, delivery model.WebhookDelivery) error {
+	delivery = normalizeDelivery(delivery)
+	where, args := sqlWhere(ownerFilter(ctx, query.Comparison{Field: "id", Op: query.Eq, Value: delivery.ID}))
+	update := "UPDATE webhook_delivery SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, delivered_at = ? WHERE " + where
+	_, err := r.exec(ctx, update, append([]any{string(delivery.Status), delivery.Attempts, delivery.NextAttemptAt, delivery.LastError, delivery.DeliveredAt}, args...)...)
+	return err
+}
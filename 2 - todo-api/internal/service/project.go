@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+)
+
+type Project struct {
+	taskRepository    repository.Task
+	projectRepository repository.Projects
+	// tasks changes tasks one by one, so they are audited and published.
+	tasks Task
+}
+
+func NewProject(store repository.Store, events *event.Bus) Project {
+	return Project{taskRepository: store.Tasks, projectRepository: store.Projects, tasks: NewTask(store, events)}
+}
+
+func (s *Project) Create(ctx context.Context, project model.Project) (model.Project, error) {
+	if err := validateProject(project); err != nil {
+		return project, err
+	}
+
+	now := model.Now()
+	project.ID, project.CreatedAt, project.UpdatedAt, project.ArchivedAt = 0, now, now, nil
+	return s.projectRepository.CreateProject(ctx, project)
+}
+
+func (s *Project) List(ctx context.Context, archived bool) ([]model.Project, error) {
+	return s.projectRepository.FindProjects(ctx, archived)
+}
+
+func (s *Project) Find(ctx context.Context, id int) (model.Project, error) {
+	if id <= 0 {
+		return model.Project{}, model.ErrInvalidProjectId
+	}
+	return s.projectRepository.FindProject(ctx, id)
+}
+
+func (s *Project) Update(ctx context.Context, project model.Project) (model.Project, error) {
+	if err := validateProject(project); err != nil {
+		return project, err
+	}
+
+	stored, err := s.Find(ctx, project.ID)
+	if err != nil {
+		return project, err
+	}
+	stored.Name, stored.Description, stored.UpdatedAt = project.Name, project.Description, model.Now()
+	return s.projectRepository.UpdateProject(ctx, stored)
+}
+
+// Delete archives the project by default, or deletes it and deals with its tasks by mode.
+func (s *Project) Delete(ctx context.Context, id int, mode model.ProjectDeleteMode, toId *int) error {
+	if !mode.Valid() {
+		return fmt.Errorf("%w %q", model.ErrInvalidProjectDeleteMode, mode)
+	}
+	if mode != model.ProjectReassign && toId != nil {
+		return fmt.Errorf("%w: only reassign moves the tasks to another project", model.ErrInvalidProjectDeleteMode)
+	}
+	project, err := s.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case model.ProjectCascade:
+		return s.tasks.inTransaction(ctx, func(tx *Task) error {
+			return emptyProject(ctx, tx, id, func(task model.Task) error {
+				if _, err := tx.trash(ctx, task.ID, 0); err != nil && !errors.Is(err, model.ErrTaskNotFound) {
+					return err
+				}
+				return nil
+			})
+		})
+	case model.ProjectReassign:
+		if toId != nil && *toId == id {
+			return fmt.Errorf("%w: the tasks are already in project %d", model.ErrInvalidTaskProject, id)
+		}
+		if err := s.tasks.checkProject(ctx, toId); err != nil {
+			return err
+		}
+		return s.tasks.inTransaction(ctx, func(tx *Task) error {
+			return emptyProject(ctx, tx, id, func(task model.Task) error {
+				task.ProjectID = toId
+				_, err := tx.update(ctx, task, model.CompleteStrict)
+				return err
+			})
+		})
+	}
+
+	if project.ArchivedAt != nil {
+		return nil
+	}
+	now := model.Now()
+	project.ArchivedAt, project.UpdatedAt = &now, now
+	_, err = s.projectRepository.UpdateProject(ctx, project)
+	return err
+}
+
+func emptyProject(ctx context.Context, tx *Task, id int, move func(task model.Task) error) error {
+	for {
+		page, err := tx.taskRepository.FindAll(ctx, id, model.PageRequest{Size: model.MaxPageSize})
+		if err != nil {
+			return err
+		}
+		if len(page.Tasks) == 0 {
+			return tx.projectRepository.DeleteProject(ctx, id)
+		}
+		for _, task := range page.Tasks {
+			if err := move(task); err != nil {
+				return fmt.Errorf("%w: at task %d", err, task.ID)
+			}
+		}
+	}
+}
+
+func (s *Project) Unarchive(ctx context.Context, id int) (model.Project, error) {
+	project, err := s.Find(ctx, id)
+	if err != nil || project.ArchivedAt == nil {
+		return project, err
+	}
+	project.ArchivedAt, project.UpdatedAt = nil, model.Now()
+	return s.projectRepository.UpdateProject(ctx, project)
+}
+
+func (s *Project) Stats(ctx context.Context, id int) (model.ProjectStats, error) {
+	if _, err := s.Find(ctx, id); err != nil {
+		return model.ProjectStats{}, err
+	}
+	return s.taskRepository.FindProjectStats(ctx, id, model.Now())
+}
+
+func validateProject(project model.Project) error {
+	if project.Name == "" || len(project.Name) > model.MaxProjectNameLength {
+		return fmt.Errorf("%w: from 1 to %d characters", model.ErrInvalidProjectName, model.MaxProjectNameLength)
+	}
+	return nil
+}
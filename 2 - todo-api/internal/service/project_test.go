@@ -0,0 +1,198 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/internal/service"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestProjects(t *testing.T) {
+	one, two, three := 1, 2, 3
+	archivedAt := model.Now()
+	home := model.Project{ID: 1, OwnerID: 2, Name: "home"}
+	work := model.Project{ID: 2, OwnerID: 2, Name: "work"}
+	old := model.Project{ID: 3, OwnerID: 2, Name: "old", ArchivedAt: &archivedAt}
+	dishes := model.Task{ID: 1, OwnerID: 2, ProjectID: &one, Name: "dishes", Version: 1}
+	laundry := model.Task{ID: 2, OwnerID: 2, ProjectID: &one, Name: "laundry", Version: 1}
+	allTasks := model.PageRequest{Size: model.MaxPageSize}
+
+	cases := []struct {
+		caseName         string
+		change           func(s *service.Task, p *service.Project) error
+		expectedBehavior func(m repository.StoreMock)
+		expectedErr      error
+	}{
+		{
+			caseName: "create a task in a project",
+			change: func(s *service.Task, p *service.Project) error {
+				_, err := s.Create(context.Background(), model.Task{Name: "dishes", ProjectID: &one})
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(dishes, nil).Times(1)
+			},
+		},
+		{
+			caseName: "create a task in a missing project",
+			change: func(s *service.Task, p *service.Project) error {
+				_, err := s.Create(context.Background(), model.Task{Name: "dishes", ProjectID: &one})
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(model.Project{}, model.ErrProjectNotFound).Times(1)
+			},
+			expectedErr: model.ErrInvalidTaskProject,
+		},
+		{
+			caseName: "move a task to an archived project",
+			change: func(s *service.Task, p *service.Project) error {
+				task := dishes
+				task.ProjectID = &three
+				_, err := s.Update(context.Background(), task)
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(dishes, nil).Times(1)
+				m.Projects.EXPECT().FindProject(gomock.Any(), 3).Return(old, nil).Times(1)
+			},
+			expectedErr: model.ErrProjectArchived,
+		},
+		{
+			caseName: "restore a task of a deleted project",
+			change: func(s *service.Task, p *service.Project) error {
+				_, err := s.Restore(context.Background(), 1)
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Restore(gomock.Any(), 1).Return(dishes, nil).Times(1)
+				m.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, true).Return(nil, nil).Times(1)
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(model.Project{}, model.ErrProjectNotFound).Times(1)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, task model.Task) (model.Task, error) {
+					if task.ProjectID != nil {
+						t.Errorf("expected the task out of the project, got %+v", task)
+					}
+					return task, nil
+				}).Times(1)
+			},
+		},
+		{
+			caseName: "create a project with a long name",
+			change: func(s *service.Task, p *service.Project) error {
+				_, err := p.Create(context.Background(), model.Project{Name: strings.Repeat("a", model.MaxProjectNameLength+1)})
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {},
+			expectedErr:      model.ErrInvalidProjectName,
+		},
+		{
+			caseName: "delete archives by default",
+			change: func(s *service.Task, p *service.Project) error {
+				return p.Delete(context.Background(), 1, "", nil)
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Projects.EXPECT().UpdateProject(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, project model.Project) (model.Project, error) {
+					if project.ArchivedAt == nil {
+						t.Errorf("expected the project archived, got %+v", project)
+					}
+					return project, nil
+				}).Times(1)
+			},
+		},
+		{
+			caseName: "cascade takes the tasks to the trash",
+			change: func(s *service.Task, p *service.Project) error {
+				return p.Delete(context.Background(), 1, model.ProjectCascade, nil)
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 1, allTasks).Return(model.TaskPage{Tasks: []model.Task{dishes, laundry}}, nil).Times(1)
+				for _, task := range []model.Task{dishes, laundry} {
+					m.Tasks.EXPECT().FindByID(gomock.Any(), task.ID).Return(task, nil).Times(1)
+					m.Tasks.EXPECT().FindSubtree(gomock.Any(), task.ID, false).Return(nil, nil).Times(1)
+					m.Tasks.EXPECT().Delete(gomock.Any(), task.ID, 0).Return(nil).Times(1)
+				}
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 1, allTasks).Return(model.TaskPage{Tasks: []model.Task{}}, nil).Times(1)
+				m.Projects.EXPECT().DeleteProject(gomock.Any(), 1).Return(nil).Times(1)
+			},
+		},
+		{
+			caseName: "reassign moves the tasks to another project",
+			change: func(s *service.Task, p *service.Project) error {
+				return p.Delete(context.Background(), 1, model.ProjectReassign, &two)
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Projects.EXPECT().FindProject(gomock.Any(), 2).Return(work, nil).Times(2)
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 1, allTasks).Return(model.TaskPage{Tasks: []model.Task{dishes}}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(dishes, nil).Times(1)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, task model.Task) (model.Task, error) {
+					if task.ProjectID == nil || *task.ProjectID != 2 {
+						t.Errorf("expected the task in project 2, got %+v", task)
+					}
+					return task, nil
+				}).Times(1)
+				m.Tasks.EXPECT().FindAll(gomock.Any(), 1, allTasks).Return(model.TaskPage{Tasks: []model.Task{}}, nil).Times(1)
+				m.Projects.EXPECT().DeleteProject(gomock.Any(), 1).Return(nil).Times(1)
+			},
+		},
+		{
+			caseName: "reassign to an archived project",
+			change: func(s *service.Task, p *service.Project) error {
+				return p.Delete(context.Background(), 1, model.ProjectReassign, &three)
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+				m.Projects.EXPECT().FindProject(gomock.Any(), 3).Return(old, nil).Times(1)
+			},
+			expectedErr: model.ErrProjectArchived,
+		},
+		{
+			caseName: "reassign to the project itself",
+			change: func(s *service.Task, p *service.Project) error {
+				return p.Delete(context.Background(), 1, model.ProjectReassign, &one)
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Projects.EXPECT().FindProject(gomock.Any(), 1).Return(home, nil).Times(1)
+			},
+			expectedErr: model.ErrInvalidTaskProject,
+		},
+		{
+			caseName: "an unknown delete mode",
+			change: func(s *service.Task, p *service.Project) error {
+				return p.Delete(context.Background(), 1, "shred", nil)
+			},
+			expectedBehavior: func(m repository.StoreMock) {},
+			expectedErr:      model.ErrInvalidProjectDeleteMode,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			testCase.expectedBehavior(repoMock)
+			repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+				return fn(repoMock.Store())
+			}).AnyTimes()
+			repoMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
+			repoMock.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			events := event.NewBus(event.DefaultReplaySize)
+			s, p := service.NewTask(repoMock.Store(), events), service.NewProject(repoMock.Store(), events)
+
+			if err := testCase.change(&s, &p); !errors.Is(err, testCase.expectedErr) {
+				t.Errorf("got %v want %v", err, testCase.expectedErr)
+			}
+		})
+	}
+}
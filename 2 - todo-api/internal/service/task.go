@@ -1,42 +1,172 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gochallenges/internal/event"
 	"gochallenges/internal/model"
+	"gochallenges/internal/query"
 	"gochallenges/internal/repository"
+	"gochallenges/pkg"
+	"io"
+	"log"
+	"time"
 )
 
 type Task struct {
-	taskRepository repository.Task
+	taskRepository        repository.Task
+	idempotencyRepository repository.Idempotency
+	auditRepository       repository.Audit
+	webhookRepository     repository.Webhooks
+	projectRepository     repository.Projects
+	events                *event.Bus
+	idempotencyWindow     time.Duration
+	// pending holds the events of a transaction until it commits.
+	pending *[]event.Event
 }
 
-func NewTask(taskRepository repository.Task) Task {
-	return Task{taskRepository: taskRepository}
+func NewTask(store repository.Store, events *event.Bus) Task {
+	s := Task{events: events, idempotencyWindow: pkg.GetIdempotencyWindow()}
+	s.use(store)
+	return s
 }
 
-func (s *Task) Create(task model.Task) (model.Task, error) {
-	if task.Name == "" {
-		return task, model.ErrInvalidTaskName
+func (s *Task) use(store repository.Store) {
+	s.taskRepository = store.Tasks
+	s.idempotencyRepository = store.Idempotency
+	s.auditRepository = store.Audit
+	s.webhookRepository = store.Webhooks
+	s.projectRepository = store.Projects
+}
+
+func (s *Task) Create(ctx context.Context, task model.Task) (created model.Task, err error) {
+	err = s.inTransaction(ctx, func(tx *Task) error {
+		created, err = tx.create(ctx, task)
+		return err
+	})
+	return created, err
+}
+
+func (s *Task) create(ctx context.Context, task model.Task) (model.Task, error) {
+	if err := validateNewTask(task); err != nil {
+		return task, err
 	}
 
 	if task.ID > 0 {
-		existing, err := s.taskRepository.FindByID(task.ID)
-		if err != nil {
-			return task, err
-		}
-		if existing.ID > 0 {
+		_, err := s.taskRepository.FindByID(ctx, task.ID)
+		if err == nil {
 			return task, model.ErrTaskAlreadyExists
 		}
+		if !errors.Is(err, model.ErrTaskNotFound) {
+			return task, err
+		}
+	}
+
+	if err := s.checkParent(ctx, 0, task.ParentID); err != nil {
+		return task, err
+	}
+	if err := s.checkProject(ctx, task.ProjectID); err != nil {
+		return task, err
 	}
 
-	createdTask, err := s.taskRepository.Create(task)
+	task.CompletedAt = completedAt(model.Task{}, task.Completed)
+
+	createdTask, err := s.taskRepository.Create(ctx, task)
 	if err != nil {
 		return task, err
 	}
 
-	return createdTask, nil
+	if err := s.publish(ctx, event.Event{Type: event.TaskCreated, Task: createdTask}); err != nil {
+		return task, err
+	}
+	return createdTask, s.audit(ctx, model.AuditCreate, nil, &createdTask)
+}
+
+// CreateOnce gives retries with the same key the task the first request created.
+func (s *Task) CreateOnce(ctx context.Context, task model.Task, key string) (created model.Task, replayed bool, err error) {
+	if key == "" {
+		created, err = s.Create(ctx, task)
+		return created, false, err
+	}
+	if len(key) > model.MaxIdempotencyKeyLength {
+		return task, false, model.ErrInvalidIdempotencyKey
+	}
+
+	fingerprint := fingerprintOf(task)
+	created, err = s.replay(ctx, key, fingerprint)
+	if !errors.Is(err, model.ErrIdempotencyKeyNotFound) {
+		return created, err == nil, err
+	}
+
+	err = s.inTransaction(ctx, func(tx *Task) error {
+		var err error
+		if created, err = tx.Create(ctx, task); err != nil {
+			return err
+		}
+		now := model.Now()
+		return tx.idempotencyRepository.CreateIdempotencyKey(ctx, model.IdempotencyKey{
+			Key:         key,
+			Fingerprint: fingerprint,
+			Task:        created,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.idempotencyWindow),
+		})
+	})
+	if errors.Is(err, model.ErrIdempotencyKeyExists) {
+		// A concurrent request with the key won, and this one's task was rolled back.
+		created, err = s.replay(ctx, key, fingerprint)
+		return created, err == nil, err
+	}
+	if err != nil {
+		return task, false, err
+	}
+	return created, false, nil
 }
 
-func (s *Task) Update(task model.Task) (model.Task, error) {
+func (s *Task) replay(ctx context.Context, key string, fingerprint string) (model.Task, error) {
+	stored, err := s.idempotencyRepository.FindIdempotencyKey(ctx, key)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if stored.Fingerprint != fingerprint {
+		return model.Task{}, model.ErrIdempotencyKeyReused
+	}
+	return stored.Task, nil
+}
+
+// fingerprintOf hashes a create request the same whether it came over REST or gRPC.
+func fingerprintOf(task model.Task) string {
+	request := model.Task{ID: task.ID, ParentID: task.ParentID, ProjectID: task.ProjectID, Name: task.Name, Completed: task.Completed, Description: task.Description, Priority: task.Priority}
+	if task.DueAt != nil {
+		dueAt := task.DueAt.UTC()
+		request.DueAt = &dueAt
+	}
+	encoded, _ := json.Marshal(request)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Update fails instead of overwriting a concurrent edit when task.Version is set.
+func (s *Task) Update(ctx context.Context, task model.Task) (model.Task, error) {
+	return s.UpdateWith(ctx, task, model.CompleteStrict)
+}
+
+func (s *Task) UpdateWith(ctx context.Context, task model.Task, mode model.CompletionMode) (updated model.Task, err error) {
+	if !mode.Valid() {
+		return task, fmt.Errorf("%w %q", model.ErrInvalidCompletionMode, mode)
+	}
+	err = s.inTransaction(ctx, func(tx *Task) error {
+		updated, err = tx.update(ctx, task, mode)
+		return err
+	})
+	return updated, err
+}
+
+func (s *Task) update(ctx context.Context, task model.Task, mode model.CompletionMode) (model.Task, error) {
 	if task.Name == "" {
 		return task, model.ErrInvalidTaskName
 	}
@@ -45,38 +175,614 @@ func (s *Task) Update(task model.Task) (model.Task, error) {
 		return task, model.ErrInvalidTaskId
 	}
 
-	existing, err := s.taskRepository.FindByID(task.ID)
+	if !task.Priority.Valid() {
+		return task, model.ErrInvalidTaskPriority
+	}
+
+	storedTask, err := s.taskRepository.FindByID(ctx, task.ID)
 	if err != nil {
 		return task, err
 	}
-	if existing.ID == 0 {
-		return task, model.ErrTaskNotFound
+	if task.Version != 0 && task.Version != storedTask.Version {
+		return task, model.ErrTaskVersionMismatch
+	}
+	if !sameId(task.ParentID, storedTask.ParentID) {
+		if err := s.checkParent(ctx, task.ID, task.ParentID); err != nil {
+			return task, err
+		}
+	}
+	if !sameId(task.ProjectID, storedTask.ProjectID) {
+		if err := s.checkProject(ctx, task.ProjectID); err != nil {
+			return task, err
+		}
 	}
 
-	updatedTask, err := s.taskRepository.Update(task)
+	var openSubtasks []model.Task
+	if task.Completed && !storedTask.Completed && mode != model.CompleteForce {
+		subtree, err := s.taskRepository.FindSubtree(ctx, task.ID, false)
+		if err != nil {
+			return task, err
+		}
+		for _, subtask := range subtree {
+			if !subtask.Completed {
+				openSubtasks = append(openSubtasks, subtask)
+			}
+		}
+		if len(openSubtasks) > 0 && mode != model.CompleteCascade {
+			return task, fmt.Errorf("%w: %d of them", model.ErrOpenSubtasks, len(openSubtasks))
+		}
+	}
+
+	task.OwnerID = storedTask.OwnerID
+	task.CreatedAt = storedTask.CreatedAt
+	task.CompletedAt = completedAt(storedTask, task.Completed)
+
+	updatedTask, err := s.taskRepository.Update(ctx, task)
 	if err != nil {
 		return updatedTask, err
 	}
 
+	if err := s.publish(ctx, event.Event{Type: event.TaskUpdated, Task: updatedTask, Previous: &storedTask}); err != nil {
+		return task, err
+	}
+	if err := s.audit(ctx, model.AuditUpdate, &storedTask, &updatedTask); err != nil {
+		return task, err
+	}
+
+	for _, subtask := range openSubtasks {
+		subtask.Completed, subtask.Version = true, 0
+		if _, err := s.update(ctx, subtask, model.CompleteForce); err != nil {
+			return task, err
+		}
+	}
 	return updatedTask, nil
 }
 
-func (s *Task) Delete(id int) error {
+func (s *Task) Delete(ctx context.Context, id int, version int) error {
+	_, err := s.delete(ctx, id, version)
+	return err
+}
+
+func (s *Task) delete(ctx context.Context, id int, version int) (deleted model.Task, err error) {
+	err = s.inTransaction(ctx, func(tx *Task) error {
+		deleted, err = tx.trash(ctx, id, version)
+		return err
+	})
+	return deleted, err
+}
+
+// trash takes the subtasks along, which cannot be left without the task.
+func (s *Task) trash(ctx context.Context, id int, version int) (model.Task, error) {
 	if id == 0 {
-		return model.ErrInvalidTaskId
+		return model.Task{}, model.ErrInvalidTaskId
 	}
 
-	existing, err := s.taskRepository.FindByID(id)
+	storedTask, err := s.taskRepository.FindByID(ctx, id)
 	if err != nil {
+		return model.Task{}, err
+	}
+	if version != 0 && version != storedTask.Version {
+		return model.Task{}, model.ErrTaskVersionMismatch
+	}
+	subtree, err := s.taskRepository.FindSubtree(ctx, id, false)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	for i, task := range append([]model.Task{storedTask}, subtree...) {
+		if i > 0 {
+			version = 0
+		}
+		if err := s.taskRepository.Delete(ctx, task.ID, version); err != nil {
+			return model.Task{}, err
+		}
+		if err := s.publish(ctx, event.Event{Type: event.TaskDeleted, Task: task}); err != nil {
+			return model.Task{}, err
+		}
+		if err := s.audit(ctx, model.AuditDelete, &task, nil); err != nil {
+			return model.Task{}, err
+		}
+	}
+	return storedTask, nil
+}
+
+// Restore also brings back the subtasks trashed under the task.
+func (s *Task) Restore(ctx context.Context, id int) (restored model.Task, err error) {
+	err = s.inTransaction(ctx, func(tx *Task) error {
+		restored, err = tx.restore(ctx, id)
 		return err
+	})
+	return restored, err
+}
+
+func (s *Task) restore(ctx context.Context, id int) (model.Task, error) {
+	if id == 0 {
+		return model.Task{}, model.ErrInvalidTaskId
+	}
+
+	restoredTask, err := s.taskRepository.Restore(ctx, id)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if parentId := restoredTask.ParentID; parentId != nil {
+		if _, err := s.taskRepository.FindByID(ctx, *parentId); errors.Is(err, model.ErrTaskNotFound) {
+			return model.Task{}, fmt.Errorf("%w: restore task %d first", model.ErrInvalidTaskParent, *parentId)
+		} else if err != nil {
+			return model.Task{}, err
+		}
+	}
+	subtree, err := s.taskRepository.FindSubtree(ctx, id, true)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	for i, task := range append([]model.Task{restoredTask}, subtree...) {
+		if i > 0 {
+			if task, err = s.taskRepository.Restore(ctx, task.ID); err != nil {
+				return model.Task{}, err
+			}
+		}
+		if task, err = s.leaveDeletedProject(ctx, task); err != nil {
+			return model.Task{}, err
+		}
+		if i == 0 {
+			restoredTask = task
+		}
+		if err := s.publish(ctx, event.Event{Type: event.TaskRestored, Task: task}); err != nil {
+			return model.Task{}, err
+		}
+		if err := s.audit(ctx, model.AuditRestore, nil, &task); err != nil {
+			return model.Task{}, err
+		}
+	}
+	return restoredTask, nil
+}
+
+func (s *Task) Subtasks(ctx context.Context, id int, q query.Query, page model.PageRequest) (model.TaskPage, error) {
+	if _, err := s.taskRepository.FindByID(ctx, id); err != nil {
+		return model.TaskPage{}, err
 	}
-	if existing.ID == 0 {
-		return model.ErrTaskNotFound
+	q.Filter = query.AndAlso(q.Filter, query.Comparison{Field: "parent_id", Op: query.Eq, Value: id})
+	return s.taskRepository.Find(ctx, q, page)
+}
+
+func (s *Task) Tree(ctx context.Context, id int) (model.TaskTree, error) {
+	root, err := s.taskRepository.FindByID(ctx, id)
+	if err != nil {
+		return model.TaskTree{}, err
 	}
+	subtree, err := s.taskRepository.FindSubtree(ctx, id, false)
+	if err != nil {
+		return model.TaskTree{}, err
+	}
+
+	children := map[int][]model.Task{}
+	for _, task := range subtree {
+		children[*task.ParentID] = append(children[*task.ParentID], task)
+	}
+	var grow func(task model.Task) model.TaskTree
+	grow = func(task model.Task) model.TaskTree {
+		tree := model.TaskTree{Task: task, Subtasks: []model.TaskTree{}}
+		for _, child := range children[task.ID] {
+			tree.Subtasks = append(tree.Subtasks, grow(child))
+		}
+		return tree
+	}
+	return grow(root), nil
+}
+
+func (s *Task) History(ctx context.Context, filter model.AuditFilter, page model.PageRequest) (model.AuditPage, error) {
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return model.AuditPage{}, fmt.Errorf("%w: from must be before to", model.ErrInvalidAuditFilter)
+	}
+	return s.auditRepository.FindAuditRecords(ctx, filter, page)
+}
+
+func (s *Task) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	return s.taskRepository.Purge(ctx, model.Now().Add(-retention))
+}
+
+func (s *Task) PurgeTrashEvery(ctx context.Context, retention time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeTrash(ctx, retention); err != nil {
+			log.Printf("Could not purge the trash: %s", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d tasks from the trash", purged)
+		}
 
-	if err = s.taskRepository.Delete(id); err != nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Batch runs in one transaction, with a savepoint per operation in per-item mode.
+func (s *Task) Batch(ctx context.Context, batch model.Batch) (model.BatchResults, error) {
+	if !batch.Mode.Valid() {
+		return model.BatchResults{}, model.ErrInvalidBatchMode
+	}
+	if batch.Size() > model.MaxBatchSize {
+		return model.BatchResults{}, fmt.Errorf("%w: at most %d", model.ErrBatchTooLarge, model.MaxBatchSize)
+	}
+
+	var results model.BatchResults
+	err := s.inTransaction(ctx, func(tx *Task) error {
+		run := func(op string, n int, apply func(tx *Task, i int) (model.Task, error)) ([]model.BatchResult, error) {
+			results := make([]model.BatchResult, n)
+			for i := range results {
+				var task model.Task
+				item := func(tx *Task) (err error) {
+					task, err = apply(tx, i)
+					return err
+				}
+				var err error
+				if batch.Mode == model.BatchPerItem {
+					err = tx.savepoint(ctx, item)
+				} else {
+					err = item(tx)
+				}
+				if err != nil {
+					if batch.Mode != model.BatchPerItem {
+						return nil, fmt.Errorf("%w: at %s[%d]", err, op, i)
+					}
+					results[i].Err = err
+					continue
+				}
+				results[i].Task = task
+			}
+			return results, nil
+		}
+
+		var err error
+		if results.Create, err = run("create", len(batch.Create), func(tx *Task, i int) (model.Task, error) {
+			return tx.Create(ctx, batch.Create[i])
+		}); err != nil {
+			return err
+		}
+		if results.Update, err = run("update", len(batch.Update), func(tx *Task, i int) (model.Task, error) {
+			return tx.Update(ctx, batch.Update[i])
+		}); err != nil {
+			return err
+		}
+		results.Delete, err = run("delete", len(batch.Delete), func(tx *Task, i int) (model.Task, error) {
+			return tx.delete(ctx, batch.Delete[i].ID, batch.Delete[i].Version)
+		})
+		return err
+	})
+	if err != nil {
+		return model.BatchResults{}, err
+	}
+	return results, nil
+}
+
+// inTransaction publishes the changes fn made once the transaction commits.
+func (s *Task) inTransaction(ctx context.Context, fn func(tx *Task) error) error {
+	if s.pending != nil {
+		return fn(s)
+	}
+
+	var pending []event.Event
+	err := s.taskRepository.Transaction(ctx, func(store repository.Store) error {
+		pending = nil
+		tx := *s
+		tx.use(store)
+		tx.pending = &pending
+		return fn(&tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range pending {
+		s.events.Publish(e)
+	}
+	return nil
+}
+
+// savepoint rolls back the writes of fn and drops its events when it fails.
+func (s *Task) savepoint(ctx context.Context, fn func(tx *Task) error) error {
+	mark := len(*s.pending)
+	err := s.taskRepository.Transaction(ctx, func(store repository.Store) error {
+		tx := *s
+		tx.use(store)
+		return fn(&tx)
+	})
+	if err != nil {
+		*s.pending = (*s.pending)[:mark]
+	}
+	return err
+}
+
+// ImportSource returns the next task to import, or io.EOF after the last one.
+type ImportSource func() (model.Task, error)
+
+// Import holds one chunk at a time and counts failed tasks instead of stopping.
+func (s *Task) Import(ctx context.Context, next ImportSource, chunkSize int) (model.ImportSummary, error) {
+	if chunkSize <= 0 {
+		chunkSize = model.DefaultImportChunkSize
+	}
+	if chunkSize > model.MaxImportChunkSize {
+		chunkSize = model.MaxImportChunkSize
+	}
+
+	var summary model.ImportSummary
+	chunk := make([]model.Task, 0, chunkSize)
+	rows := make([]int, 0, chunkSize)
+	parents, projects := map[int]bool{}, map[int]bool{}
+	for {
+		task, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary.Received++
+
+		if err := validateNewTask(task); err != nil {
+			failImport(&summary, summary.Received, err)
+			continue
+		}
+		if parentId := task.ParentID; parentId != nil && !parents[*parentId] {
+			// The parent may be in the held chunk, so that is stored first.
+			if err := s.importChunk(ctx, &summary, chunk, rows); err != nil {
+				return summary, err
+			}
+			chunk, rows = chunk[:0], rows[:0]
+			if err := s.checkParent(ctx, 0, parentId); errors.Is(err, model.ErrInvalidTaskParent) {
+				failImport(&summary, summary.Received, err)
+				continue
+			} else if err != nil {
+				return summary, err
+			}
+			parents[*parentId] = true
+		}
+		if projectId := task.ProjectID; projectId != nil && !projects[*projectId] {
+			err := s.checkProject(ctx, projectId)
+			if errors.Is(err, model.ErrInvalidTaskProject) || errors.Is(err, model.ErrProjectArchived) {
+				failImport(&summary, summary.Received, err)
+				continue
+			} else if err != nil {
+				return summary, err
+			}
+			projects[*projectId] = true
+		}
+		task.CompletedAt = completedAt(model.Task{}, task.Completed)
+		chunk, rows = append(chunk, task), append(rows, summary.Received)
+
+		if len(chunk) == chunkSize {
+			if err := s.importChunk(ctx, &summary, chunk, rows); err != nil {
+				return summary, err
+			}
+			chunk, rows = chunk[:0], rows[:0]
+		}
+	}
+
+	return summary, s.importChunk(ctx, &summary, chunk, rows)
+}
+
+// importChunk retries a chunk that fails one task at a time, to find the rows at fault.
+func (s *Task) importChunk(ctx context.Context, summary *model.ImportSummary, tasks []model.Task, rows []int) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	err := s.inTransaction(ctx, func(tx *Task) error {
+		created, err := tx.taskRepository.CreateMany(ctx, tasks)
+		if err != nil {
+			return err
+		}
+		return tx.imported(ctx, created)
+	})
+	if err == nil {
+		summary.Imported += len(tasks)
+		return nil
+	}
+
+	for i, task := range tasks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := s.inTransaction(ctx, func(tx *Task) error {
+			created, err := tx.taskRepository.Create(ctx, task)
+			if err != nil {
+				return err
+			}
+			return tx.imported(ctx, []model.Task{created})
+		})
+		if err != nil {
+			failImport(summary, rows[i], err)
+			continue
+		}
+		summary.Imported++
+	}
+	return nil
+}
+
+// imported records and publishes the creation of tasks.
+func (s *Task) imported(ctx context.Context, tasks []model.Task) error {
+	events := make([]event.Event, len(tasks))
+	for i := range tasks {
+		if err := s.audit(ctx, model.AuditCreate, nil, &tasks[i]); err != nil {
+			return err
+		}
+		events[i] = event.Event{Type: event.TaskCreated, Task: tasks[i]}
+	}
+	return s.publish(ctx, events...)
+}
+
+func failImport(summary *model.ImportSummary, row int, err error) {
+	summary.Failed++
+	if len(summary.Errors) < model.MaxImportErrors {
+		summary.Errors = append(summary.Errors, model.ImportError{Row: row, Err: err})
+	}
+}
+
+func (s *Task) audit(ctx context.Context, operation model.AuditOperation, before *model.Task, after *model.Task) error {
+	task := after
+	if task == nil {
+		task = before
+	}
+	return s.auditRepository.CreateAuditRecord(ctx, model.AuditRecord{
+		TaskID:    task.ID,
+		OwnerID:   task.OwnerID,
+		Operation: operation,
+		Before:    before,
+		After:     after,
+		CreatedAt: model.Now(),
+	})
+}
+
+// publish writes events to the outbox and sends them on the bus once the transaction commits.
+func (s *Task) publish(ctx context.Context, events ...event.Event) error {
+	if err := s.outbox(ctx, events); err != nil {
+		return err
+	}
+	if s.pending != nil {
+		*s.pending = append(*s.pending, events...)
+		return nil
+	}
+	for _, e := range events {
+		s.events.Publish(e)
+	}
+	return nil
+}
+
+func (s *Task) outbox(ctx context.Context, events []event.Event) error {
+	webhooks, err := s.webhookRepository.FindWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := model.Now()
+	var deliveries []model.WebhookDelivery
+	for _, e := range events {
+		var payload []byte
+		for _, webhook := range webhooks {
+			if webhook.OwnerID != e.Task.OwnerID || !webhook.Subscribes(e.Type) {
+				continue
+			}
+			if payload == nil {
+				if payload, err = json.Marshal(webhookPayload{Type: e.Type, OccurredAt: now, Task: e.Task, Previous: e.Previous}); err != nil {
+					return err
+				}
+			}
+			deliveries = append(deliveries, model.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				OwnerID:       webhook.OwnerID,
+				EventType:     e.Type,
+				Payload:       payload,
+				Status:        model.DeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.webhookRepository.CreateDeliveries(ctx, deliveries)
+}
+
+// checkParent takes id 0 for a new task.
+func (s *Task) checkParent(ctx context.Context, id int, parentId *int) error {
+	if parentId == nil {
+		return nil
+	}
+	if *parentId <= 0 {
+		return fmt.Errorf("%w: %d", model.ErrInvalidTaskParent, *parentId)
+	}
+
+	parent, err := s.taskRepository.FindByID(ctx, *parentId)
+	if errors.Is(err, model.ErrTaskNotFound) {
+		return fmt.Errorf("%w: task %d not found", model.ErrInvalidTaskParent, *parentId)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only a stored task can have subtasks, so only a move looks for a cycle.
+	seen := map[int]bool{}
+	for ancestor := parent; id != 0; {
+		if ancestor.ID == id {
+			return model.ErrTaskParentCycle
+		}
+		if ancestor.ParentID == nil || seen[ancestor.ID] {
+			return nil
+		}
+		seen[ancestor.ID] = true
+
+		if ancestor, err = s.taskRepository.FindByID(ctx, *ancestor.ParentID); errors.Is(err, model.ErrTaskNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Task) checkProject(ctx context.Context, projectId *int) error {
+	if projectId == nil {
+		return nil
+	}
+	if *projectId <= 0 {
+		return fmt.Errorf("%w: %d", model.ErrInvalidTaskProject, *projectId)
+	}
+
+	project, err := s.projectRepository.FindProject(ctx, *projectId)
+	if errors.Is(err, model.ErrProjectNotFound) {
+		return fmt.Errorf("%w: project %d not found", model.ErrInvalidTaskProject, *projectId)
+	}
+	if err != nil {
 		return err
 	}
+	if project.ArchivedAt != nil {
+		return fmt.Errorf("%w: project %d", model.ErrProjectArchived, *projectId)
+	}
+	return nil
+}
+
+func (s *Task) leaveDeletedProject(ctx context.Context, task model.Task) (model.Task, error) {
+	if task.ProjectID == nil {
+		return task, nil
+	}
+	_, err := s.projectRepository.FindProject(ctx, *task.ProjectID)
+	if !errors.Is(err, model.ErrProjectNotFound) {
+		return task, err
+	}
+	task.ProjectID = nil
+	return s.taskRepository.Update(ctx, task)
+}
 
+func validateNewTask(task model.Task) error {
+	if task.Name == "" {
+		return model.ErrInvalidTaskName
+	}
+	if !task.Priority.Valid() {
+		return model.ErrInvalidTaskPriority
+	}
 	return nil
 }
+
+func sameId(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func completedAt(stored model.Task, completed bool) *time.Time {
+	switch {
+	case !completed:
+		return nil
+	case stored.Completed:
+		return stored.CompletedAt
+	}
+	now := model.Now()
+	return &now
+}
@@ -0,0 +1,565 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/internal/service"
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestImport(t *testing.T) {
+	five, nine := 5, 9
+	named := func(names ...string) []model.Task {
+		tasks := make([]model.Task, len(names))
+		for i, name := range names {
+			tasks[i] = model.Task{Name: name}
+		}
+		return tasks
+	}
+
+	cases := []struct {
+		caseName         string
+		tasks            []model.Task
+		chunkSize        int
+		expectedBehavior func(m repository.StoreMock)
+		expectedSummary  model.ImportSummary
+	}{
+		{
+			caseName:  "inserts a chunk at a time",
+			tasks:     named("1", "2", "3", "4", "5"),
+			chunkSize: 2,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().CreateMany(gomock.Any(), named("1", "2")).Return(named("1", "2"), nil).Times(1)
+				m.Tasks.EXPECT().CreateMany(gomock.Any(), named("3", "4")).Return(named("3", "4"), nil).Times(1)
+				m.Tasks.EXPECT().CreateMany(gomock.Any(), named("5")).Return(named("5"), nil).Times(1)
+			},
+			expectedSummary: model.ImportSummary{Received: 5, Imported: 5},
+		},
+		{
+			caseName:  "leaves invalid tasks out of their chunk",
+			tasks:     named("1", "", "3"),
+			chunkSize: 2,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().CreateMany(gomock.Any(), named("1", "3")).Return(named("1", "3"), nil).Times(1)
+			},
+			expectedSummary: model.ImportSummary{Received: 3, Imported: 2, Failed: 1, Errors: []model.ImportError{{Row: 2, Err: model.ErrInvalidTaskName}}},
+		},
+		{
+			caseName:  "retries a failed chunk one task at a time",
+			tasks:     []model.Task{{Name: "1"}, {ID: 7, Name: "2"}, {Name: "3"}},
+			chunkSize: 3,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().CreateMany(gomock.Any(), gomock.Any()).Return(nil, model.ErrTaskAlreadyExists).Times(1)
+				m.Tasks.EXPECT().Create(gomock.Any(), model.Task{Name: "1"}).Return(model.Task{ID: 1, Name: "1"}, nil).Times(1)
+				m.Tasks.EXPECT().Create(gomock.Any(), model.Task{ID: 7, Name: "2"}).Return(model.Task{}, model.ErrTaskAlreadyExists).Times(1)
+				m.Tasks.EXPECT().Create(gomock.Any(), model.Task{Name: "3"}).Return(model.Task{ID: 2, Name: "3"}, nil).Times(1)
+			},
+			expectedSummary: model.ImportSummary{Received: 3, Imported: 2, Failed: 1, Errors: []model.ImportError{{Row: 2, Err: model.ErrTaskAlreadyExists}}},
+		},
+		{
+			caseName:  "stores the held chunk before looking for a parent",
+			tasks:     []model.Task{{ID: 5, Name: "1"}, {Name: "2", ParentID: &five}, {Name: "3", ParentID: &nine}},
+			chunkSize: 10,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().CreateMany(gomock.Any(), []model.Task{{ID: 5, Name: "1"}}).Return([]model.Task{{ID: 5, Name: "1"}}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 5).Return(model.Task{ID: 5, Name: "1"}, nil).Times(1)
+				m.Tasks.EXPECT().CreateMany(gomock.Any(), []model.Task{{Name: "2", ParentID: &five}}).Return([]model.Task{{ID: 6, Name: "2", ParentID: &five}}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 9).Return(model.Task{}, model.ErrTaskNotFound).Times(1)
+			},
+			expectedSummary: model.ImportSummary{Received: 3, Imported: 2, Failed: 1},
+		},
+		{
+			caseName:         "lists only the first failures",
+			tasks:            make([]model.Task, model.MaxImportErrors+5),
+			expectedBehavior: func(m repository.StoreMock) {},
+			expectedSummary:  model.ImportSummary{Received: model.MaxImportErrors + 5, Failed: model.MaxImportErrors + 5},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			testCase.expectedBehavior(repoMock)
+			repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+				return fn(repoMock.Store())
+			}).AnyTimes()
+			repoMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
+			audited := 0
+			repoMock.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, record model.AuditRecord) error {
+				if record.Operation != model.AuditCreate || record.After == nil {
+					t.Errorf("expected a create record, got %+v", record)
+				}
+				audited++
+				return nil
+			}).AnyTimes()
+
+			bus := event.NewBus(event.DefaultReplaySize)
+			sub, _, _ := bus.Subscribe(0)
+			defer sub.Close()
+			s := service.NewTask(repoMock.Store(), bus)
+
+			summary, err := s.Import(context.Background(), source(testCase.tasks), testCase.chunkSize)
+			if err != nil {
+				t.Fatalf("Error was not expected while importing, got %s", err)
+			}
+
+			if len(summary.Errors) > model.MaxImportErrors {
+				t.Errorf("expected at most %d errors, got %d", model.MaxImportErrors, len(summary.Errors))
+			}
+			if testCase.expectedSummary.Errors == nil {
+				summary.Errors = nil
+			}
+			if !reflect.DeepEqual(summary, testCase.expectedSummary) {
+				t.Errorf("got %+v want %+v", summary, testCase.expectedSummary)
+			}
+			if published := len(sub.C); audited != summary.Imported || published != summary.Imported {
+				t.Errorf("expected every imported task audited and published, got %d records and %d events for %d tasks", audited, published, summary.Imported)
+			}
+		})
+	}
+}
+
+func TestImportStopsWhenTheSourceFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := repository.NewStoreMock(ctrl)
+	repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+		return fn(repoMock.Store())
+	}).Times(1)
+	repoMock.Tasks.EXPECT().CreateMany(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, tasks []model.Task) ([]model.Task, error) {
+		return tasks, nil
+	}).Times(1)
+	repoMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(nil, nil).Times(1)
+	repoMock.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	s := service.NewTask(repoMock.Store(), event.NewBus(event.DefaultReplaySize))
+
+	broken := errors.New("stream broke")
+	sent := 0
+	summary, err := s.Import(context.Background(), func() (model.Task, error) {
+		sent++
+		if sent > 3 {
+			return model.Task{}, broken
+		}
+		return model.Task{Name: strconv.Itoa(sent)}, nil
+	}, 2)
+
+	if !errors.Is(err, broken) {
+		t.Errorf("got %v want %v", err, broken)
+	}
+	if summary.Imported != 2 || summary.Received != 3 {
+		t.Errorf("expected the first chunk to be kept, got %+v", summary)
+	}
+}
+
+func TestPurgeTrashEvery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	retention := 30 * 24 * time.Hour
+	repoMock := repository.NewStoreMock(ctrl)
+	repoMock.Tasks.EXPECT().Purge(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, before time.Time) (int, error) {
+		if cutoff := model.Now().Add(-retention); before.After(cutoff) || before.Before(cutoff.Add(-time.Minute)) {
+			t.Errorf("expected to purge the tasks trashed before %v, got %v", cutoff, before)
+		}
+		return 1, nil
+	}).Times(1)
+	repoMock.Tasks.EXPECT().Purge(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, time.Time) (int, error) {
+		cancel()
+		return 0, model.ErrExecuteQuery
+	}).MinTimes(1)
+	s := service.NewTask(repoMock.Store(), event.NewBus(event.DefaultReplaySize))
+
+	done := make(chan struct{})
+	go func() {
+		s.PurgeTrashEvery(ctx, retention, time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the purge job to stop when its context is done")
+	}
+}
+
+func TestAudit(t *testing.T) {
+	stored := model.Task{ID: 1, OwnerID: 2, Name: "Task 1", Version: 1}
+	renamed := model.Task{ID: 1, OwnerID: 2, Name: "renamed", Version: 2}
+
+	cases := []struct {
+		caseName         string
+		change           func(s *service.Task) error
+		expectedBehavior func(tx repository.StoreMock)
+		expectedRecord   model.AuditRecord
+		auditErr         error
+	}{
+		{
+			caseName: "create",
+			change: func(s *service.Task) error {
+				_, err := s.Create(context.Background(), model.Task{Name: "Task 1"})
+				return err
+			},
+			expectedBehavior: func(tx repository.StoreMock) {
+				tx.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(stored, nil).Times(1)
+			},
+			expectedRecord: model.AuditRecord{TaskID: 1, OwnerID: 2, Operation: model.AuditCreate, After: &stored},
+		},
+		{
+			caseName: "update",
+			change: func(s *service.Task) error {
+				_, err := s.Update(context.Background(), model.Task{ID: 1, Name: "renamed"})
+				return err
+			},
+			expectedBehavior: func(tx repository.StoreMock) {
+				tx.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(stored, nil).Times(1)
+				tx.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).Return(renamed, nil).Times(1)
+			},
+			expectedRecord: model.AuditRecord{TaskID: 1, OwnerID: 2, Operation: model.AuditUpdate, Before: &stored, After: &renamed},
+		},
+		{
+			caseName: "delete",
+			change: func(s *service.Task) error {
+				return s.Delete(context.Background(), 1, 0)
+			},
+			expectedBehavior: func(tx repository.StoreMock) {
+				tx.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(stored, nil).Times(1)
+				tx.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, false).Return(nil, nil).Times(1)
+				tx.Tasks.EXPECT().Delete(gomock.Any(), 1, 0).Return(nil).Times(1)
+			},
+			expectedRecord: model.AuditRecord{TaskID: 1, OwnerID: 2, Operation: model.AuditDelete, Before: &stored},
+		},
+		{
+			caseName: "restore",
+			change: func(s *service.Task) error {
+				_, err := s.Restore(context.Background(), 1)
+				return err
+			},
+			expectedBehavior: func(tx repository.StoreMock) {
+				tx.Tasks.EXPECT().Restore(gomock.Any(), 1).Return(stored, nil).Times(1)
+				tx.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, true).Return(nil, nil).Times(1)
+			},
+			expectedRecord: model.AuditRecord{TaskID: 1, OwnerID: 2, Operation: model.AuditRestore, After: &stored},
+		},
+		{
+			caseName: "a failed record fails the change",
+			change: func(s *service.Task) error {
+				_, err := s.Create(context.Background(), model.Task{Name: "Task 1"})
+				return err
+			},
+			expectedBehavior: func(tx repository.StoreMock) {
+				tx.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(stored, nil).Times(1)
+			},
+			expectedRecord: model.AuditRecord{TaskID: 1, OwnerID: 2, Operation: model.AuditCreate, After: &stored},
+			auditErr:       model.ErrExecuteQuery,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock, txMock := repository.NewStoreMock(ctrl), repository.NewStoreMock(ctrl)
+			repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+				return fn(txMock.Store())
+			}).Times(1)
+			testCase.expectedBehavior(txMock)
+			txMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
+			txMock.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, record model.AuditRecord) error {
+				if record.CreatedAt.IsZero() {
+					t.Errorf("expected the record to be timestamped")
+				}
+				record.CreatedAt = time.Time{}
+				if !reflect.DeepEqual(record, testCase.expectedRecord) {
+					t.Errorf("got %+v want %+v", record, testCase.expectedRecord)
+				}
+				return testCase.auditErr
+			}).Times(1)
+
+			bus := event.NewBus(event.DefaultReplaySize)
+			sub, _, _ := bus.Subscribe(0)
+			defer sub.Close()
+			s := service.NewTask(repoMock.Store(), bus)
+
+			if err := testCase.change(&s); !errors.Is(err, testCase.auditErr) {
+				t.Fatalf("got %v want %v", err, testCase.auditErr)
+			}
+			published := len(sub.C)
+			if testCase.auditErr == nil && published != 1 || testCase.auditErr != nil && published != 0 {
+				t.Errorf("expected the change to be published only with its record, got %d events", published)
+			}
+		})
+	}
+}
+
+func TestBatchPerItemDropsTheChangesOfAFailedItem(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := repository.NewStoreMock(ctrl)
+	repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+		return fn(repoMock.Store())
+	}).Times(3)
+	repoMock.Tasks.EXPECT().Create(gomock.Any(), model.Task{Name: "kept"}).Return(model.Task{ID: 1, Name: "kept"}, nil).Times(1)
+	repoMock.Tasks.EXPECT().Create(gomock.Any(), model.Task{Name: "dropped"}).Return(model.Task{ID: 2, Name: "dropped"}, nil).Times(1)
+	repoMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(nil, nil).Times(2)
+	repoMock.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, record model.AuditRecord) error {
+		if record.TaskID == 2 {
+			return model.ErrExecuteQuery
+		}
+		return nil
+	}).Times(2)
+
+	bus := event.NewBus(event.DefaultReplaySize)
+	sub, _, _ := bus.Subscribe(0)
+	defer sub.Close()
+	s := service.NewTask(repoMock.Store(), bus)
+
+	results, err := s.Batch(context.Background(), model.Batch{Mode: model.BatchPerItem, Create: []model.Task{{Name: "kept"}, {Name: "dropped"}}})
+	if err != nil {
+		t.Fatalf("Error was not expected while running the batch, got %s", err)
+	}
+	if results.Create[0].Err != nil || !errors.Is(results.Create[1].Err, model.ErrExecuteQuery) {
+		t.Errorf("expected only the second create to fail, got %+v", results.Create)
+	}
+	if published := len(sub.C); published != 1 {
+		t.Fatalf("expected only the kept task published, got %d events", published)
+	}
+	if e := <-sub.C; e.Task.ID != 1 {
+		t.Errorf("expected the kept task published, got %+v", e.Task)
+	}
+}
+
+func TestSubtasks(t *testing.T) {
+	one, two, three := 1, 2, 3
+	parent := model.Task{ID: 1, OwnerID: 2, Name: "parent", Version: 1}
+	child := model.Task{ID: 2, OwnerID: 2, ParentID: &one, Name: "child", Version: 1}
+	grandchild := model.Task{ID: 3, OwnerID: 2, ParentID: &two, Name: "grandchild", Version: 1}
+	done := model.Task{ID: 4, OwnerID: 2, ParentID: &one, Name: "done", Completed: true, Version: 1}
+	completed := func(task model.Task) model.Task {
+		task.Completed = true
+		return task
+	}
+
+	cases := []struct {
+		caseName         string
+		change           func(s *service.Task) error
+		expectedBehavior func(m repository.StoreMock)
+		expectedErr      error
+	}{
+		{
+			caseName: "create under a parent",
+			change: func(s *service.Task) error {
+				_, err := s.Create(context.Background(), model.Task{Name: "child", ParentID: &one})
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(child, nil).Times(1)
+			},
+		},
+		{
+			caseName: "create under a missing parent",
+			change: func(s *service.Task) error {
+				_, err := s.Create(context.Background(), model.Task{Name: "child", ParentID: &three})
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 3).Return(model.Task{}, model.ErrTaskNotFound).Times(1)
+			},
+			expectedErr: model.ErrInvalidTaskParent,
+		},
+		{
+			caseName: "move under itself",
+			change: func(s *service.Task) error {
+				_, err := s.Update(context.Background(), model.Task{ID: 1, Name: "parent", ParentID: &one})
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(2)
+			},
+			expectedErr: model.ErrTaskParentCycle,
+		},
+		{
+			caseName: "move under its own subtask",
+			change: func(s *service.Task) error {
+				_, err := s.Update(context.Background(), model.Task{ID: 1, Name: "parent", ParentID: &three})
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 3).Return(grandchild, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 2).Return(child, nil).Times(1)
+			},
+			expectedErr: model.ErrTaskParentCycle,
+		},
+		{
+			caseName: "complete with open subtasks",
+			change: func(s *service.Task) error {
+				_, err := s.Update(context.Background(), completed(parent))
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, false).Return([]model.Task{child, grandchild, done}, nil).Times(1)
+			},
+			expectedErr: model.ErrOpenSubtasks,
+		},
+		{
+			caseName: "force completes the task alone",
+			change: func(s *service.Task) error {
+				_, err := s.UpdateWith(context.Background(), completed(parent), model.CompleteForce)
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).Return(completed(parent), nil).Times(1)
+			},
+		},
+		{
+			caseName: "cascade completes the open subtasks",
+			change: func(s *service.Task) error {
+				_, err := s.UpdateWith(context.Background(), completed(parent), model.CompleteCascade)
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, false).Return([]model.Task{child, grandchild, done}, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 2).Return(child, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 3).Return(grandchild, nil).Times(1)
+				for _, task := range []model.Task{parent, child, grandchild} {
+					id := task.ID
+					m.Tasks.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, task model.Task) (model.Task, error) {
+						if task.ID != id || !task.Completed || task.CompletedAt == nil {
+							t.Errorf("expected task %d to be completed, got %+v", id, task)
+						}
+						return task, nil
+					}).Times(1)
+				}
+			},
+		},
+		{
+			caseName: "an unknown completion mode",
+			change: func(s *service.Task) error {
+				_, err := s.UpdateWith(context.Background(), completed(parent), "later")
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {},
+			expectedErr:      model.ErrInvalidCompletionMode,
+		},
+		{
+			caseName: "delete takes the subtasks to the trash",
+			change: func(s *service.Task) error {
+				return s.Delete(context.Background(), 1, 1)
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, false).Return([]model.Task{child, grandchild}, nil).Times(1)
+				m.Tasks.EXPECT().Delete(gomock.Any(), 1, 1).Return(nil).Times(1)
+				m.Tasks.EXPECT().Delete(gomock.Any(), 2, 0).Return(nil).Times(1)
+				m.Tasks.EXPECT().Delete(gomock.Any(), 3, 0).Return(nil).Times(1)
+			},
+		},
+		{
+			caseName: "restore brings the trashed subtasks back",
+			change: func(s *service.Task) error {
+				_, err := s.Restore(context.Background(), 1)
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Restore(gomock.Any(), 1).Return(parent, nil).Times(1)
+				m.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, true).Return([]model.Task{child}, nil).Times(1)
+				m.Tasks.EXPECT().Restore(gomock.Any(), 2).Return(child, nil).Times(1)
+			},
+		},
+		{
+			caseName: "restore under a trashed parent",
+			change: func(s *service.Task) error {
+				_, err := s.Restore(context.Background(), 2)
+				return err
+			},
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Tasks.EXPECT().Restore(gomock.Any(), 2).Return(child, nil).Times(1)
+				m.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(model.Task{}, model.ErrTaskNotFound).Times(1)
+			},
+			expectedErr: model.ErrInvalidTaskParent,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			testCase.expectedBehavior(repoMock)
+			repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+				return fn(repoMock.Store())
+			}).AnyTimes()
+			repoMock.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(parent, nil).AnyTimes()
+			repoMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
+			repoMock.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			s := service.NewTask(repoMock.Store(), event.NewBus(event.DefaultReplaySize))
+
+			if err := testCase.change(&s); !errors.Is(err, testCase.expectedErr) {
+				t.Errorf("got %v want %v", err, testCase.expectedErr)
+			}
+		})
+	}
+}
+
+func TestTree(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	one, two := 1, 2
+	root := model.Task{ID: 1, Name: "root"}
+	first := model.Task{ID: 2, ParentID: &one, Name: "first"}
+	second := model.Task{ID: 3, ParentID: &one, Name: "second"}
+	nested := model.Task{ID: 4, ParentID: &two, Name: "nested"}
+
+	repoMock := repository.NewStoreMock(ctrl)
+	repoMock.Tasks.EXPECT().FindByID(gomock.Any(), 1).Return(root, nil).Times(1)
+	repoMock.Tasks.EXPECT().FindSubtree(gomock.Any(), 1, false).Return([]model.Task{first, second, nested}, nil).Times(1)
+	s := service.NewTask(repoMock.Store(), event.NewBus(event.DefaultReplaySize))
+
+	tree, err := s.Tree(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Error was not expected while building the tree, got %s", err)
+	}
+
+	leaf := func(task model.Task) model.TaskTree {
+		return model.TaskTree{Task: task, Subtasks: []model.TaskTree{}}
+	}
+	expected := model.TaskTree{Task: root, Subtasks: []model.TaskTree{
+		{Task: first, Subtasks: []model.TaskTree{leaf(nested)}},
+		leaf(second),
+	}}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("got %+v want %+v", tree, expected)
+	}
+}
+
+func source(tasks []model.Task) service.ImportSource {
+	return func() (model.Task, error) {
+		if len(tasks) == 0 {
+			return model.Task{}, io.EOF
+		}
+		task := tasks[0]
+		tasks = tasks[1:]
+		return task, nil
+	}
+}
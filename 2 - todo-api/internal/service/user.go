@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gochallenges/internal/auth"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/pkg"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const bearerPrefix = "Bearer "
+const tokenPrefix = "gct_"
+const minPasswordLength, maxPasswordLength = 8, 72
+const maxNameLength = 100
+
+// lastUsedResolution keeps busy tokens from writing on every request.
+const lastUsedResolution = time.Minute
+
+type User struct {
+	userRepository repository.User
+	jwt            *auth.JWT
+}
+
+func NewUser(userRepository repository.User, jwt *auth.JWT) User {
+	return User{userRepository: userRepository, jwt: jwt}
+}
+
+func (s *User) Register(ctx context.Context, name string, password string) (model.User, error) {
+	if name == "" || len(name) > maxNameLength || strings.ContainsAny(name, ": \t\r\n") {
+		return model.User{}, model.ErrInvalidUserName
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return model.User{}, model.ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return s.userRepository.Create(ctx, model.User{Name: name, PasswordHash: string(hash)})
+}
+
+func (s *User) Login(ctx context.Context, name string, password string) (model.User, error) {
+	user, err := s.userRepository.FindByName(ctx, name)
+	if errors.Is(err, model.ErrUserNotFound) {
+		return user, model.ErrInvalidCredentials
+	}
+	if err != nil {
+		return user, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return model.User{}, model.ErrInvalidCredentials
+	}
+
+	// The password can do anything its user can.
+	user.Scopes = model.AllScopes
+	return user, nil
+}
+
+// Authenticate resolves a personal token, a JWT or the shared BEARER_TOKEN to its user.
+func (s *User) Authenticate(ctx context.Context, authorization string) (model.User, error) {
+	if authorization == "" {
+		return model.User{}, model.ErrUnauthorized
+	}
+	if legacy := pkg.GetBearerToken(); legacy != "" && subtle.ConstantTimeCompare([]byte(authorization), []byte(legacy)) == 1 {
+		return model.User{ID: model.DefaultUserID, Name: "default", Scopes: model.AllScopes}, nil
+	}
+
+	secret := strings.TrimPrefix(authorization, bearerPrefix)
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return model.User{}, model.ErrUnauthorized
+	}
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return s.verifyJwt(ctx, secret)
+	}
+	return s.findByToken(ctx, secret)
+}
+
+// AuthenticateToken only accepts a personal token, so a JWT cannot renew itself.
+func (s *User) AuthenticateToken(ctx context.Context, authorization string) (model.User, error) {
+	secret := strings.TrimPrefix(authorization, bearerPrefix)
+	if !strings.HasPrefix(authorization, bearerPrefix) || !strings.HasPrefix(secret, tokenPrefix) {
+		return model.User{}, model.ErrUnauthorized
+	}
+	return s.findByToken(ctx, secret)
+}
+
+func (s *User) findByToken(ctx context.Context, secret string) (model.User, error) {
+	user, token, err := s.userRepository.FindByToken(ctx, hashToken(secret))
+	if errors.Is(err, model.ErrUserNotFound) {
+		return user, model.ErrUnauthorized
+	}
+	if err != nil {
+		return user, err
+	}
+
+	now := model.Now()
+	if token.Expired(now) {
+		return model.User{}, fmt.Errorf("%w: token expired", model.ErrUnauthorized)
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := s.userRepository.TouchToken(ctx, token.ID, now); err != nil {
+			return model.User{}, err
+		}
+	}
+
+	user.Scopes = token.Scopes
+	user.TokenID = token.ID
+	return user, nil
+}
+
+// verifyJwt also checks the user and the token the JWT was issued for still exist.
+func (s *User) verifyJwt(ctx context.Context, signed string) (model.User, error) {
+	user, err := s.jwt.Verify(signed)
+	if err != nil {
+		return user, err
+	}
+
+	if user.TokenID == 0 {
+		_, err = s.userRepository.FindById(ctx, user.ID)
+	} else {
+		var token model.Token
+		token, err = s.userRepository.FindToken(ctx, user.ID, user.TokenID)
+		if err == nil && token.Expired(model.Now()) {
+			return model.User{}, fmt.Errorf("%w: token expired", model.ErrUnauthorized)
+		}
+	}
+	if errors.Is(err, model.ErrTokenNotFound) || errors.Is(err, model.ErrUserNotFound) {
+		return model.User{}, fmt.Errorf("%w: credentials revoked", model.ErrUnauthorized)
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// CreateToken gives the token all of the user's scopes when none are given.
+func (s *User) CreateToken(ctx context.Context, user model.User, name string, scopes model.Scopes, expiresAt *time.Time) (model.Token, error) {
+	if len(name) > maxNameLength {
+		return model.Token{}, model.ErrInvalidRequestBody
+	}
+	if scopes == 0 {
+		scopes = user.Scopes
+	}
+	if !user.Scopes.Has(scopes) {
+		return model.Token{}, fmt.Errorf("%w: cannot grant scopes you do not have", model.ErrForbidden)
+	}
+	if expiresAt != nil && !expiresAt.After(model.Now()) {
+		return model.Token{}, model.ErrInvalidTokenExpiry
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return model.Token{}, err
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	token, err := s.userRepository.CreateToken(ctx, model.Token{UserID: user.ID, Name: name, Hash: hashToken(secret), Scopes: scopes, ExpiresAt: expiresAt})
+	if err != nil {
+		return token, err
+	}
+
+	token.Secret = secret
+	return token, nil
+}
+
+func (s *User) IssueJwt(user model.User) (auth.AccessToken, error) {
+	return s.jwt.Issue(user)
+}
+
+func (s *User) Tokens(ctx context.Context, user model.User) ([]model.Token, error) {
+	return s.userRepository.FindTokens(ctx, user.ID)
+}
+
+func (s *User) RevokeToken(ctx context.Context, user model.User, tokenId int) error {
+	if tokenId <= 0 {
+		return model.ErrInvalidTokenId
+	}
+	return s.userRepository.DeleteToken(ctx, user.ID, tokenId)
+}
+
+// hashToken is unsalted: tokens are random, and the hash is their lookup key.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The headers of every delivery; the signature covers the timestamp and the body.
+const (
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "sha256="
+const minWebhookSecretLength = 16
+const maxWebhookUrlLength = 2048
+const maxDeliveryErrorLength = 1024
+
+// webhookTimeout bounds how long a slow receiver holds the dispatcher up.
+const webhookTimeout = 10 * time.Second
+
+const dispatchBatchSize = 100
+
+// Retries back off exponentially from firstRetryDelay up to maxRetryDelay.
+const firstRetryDelay, maxRetryDelay = 30 * time.Second, time.Hour
+
+type Webhook struct {
+	webhookRepository repository.Webhooks
+	client            *http.Client
+}
+
+type webhookPayload struct {
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Task       model.Task  `json:"task"`
+	Previous   *model.Task `json:"previous,omitempty"`
+}
+
+// NewWebhook does not follow redirects, which count as failed attempts.
+func NewWebhook(webhookRepository repository.Webhooks) Webhook {
+	return Webhook{
+		webhookRepository: webhookRepository,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Register generates a secret when webhook has none; it is only in the result.
+func (s *Webhook) Register(ctx context.Context, webhook model.Webhook) (model.Webhook, error) {
+	if err := validateWebhook(webhook); err != nil {
+		return webhook, err
+	}
+	webhook.Events = uniqueEvents(webhook.Events)
+
+	if webhook.Secret == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return webhook, err
+		}
+		webhook.Secret = base64.RawURLEncoding.EncodeToString(random)
+	}
+	webhook.CreatedAt = model.Now()
+
+	return s.webhookRepository.CreateWebhook(ctx, webhook)
+}
+
+func (s *Webhook) List(ctx context.Context) ([]model.Webhook, error) {
+	webhooks, err := s.webhookRepository.FindWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *Webhook) Delete(ctx context.Context, id int) error {
+	if id <= 0 {
+		return model.ErrInvalidWebhookId
+	}
+	return s.webhookRepository.DeleteWebhook(ctx, id)
+}
+
+func (s *Webhook) Deliveries(ctx context.Context, webhookId int, status model.DeliveryStatus) ([]model.WebhookDelivery, error) {
+	if status != "" && !status.Valid() {
+		return nil, fmt.Errorf("%w %q", model.ErrInvalidDeliveryStatus, status)
+	}
+	if _, err := s.find(ctx, webhookId); err != nil {
+		return nil, err
+	}
+	return s.webhookRepository.FindDeliveries(ctx, model.DeliveryFilter{WebhookID: webhookId, Status: status}, model.MaxPageSize)
+}
+
+// Redeliver gives the delivery a fresh set of attempts, whatever its status.
+func (s *Webhook) Redeliver(ctx context.Context, webhookId int, deliveryId int) (model.WebhookDelivery, error) {
+	if deliveryId <= 0 {
+		return model.WebhookDelivery{}, model.ErrInvalidDeliveryId
+	}
+	if _, err := s.find(ctx, webhookId); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	found, err := s.webhookRepository.FindDeliveries(ctx, model.DeliveryFilter{ID: deliveryId, WebhookID: webhookId}, 1)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	if len(found) == 0 {
+		return model.WebhookDelivery{}, model.ErrDeliveryNotFound
+	}
+
+	delivery := found[0]
+	delivery.Status = model.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = model.Now()
+	delivery.LastError = ""
+	delivery.DeliveredAt = nil
+	return delivery, s.webhookRepository.UpdateDelivery(ctx, delivery)
+}
+
+// Dispatch claims each due delivery before sending it, so two dispatchers never both send it.
+func (s *Webhook) Dispatch(ctx context.Context) (int, error) {
+	now := model.Now()
+	due, err := s.webhookRepository.FindDeliveries(ctx, model.DeliveryFilter{Status: model.DeliveryPending, DueBy: &now}, dispatchBatchSize)
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+	webhooks, err := s.webhookRepository.FindWebhooks(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byId := map[int]model.Webhook{}
+	for _, webhook := range webhooks {
+		byId[webhook.ID] = webhook
+	}
+
+	delivered := 0
+	for _, delivery := range due {
+		webhook, ok := byId[delivery.WebhookID]
+		if !ok {
+			continue
+		}
+
+		delivery.NextAttemptAt = now.Add(retryDelay(delivery.Attempts + 1))
+		claimed, err := s.webhookRepository.ClaimDelivery(ctx, delivery.ID, delivery.Attempts, delivery.NextAttemptAt)
+		if err != nil {
+			return delivered, err
+		}
+		if !claimed {
+			continue
+		}
+		delivery.Attempts++
+
+		if err := s.send(ctx, webhook, delivery); err != nil {
+			delivery.LastError = truncate(err.Error(), maxDeliveryErrorLength)
+			if delivery.Attempts >= model.MaxDeliveryAttempts {
+				delivery.Status = model.DeliveryDead
+			}
+		} else {
+			deliveredAt := model.Now()
+			delivery.Status, delivery.DeliveredAt, delivery.LastError = model.DeliveryDone, &deliveredAt, ""
+			delivered++
+		}
+		if err := s.webhookRepository.UpdateDelivery(ctx, delivery); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+func (s *Webhook) DispatchEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Dispatch(ctx); err != nil {
+			log.Printf("Could not dispatch the webhook deliveries: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SignWebhook is the hex HMAC-SHA256 of the timestamp, a dot and the body.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send fails on any answer but a 2xx.
+func (s *Webhook) send(ctx context.Context, webhook model.Webhook, delivery model.WebhookDelivery) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := model.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookDeliveryHeader, strconv.Itoa(delivery.ID))
+	request.Header.Set(WebhookEventHeader, delivery.EventType)
+	request.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("receiver answered %s", response.Status)
+	}
+	return nil
+}
+
+func (s *Webhook) find(ctx context.Context, id int) (model.Webhook, error) {
+	if id <= 0 {
+		return model.Webhook{}, model.ErrInvalidWebhookId
+	}
+	webhooks, err := s.webhookRepository.FindWebhooks(ctx)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	for _, webhook := range webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return model.Webhook{}, model.ErrWebhookNotFound
+}
+
+func retryDelay(attempt int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+func validateWebhook(webhook model.Webhook) error {
+	target, err := url.Parse(webhook.URL)
+	if err != nil || len(webhook.URL) > maxWebhookUrlLength || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w %q: needs an absolute http or https url", model.ErrInvalidWebhookUrl, webhook.URL)
+	}
+	for _, eventType := range webhook.Events {
+		if !knownEvent(eventType) {
+			return fmt.Errorf("%w %q", model.ErrInvalidWebhookEvent, eventType)
+		}
+	}
+	if webhook.Secret != "" && (len(webhook.Secret) < minWebhookSecretLength || len(webhook.Secret) > model.MaxWebhookSecretLength) {
+		return fmt.Errorf("%w: must have between %d and %d characters", model.ErrInvalidWebhookSecret, minWebhookSecretLength, model.MaxWebhookSecretLength)
+	}
+	return nil
+}
+
+func knownEvent(eventType string) bool {
+	for _, known := range event.Types {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueEvents(events []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, eventType := range events {
+		if !seen[eventType] {
+			seen[eventType] = true
+			unique = append(unique, eventType)
+		}
+	}
+	return unique
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length]
+}
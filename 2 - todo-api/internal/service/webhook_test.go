@@ -0,0 +1,302 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gochallenges/internal/event"
+	"gochallenges/internal/model"
+	"gochallenges/internal/repository"
+	"gochallenges/internal/service"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestOutbox(t *testing.T) {
+	stored := model.Task{ID: 1, OwnerID: 2, Name: "Task 1", Version: 1}
+	webhooks := []model.Webhook{
+		{ID: 1, OwnerID: 2, URL: "http://example.com/every"},
+		{ID: 2, OwnerID: 2, URL: "http://example.com/deleted", Events: []string{event.TaskDeleted}},
+		{ID: 3, OwnerID: 3, URL: "http://example.com/bob"},
+	}
+
+	cases := []struct {
+		caseName           string
+		outboxErr          error
+		expectedWebhookIds []int
+	}{
+		{caseName: "deliveries for the subscribed webhooks of the owner", expectedWebhookIds: []int{1}},
+		{caseName: "a failed outbox fails the change", outboxErr: model.ErrInsertingRow, expectedWebhookIds: []int{1}},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock, txMock := repository.NewStoreMock(ctrl), repository.NewStoreMock(ctrl)
+			repoMock.Tasks.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(tx repository.Store) error) error {
+				return fn(txMock.Store())
+			}).Times(1)
+			txMock.Tasks.EXPECT().Create(gomock.Any(), gomock.Any()).Return(stored, nil).Times(1)
+			txMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return(webhooks, nil).Times(1)
+			txMock.Webhooks.EXPECT().CreateDeliveries(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, deliveries []model.WebhookDelivery) error {
+				webhookIds := []int{}
+				for _, delivery := range deliveries {
+					webhookIds = append(webhookIds, delivery.WebhookID)
+					if delivery.Status != model.DeliveryPending || delivery.EventType != event.TaskCreated || delivery.NextAttemptAt.IsZero() {
+						t.Errorf("expected a pending task.created delivery due now, got %+v", delivery)
+					}
+					var payload struct {
+						Type string     `json:"type"`
+						Task model.Task `json:"task"`
+					}
+					if err := json.Unmarshal(delivery.Payload, &payload); err != nil || payload.Type != event.TaskCreated || payload.Task.ID != stored.ID {
+						t.Errorf("expected the created task in the payload, got %s", delivery.Payload)
+					}
+				}
+				if len(webhookIds) != len(testCase.expectedWebhookIds) || webhookIds[0] != testCase.expectedWebhookIds[0] {
+					t.Errorf("got deliveries for %v want %v", webhookIds, testCase.expectedWebhookIds)
+				}
+				return testCase.outboxErr
+			}).Times(1)
+			txMock.Audit.EXPECT().CreateAuditRecord(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			bus := event.NewBus(event.DefaultReplaySize)
+			sub, _, _ := bus.Subscribe(0)
+			defer sub.Close()
+			s := service.NewTask(repoMock.Store(), bus)
+
+			if _, err := s.Create(context.Background(), model.Task{Name: "Task 1"}); !errors.Is(err, testCase.outboxErr) {
+				t.Fatalf("got %v want %v", err, testCase.outboxErr)
+			}
+			if published := len(sub.C); testCase.outboxErr != nil && published != 0 {
+				t.Errorf("expected nothing to be published without the outbox, got %d events", published)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	const secret = "a-secret-of-some-length"
+	payload := []byte(`{"type":"task.created"}`)
+
+	cases := []struct {
+		caseName          string
+		status            int
+		attempts          int
+		claimed           bool
+		expectedSent      bool
+		expectedStatus    model.DeliveryStatus
+		expectedDelivered int
+	}{
+		{caseName: "a delivery the receiver takes is done", status: http.StatusNoContent, claimed: true, expectedSent: true, expectedStatus: model.DeliveryDone, expectedDelivered: 1},
+		{caseName: "a failed attempt is retried later", status: http.StatusInternalServerError, claimed: true, expectedSent: true, expectedStatus: model.DeliveryPending},
+		{caseName: "a redirect is a failed attempt", status: http.StatusFound, attempts: 3, claimed: true, expectedSent: true, expectedStatus: model.DeliveryPending},
+		{caseName: "the last failed attempt leaves it dead", status: http.StatusBadGateway, attempts: model.MaxDeliveryAttempts - 1, claimed: true, expectedSent: true, expectedStatus: model.DeliveryDead},
+		{caseName: "a delivery another dispatcher claimed is not sent", status: http.StatusOK},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			sent := false
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent = true
+				body, _ := io.ReadAll(r.Body)
+				timestamp, _ := strconv.ParseInt(r.Header.Get(service.WebhookTimestampHeader), 10, 64)
+				if r.Header.Get(service.WebhookSignatureHeader) != service.SignWebhook(secret, timestamp, body) {
+					t.Errorf("expected a valid signature, got %q", r.Header.Get(service.WebhookSignatureHeader))
+				}
+				if r.Header.Get(service.WebhookDeliveryHeader) != "7" || r.Header.Get(service.WebhookEventHeader) != event.TaskCreated || string(body) != string(payload) {
+					t.Errorf("expected delivery 7 of task.created, got %v %s", r.Header, body)
+				}
+				if testCase.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(testCase.status)
+			}))
+			defer receiver.Close()
+
+			due := model.WebhookDelivery{ID: 7, WebhookID: 1, OwnerID: 2, EventType: event.TaskCreated, Payload: payload, Status: model.DeliveryPending, Attempts: testCase.attempts}
+			repoMock := repository.NewStoreMock(ctrl)
+			repoMock.Webhooks.EXPECT().FindDeliveries(gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.WebhookDelivery{due}, nil).Times(1)
+			repoMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return([]model.Webhook{{ID: 1, OwnerID: 2, URL: receiver.URL, Secret: secret}}, nil).Times(1)
+
+			var retryAt time.Time
+			repoMock.Webhooks.EXPECT().ClaimDelivery(gomock.Any(), 7, testCase.attempts, gomock.Any()).DoAndReturn(func(_ context.Context, _ int, _ int, next time.Time) (bool, error) {
+				retryAt = next
+				return testCase.claimed, nil
+			}).Times(1)
+			if testCase.claimed {
+				repoMock.Webhooks.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, delivery model.WebhookDelivery) error {
+					if delivery.Status != testCase.expectedStatus || delivery.Attempts != testCase.attempts+1 {
+						t.Errorf("got %s after %d attempts, want %s after %d", delivery.Status, delivery.Attempts, testCase.expectedStatus, testCase.attempts+1)
+					}
+					if (delivery.Status == model.DeliveryDone) != (delivery.DeliveredAt != nil && delivery.LastError == "") {
+						t.Errorf("expected a delivery time only on success, got %+v", delivery)
+					}
+					if delivery.Status == model.DeliveryPending && !delivery.NextAttemptAt.Equal(retryAt) {
+						t.Errorf("expected the retry the claim scheduled at %v, got %v", retryAt, delivery.NextAttemptAt)
+					}
+					return nil
+				}).Times(1)
+			}
+
+			s := service.NewWebhook(repoMock.Webhooks)
+			delivered, err := s.Dispatch(context.Background())
+			if err != nil || delivered != testCase.expectedDelivered {
+				t.Errorf("got %d delivered, %v, want %d", delivered, err, testCase.expectedDelivered)
+			}
+			if sent != testCase.expectedSent {
+				t.Errorf("got sent %v want %v", sent, testCase.expectedSent)
+			}
+			if testCase.claimed {
+				if delay := retryAt.Sub(model.Now()); delay <= 0 || delay > time.Hour {
+					t.Errorf("expected the retry to back off by up to an hour, got %v", delay)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatchBacksOffExponentially(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var delays []time.Duration
+	repoMock := repository.NewStoreMock(ctrl)
+	for attempts := 0; attempts < 9; attempts++ {
+		due := model.WebhookDelivery{ID: 1, WebhookID: 1, Status: model.DeliveryPending, Attempts: attempts}
+		repoMock.Webhooks.EXPECT().FindDeliveries(gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.WebhookDelivery{due}, nil).Times(1)
+		repoMock.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return([]model.Webhook{{ID: 1}}, nil).Times(1)
+		repoMock.Webhooks.EXPECT().ClaimDelivery(gomock.Any(), 1, attempts, gomock.Any()).DoAndReturn(func(_ context.Context, _ int, _ int, next time.Time) (bool, error) {
+			delays = append(delays, next.Sub(model.Now()).Round(time.Second))
+			return false, nil
+		}).Times(1)
+	}
+
+	s := service.NewWebhook(repoMock.Webhooks)
+	for i := 0; i < 9; i++ {
+		s.Dispatch(context.Background())
+	}
+
+	expected := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute, 32 * time.Minute, time.Hour, time.Hour}
+	for i := range expected {
+		if delays[i] < expected[i]-time.Second || delays[i] > expected[i] {
+			t.Errorf("got %v before attempt %d, want %v", delays[i], i+2, expected[i])
+		}
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	deliveredAt := model.Now()
+	dead := model.WebhookDelivery{ID: 7, WebhookID: 1, OwnerID: 2, Status: model.DeliveryDead, Attempts: model.MaxDeliveryAttempts, LastError: "receiver answered 500", DeliveredAt: &deliveredAt}
+
+	cases := []struct {
+		caseName         string
+		webhookId        int
+		deliveryId       int
+		expectedBehavior func(m repository.StoreMock)
+		expectedErr      error
+	}{
+		{
+			caseName:   "dead delivery",
+			webhookId:  1,
+			deliveryId: 7,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return([]model.Webhook{{ID: 1, OwnerID: 2}}, nil).Times(1)
+				m.Webhooks.EXPECT().FindDeliveries(gomock.Any(), model.DeliveryFilter{ID: 7, WebhookID: 1}, 1).Return([]model.WebhookDelivery{dead}, nil).Times(1)
+				m.Webhooks.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, delivery model.WebhookDelivery) error {
+					if delivery.Status != model.DeliveryPending || delivery.Attempts != 0 || delivery.LastError != "" || delivery.DeliveredAt != nil || delivery.NextAttemptAt.After(model.Now()) {
+						t.Errorf("expected the delivery to be due again with fresh attempts, got %+v", delivery)
+					}
+					return nil
+				}).Times(1)
+			},
+		},
+		{
+			caseName:   "delivery of another webhook",
+			webhookId:  1,
+			deliveryId: 8,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return([]model.Webhook{{ID: 1, OwnerID: 2}}, nil).Times(1)
+				m.Webhooks.EXPECT().FindDeliveries(gomock.Any(), model.DeliveryFilter{ID: 8, WebhookID: 1}, 1).Return([]model.WebhookDelivery{}, nil).Times(1)
+			},
+			expectedErr: model.ErrDeliveryNotFound,
+		},
+		{
+			caseName:   "webhook of another user",
+			webhookId:  2,
+			deliveryId: 7,
+			expectedBehavior: func(m repository.StoreMock) {
+				m.Webhooks.EXPECT().FindWebhooks(gomock.Any()).Return([]model.Webhook{{ID: 1, OwnerID: 2}}, nil).Times(1)
+			},
+			expectedErr: model.ErrWebhookNotFound,
+		},
+		{
+			caseName:         "invalid delivery id",
+			webhookId:        1,
+			expectedBehavior: func(m repository.StoreMock) {},
+			expectedErr:      model.ErrInvalidDeliveryId,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			testCase.expectedBehavior(repoMock)
+			s := service.NewWebhook(repoMock.Webhooks)
+
+			if _, err := s.Redeliver(context.Background(), testCase.webhookId, testCase.deliveryId); !errors.Is(err, testCase.expectedErr) {
+				t.Errorf("got %v want %v", err, testCase.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRegisterWebhook(t *testing.T) {
+	cases := []struct {
+		caseName    string
+		webhook     model.Webhook
+		expectedErr error
+	}{
+		{caseName: "generated secret", webhook: model.Webhook{URL: "https://example.com/hook", Events: []string{event.TaskCreated, event.TaskCreated}}},
+		{caseName: "relative url", webhook: model.Webhook{URL: "/hook"}, expectedErr: model.ErrInvalidWebhookUrl},
+		{caseName: "unknown event", webhook: model.Webhook{URL: "https://example.com/hook", Events: []string{"task.renamed"}}, expectedErr: model.ErrInvalidWebhookEvent},
+		{caseName: "short secret", webhook: model.Webhook{URL: "https://example.com/hook", Secret: "short"}, expectedErr: model.ErrInvalidWebhookSecret},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.caseName, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repoMock := repository.NewStoreMock(ctrl)
+			if testCase.expectedErr == nil {
+				repoMock.Webhooks.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, webhook model.Webhook) (model.Webhook, error) {
+					if len(webhook.Secret) < 16 || len(webhook.Events) != 1 || webhook.CreatedAt.IsZero() {
+						t.Errorf("expected a secret, the events once and a creation time, got %+v", webhook)
+					}
+					return webhook, nil
+				}).Times(1)
+			}
+			s := service.NewWebhook(repoMock.Webhooks)
+
+			if _, err := s.Register(context.Background(), testCase.webhook); !errors.Is(err, testCase.expectedErr) {
+				t.Errorf("got %v want %v", err, testCase.expectedErr)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,12 +14,27 @@ import (
 const projectDir = "go-challenges"
 const configsDir = "configs"
 const propertiesFile = "properties.env"
+const defaultSqliteFile = "todoapi.db"
+const defaultJwtIssuer = "gochallenges"
+const defaultJwtAudience = "gochallenges-api"
+const defaultJwtTTL = 15 * time.Minute
+const defaultIdempotencyWindow = 24 * time.Hour
+const defaultTrashRetentionDays = 30
+const defaultWebhookDispatchInterval = 5 * time.Second
 
 type DbConfig struct {
 	User     string
 	Password string
 	Database string
 	Driver   string
+	File     string
+}
+
+type JwtConfig struct {
+	KeysFile string
+	Issuer   string
+	Audience string
+	TTL      time.Duration
 }
 
 func loadEnv() {
@@ -39,6 +56,7 @@ func GetDbConfig() DbConfig {
 		Password: os.Getenv("DB_PASS"),
 		Database: os.Getenv("DB_NAME"),
 		Driver:   os.Getenv("DB_DRIVER"),
+		File:     os.Getenv("DB_FILE"),
 	}
 }
 
@@ -46,6 +64,70 @@ func GetMysqlDbConnection(config DbConfig) string {
 	return fmt.Sprintf("%s:%s@/%s?charset=utf8&parseTime=True&loc=Local", config.User, config.Password, config.Database)
 }
 
+func GetSqliteDbConnection(config DbConfig) string {
+	if config.File == "" {
+		return defaultSqliteFile
+	}
+	return config.File
+}
+
+func GetJwtConfig() JwtConfig {
+	loadEnv()
+	config := JwtConfig{
+		KeysFile: os.Getenv("JWT_KEYS_FILE"),
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+		TTL:      defaultJwtTTL,
+	}
+	if config.Issuer == "" {
+		config.Issuer = defaultJwtIssuer
+	}
+	if config.Audience == "" {
+		config.Audience = defaultJwtAudience
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("JWT_TTL")); err == nil && ttl > 0 {
+		config.TTL = ttl
+	}
+	return config
+}
+
+func GetImportChunkSize() int {
+	loadEnv()
+	size, err := strconv.Atoi(os.Getenv("IMPORT_CHUNK_SIZE"))
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+func GetIdempotencyWindow() time.Duration {
+	loadEnv()
+	if window, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_WINDOW")); err == nil && window > 0 {
+		return window
+	}
+	return defaultIdempotencyWindow
+}
+
+// GetTrashRetentionDays returns 0 to leave purging to the CLI.
+func GetTrashRetentionDays() int {
+	loadEnv()
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		return defaultTrashRetentionDays
+	}
+	return days
+}
+
+// GetWebhookDispatchInterval is 0 when another process sends the deliveries.
+func GetWebhookDispatchInterval() time.Duration {
+	loadEnv()
+	interval, err := time.ParseDuration(os.Getenv("WEBHOOK_DISPATCH_INTERVAL"))
+	if err != nil || interval < 0 {
+		return defaultWebhookDispatchInterval
+	}
+	return interval
+}
+
 func GetBearerToken() string {
 	loadEnv()
 	return os.Getenv("BEARER_TOKEN")